@@ -6,23 +6,47 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/go-kit/log"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/oklog/oklog/pkg/group"
 	pgxvector "github.com/pgvector/pgvector-go/pgx"
 	"github.com/spf13/viper"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"github.com/yckao/image-search-demo-go/api/openapi"
 	"github.com/yckao/image-search-demo-go/pkg/clients/clip"
+	"github.com/yckao/image-search-demo-go/pkg/netguard"
+	"github.com/yckao/image-search-demo-go/pkg/retry"
 	"github.com/yckao/image-search-demo-go/services/image/imageendpoint"
 	"github.com/yckao/image-search-demo-go/services/image/imagerepository"
 	"github.com/yckao/image-search-demo-go/services/image/imageservice"
 	"github.com/yckao/image-search-demo-go/services/image/imagetransport"
+	"github.com/yckao/image-search-demo-go/services/job/jobendpoint"
+	"github.com/yckao/image-search-demo-go/services/job/jobrepository"
+	"github.com/yckao/image-search-demo-go/services/job/jobservice"
+	"github.com/yckao/image-search-demo-go/services/job/jobtransport"
+	"github.com/yckao/image-search-demo-go/services/library/libraryendpoint"
+	"github.com/yckao/image-search-demo-go/services/library/libraryrepository"
+	"github.com/yckao/image-search-demo-go/services/library/libraryservice"
+	"github.com/yckao/image-search-demo-go/services/library/librarytransport"
+	"github.com/yckao/image-search-demo-go/services/outbox/outboxrepository"
+	"github.com/yckao/image-search-demo-go/services/outbox/outboxservice"
+	"github.com/yckao/image-search-demo-go/services/savedsearch/savedsearchendpoint"
+	"github.com/yckao/image-search-demo-go/services/savedsearch/savedsearchrepository"
+	"github.com/yckao/image-search-demo-go/services/savedsearch/savedsearchservice"
+	"github.com/yckao/image-search-demo-go/services/savedsearch/savedsearchtransport"
 	"github.com/yckao/image-search-demo-go/services/storage/storageendpoint"
 	"github.com/yckao/image-search-demo-go/services/storage/storageservice"
 	"github.com/yckao/image-search-demo-go/services/storage/storagetransport"
+	"github.com/yckao/image-search-demo-go/services/webhook/webhookendpoint"
+	"github.com/yckao/image-search-demo-go/services/webhook/webhookrepository"
+	"github.com/yckao/image-search-demo-go/services/webhook/webhookservice"
+	"github.com/yckao/image-search-demo-go/services/webhook/webhooktransport"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -34,6 +58,41 @@ func initConfig() {
 	viper.ReadInConfig()
 
 	viper.SetDefault("BIND_ADDR", "0.0.0.0:8080")
+	viper.SetDefault("SEARCH_MIN_SCORE", -1)
+	viper.SetDefault("SEARCH_STORE_QUERY_IMAGES", false)
+	viper.SetDefault("IMAGE_RETAIN_REPLACED_FILES", false)
+	viper.SetDefault("IMAGE_IMPORT_CONCURRENCY", imageservice.DefaultImportConcurrency)
+	viper.SetDefault("IMAGE_FETCH_TIMEOUT", imageservice.DefaultFetchTimeout)
+	viper.SetDefault("IMAGE_FETCH_MAX_SIZE", imageservice.DefaultFetchMaxSize)
+	viper.SetDefault("IMAGE_FETCH_ALLOWLIST", "")
+	viper.SetDefault("SEARCH_BATCH_CONCURRENCY", imageservice.DefaultBatchSearchConcurrency)
+	viper.SetDefault("SEARCH_SUGGEST_MIN_FREQUENCY", imageservice.DefaultSuggestMinFrequency)
+	viper.SetDefault("AUTOTAG_VOCABULARY_FILE", "")
+	viper.SetDefault("SAVED_SEARCH_NOTIFIER", "http")
+	viper.SetDefault("SAVED_SEARCH_NOTIFY_MAX_ATTEMPTS", savedsearchservice.DefaultNotifyMaxAttempts)
+	viper.SetDefault("SAVED_SEARCH_NOTIFY_BASE_BACKOFF", savedsearchservice.DefaultNotifyBaseBackoff)
+	viper.SetDefault("SAVED_SEARCH_NOTIFY_MAX_BACKOFF", savedsearchservice.DefaultNotifyMaxBackoff)
+	viper.SetDefault("SAVED_SEARCH_NOTIFY_TIMEOUT", savedsearchservice.DefaultHTTPNotifierTimeout)
+	viper.SetDefault("SAVED_SEARCH_CALLBACK_ALLOWLIST", "")
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", webhookservice.DefaultMaxAttempts)
+	viper.SetDefault("WEBHOOK_BASE_BACKOFF", webhookservice.DefaultBaseBackoff)
+	viper.SetDefault("WEBHOOK_MAX_BACKOFF", webhookservice.DefaultMaxBackoff)
+	viper.SetDefault("WEBHOOK_TIMEOUT", webhookservice.DefaultTimeout)
+	viper.SetDefault("WEBHOOK_ALLOWLIST", "")
+	viper.SetDefault("JOB_MAX_ATTEMPTS", jobservice.DefaultMaxAttempts)
+	viper.SetDefault("JOB_BASE_BACKOFF", jobservice.DefaultBaseBackoff)
+	viper.SetDefault("JOB_MAX_BACKOFF", jobservice.DefaultMaxBackoff)
+	viper.SetDefault("JOB_TIMEOUT", jobservice.DefaultTimeout)
+	viper.SetDefault("JOB_CONCURRENCY", jobservice.DefaultConcurrency)
+	viper.SetDefault("OUTBOX_SINK", "log")
+	viper.SetDefault("OUTBOX_HTTP_URL", "")
+	viper.SetDefault("OUTBOX_NATS_URL", "")
+	viper.SetDefault("OUTBOX_NATS_ADDR", "127.0.0.1:4222")
+	viper.SetDefault("OUTBOX_NATS_SUBJECT_PREFIX", outboxservice.DefaultNATSSubjectPrefix)
+	viper.SetDefault("OUTBOX_NATS_STREAM", outboxservice.DefaultNATSStream)
+	viper.SetDefault("OUTBOX_NATS_STORE_DIR", "")
+	viper.SetDefault("OUTBOX_POLL_INTERVAL", outboxservice.DefaultPollInterval)
+	viper.SetDefault("OUTBOX_RETENTION", outboxservice.DefaultRetention)
 
 	viper.MustBindEnv("BASE_URL")
 
@@ -103,16 +162,203 @@ func main() {
 
 	imageRepository := imagerepository.NewPGRepository(logger, db)
 
+	var autotagVocabulary *imageservice.AutotagVocabulary
+	if path := viper.GetString("AUTOTAG_VOCABULARY_FILE"); path != "" {
+		autotagVocabulary, err = imageservice.LoadAutotagVocabulary(path)
+		if err != nil {
+			logger.Log("autotag", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	urlFetcher, err := imageservice.NewURLFetcher(logger, imageservice.URLFetcherConfig{
+		Timeout:   viper.GetDuration("IMAGE_FETCH_TIMEOUT"),
+		MaxSize:   viper.GetInt64("IMAGE_FETCH_MAX_SIZE"),
+		Allowlist: strings.Split(viper.GetString("IMAGE_FETCH_ALLOWLIST"), ","),
+	})
+	if err != nil {
+		logger.Log("image_fetch", "error", err)
+		os.Exit(1)
+	}
+
+	savedSearchRepository := savedsearchrepository.NewPGRepository(logger, db)
+
+	var savedSearchNotifier savedsearchservice.Notifier
+	switch notifier := viper.GetString("SAVED_SEARCH_NOTIFIER"); notifier {
+	case "http":
+		callbackGuard, err := netguard.New(strings.Split(viper.GetString("SAVED_SEARCH_CALLBACK_ALLOWLIST"), ","))
+		if err != nil {
+			logger.Log("savedsearch", "error", err)
+			os.Exit(1)
+		}
+		savedSearchNotifier = savedsearchservice.NewHTTPNotifier(&http.Client{
+			Timeout:   viper.GetDuration("SAVED_SEARCH_NOTIFY_TIMEOUT"),
+			Transport: callbackGuard.Transport(),
+		})
+	case "log":
+		savedSearchNotifier = savedsearchservice.NewLogNotifier(logger)
+	default:
+		logger.Log("savedsearch", "error", fmt.Sprintf("unknown notifier %q", notifier))
+		os.Exit(1)
+	}
+
+	imageMatcher := savedsearchservice.NewImageMatcher(logger, savedsearchservice.MatcherConfig{
+		MaxAttempts: viper.GetInt("SAVED_SEARCH_NOTIFY_MAX_ATTEMPTS"),
+		Backoff: retry.Backoff{
+			Base: viper.GetDuration("SAVED_SEARCH_NOTIFY_BASE_BACKOFF"),
+			Max:  viper.GetDuration("SAVED_SEARCH_NOTIFY_MAX_BACKOFF"),
+		},
+		Timeout: viper.GetDuration("SAVED_SEARCH_NOTIFY_TIMEOUT"),
+	}, savedSearchNotifier, storageService, savedSearchRepository)
+
+	webhookRepository := webhookrepository.NewPGRepository(logger, db)
+	webhookGuard, err := netguard.New(strings.Split(viper.GetString("WEBHOOK_ALLOWLIST"), ","))
+	if err != nil {
+		logger.Log("webhook", "error", err)
+		os.Exit(1)
+	}
+
+	webhookDispatcher := webhookservice.NewDispatcher(logger, webhookservice.DispatcherConfig{
+		MaxAttempts: viper.GetInt("WEBHOOK_MAX_ATTEMPTS"),
+		Backoff: retry.Backoff{
+			Base: viper.GetDuration("WEBHOOK_BASE_BACKOFF"),
+			Max:  viper.GetDuration("WEBHOOK_MAX_BACKOFF"),
+		},
+	}, &http.Client{
+		Timeout:   viper.GetDuration("WEBHOOK_TIMEOUT"),
+		Transport: webhookGuard.Transport(),
+	}, webhookRepository)
+
+	// Every change recorded in the outbox goes to the configured sink, to the webhooks and, for new
+	// images, to the saved searches.
+	var outboxSink outboxservice.Sink
+	var natsServer *server.Server
+	switch sink := viper.GetString("OUTBOX_SINK"); sink {
+	case "log":
+		outboxSink = outboxservice.NewLogSink(logger)
+	case "http":
+		if viper.GetString("OUTBOX_HTTP_URL") == "" {
+			logger.Log("outbox", "error", "OUTBOX_HTTP_URL is required by the http sink")
+			os.Exit(1)
+		}
+		outboxSink = outboxservice.NewHTTPSink(&http.Client{Timeout: outboxservice.DefaultHTTPSinkTimeout}, viper.GetString("OUTBOX_HTTP_URL"))
+	case "nats":
+		natsURL := viper.GetString("OUTBOX_NATS_URL")
+		if natsURL == "" {
+			natsServer, err = outboxservice.StartEmbeddedNATS(viper.GetString("OUTBOX_NATS_ADDR"), viper.GetString("OUTBOX_NATS_STORE_DIR"))
+			if err != nil {
+				logger.Log("outbox", "error", err)
+				os.Exit(1)
+			}
+			natsURL = natsServer.ClientURL()
+			logger.Log("outbox", "nats", "addr", natsURL)
+		}
+		natsConn, err := nats.Connect(natsURL)
+		if err != nil {
+			logger.Log("outbox", "error", err)
+			os.Exit(1)
+		}
+		js, err := jetstream.New(natsConn)
+		if err != nil {
+			logger.Log("outbox", "error", err)
+			os.Exit(1)
+		}
+		if err := outboxservice.CreateNATSStream(ctx, js, viper.GetString("OUTBOX_NATS_STREAM"), viper.GetString("OUTBOX_NATS_SUBJECT_PREFIX")); err != nil {
+			logger.Log("outbox", "error", err)
+			os.Exit(1)
+		}
+		outboxSink = outboxservice.NewNATSSink(js, viper.GetString("OUTBOX_NATS_SUBJECT_PREFIX"))
+	default:
+		logger.Log("outbox", "error", fmt.Sprintf("unknown sink %q", sink))
+		os.Exit(1)
+	}
+
+	outboxRelay := outboxservice.NewRelay(logger, outboxservice.RelayConfig{
+		PollInterval: viper.GetDuration("OUTBOX_POLL_INTERVAL"),
+		Retention:    viper.GetDuration("OUTBOX_RETENTION"),
+	}, outboxrepository.NewPGRepository(logger, db), outboxSink, webhookDispatcher, imageMatcher)
+
+	jobRepository := jobrepository.NewPGRepository(logger, db)
+	jobWorker := jobservice.NewWorker(logger, jobservice.WorkerConfig{
+		MaxAttempts: viper.GetInt("JOB_MAX_ATTEMPTS"),
+		Backoff: retry.Backoff{
+			Base: viper.GetDuration("JOB_BASE_BACKOFF"),
+			Max:  viper.GetDuration("JOB_MAX_BACKOFF"),
+		},
+		Timeout:     viper.GetDuration("JOB_TIMEOUT"),
+		Concurrency: viper.GetInt("JOB_CONCURRENCY"),
+	}, jobRepository)
+
+	if viper.GetString("CLIP_MODEL_NAME") == "" {
+		logger.Log("clip", "error", "CLIP_MODEL_NAME is required")
+		os.Exit(1)
+	}
+
+	if minScore := viper.GetFloat64("SEARCH_MIN_SCORE"); !(minScore >= -1 && minScore <= 1) {
+		logger.Log("search", "error", "SEARCH_MIN_SCORE must be between -1 and 1")
+		os.Exit(1)
+	}
+
 	var (
-		imageService     = imageservice.New(logger, clipService, storageService, imageRepository)
+		imageService = imageservice.New(logger, imageservice.Config{
+			ModelName:              viper.GetString("CLIP_MODEL_NAME"),
+			DefaultMinScore:        viper.GetFloat64("SEARCH_MIN_SCORE"),
+			StoreQueryImages:       viper.GetBool("SEARCH_STORE_QUERY_IMAGES"),
+			BatchSearchConcurrency: viper.GetInt("SEARCH_BATCH_CONCURRENCY"),
+			SuggestMinFrequency:    viper.GetInt("SEARCH_SUGGEST_MIN_FREQUENCY"),
+			Autotag:                autotagVocabulary,
+			ImportConcurrency:      viper.GetInt("IMAGE_IMPORT_CONCURRENCY"),
+			RetainReplacedFiles:    viper.GetBool("IMAGE_RETAIN_REPLACED_FILES"),
+			URLFetcher:             urlFetcher,
+			Jobs:                   jobWorker,
+		}, clipService, storageService, imageRepository)
 		imageEndpoint    = imageendpoint.New(imageService, logger)
 		imageHTTPHandler = imagetransport.NewHTTPHandler(imageEndpoint, logger)
 	)
 
+	libraryRepository := libraryrepository.NewPGRepository(logger, db)
+
+	var (
+		libraryService     = libraryservice.New(logger, storageService, libraryRepository)
+		libraryEndpoint    = libraryendpoint.New(libraryService, logger)
+		libraryHTTPHandler = librarytransport.NewHTTPHandler(libraryEndpoint, logger)
+	)
+
+	var (
+		savedSearchService     = savedsearchservice.New(logger, viper.GetString("CLIP_MODEL_NAME"), clipService, storageService, savedSearchRepository)
+		savedSearchEndpoint    = savedsearchendpoint.New(savedSearchService, logger)
+		savedSearchHTTPHandler = savedsearchtransport.NewHTTPHandler(savedSearchEndpoint, logger)
+	)
+
+	var (
+		webhookService     = webhookservice.New(logger, webhookDispatcher, webhookRepository)
+		webhookEndpoint    = webhookendpoint.New(webhookService, logger)
+		webhookHTTPHandler = webhooktransport.NewHTTPHandler(webhookEndpoint, logger)
+	)
+
+	var (
+		jobService     = jobservice.New(logger, jobRepository)
+		jobEndpoint    = jobendpoint.New(jobService, logger)
+		jobHTTPHandler = jobtransport.NewHTTPHandler(jobEndpoint, logger)
+	)
+
 	httpHandler := http.NewServeMux()
 
 	httpHandler.Handle("/images", imageHTTPHandler)
 	httpHandler.Handle("/images/", imageHTTPHandler)
+	httpHandler.Handle("/searches", imageHTTPHandler)
+	httpHandler.Handle("/searches/", imageHTTPHandler)
+	httpHandler.Handle("/autotag/", imageHTTPHandler)
+	httpHandler.Handle("/classify", imageHTTPHandler)
+	httpHandler.Handle("/tags", libraryHTTPHandler)
+	httpHandler.Handle("/tags/", libraryHTTPHandler)
+	httpHandler.Handle("/collections", libraryHTTPHandler)
+	httpHandler.Handle("/collections/", libraryHTTPHandler)
+	httpHandler.Handle("/saved-searches", savedSearchHTTPHandler)
+	httpHandler.Handle("/saved-searches/", savedSearchHTTPHandler)
+	httpHandler.Handle("/webhooks", webhookHTTPHandler)
+	httpHandler.Handle("/webhooks/", webhookHTTPHandler)
+	httpHandler.Handle("/jobs/", jobHTTPHandler)
 	httpHandler.Handle("/storage/", storageHTTPHandler)
 	httpHandler.Handle("/openapi.json", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -136,5 +382,54 @@ func main() {
 			_ = httpListener.Close()
 		})
 	}
+	{
+		ctx, cancel := context.WithCancel(ctx)
+		g.Add(func() error {
+			return webhookDispatcher.Run(ctx)
+		}, func(error) {
+			cancel()
+		})
+	}
+	{
+		ctx, cancel := context.WithCancel(ctx)
+		g.Add(func() error {
+			return imageMatcher.Run(ctx)
+		}, func(error) {
+			cancel()
+		})
+	}
+	{
+		ctx, cancel := context.WithCancel(ctx)
+		g.Add(func() error {
+			return jobWorker.Run(ctx)
+		}, func(error) {
+			cancel()
+		})
+	}
+	{
+		// Images indexed before uploads were deduplicated get their content hashes in the
+		// background. The actor outlives the backfill, since its return would stop the group.
+		ctx, cancel := context.WithCancel(ctx)
+		g.Add(func() error {
+			if err := imageservice.BackfillContentHashes(ctx, logger, storageService, imageRepository); err != nil && ctx.Err() == nil {
+				logger.Log("content_hash", "backfill", "error", err)
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		}, func(error) {
+			cancel()
+		})
+	}
+	{
+		ctx, cancel := context.WithCancel(ctx)
+		g.Add(func() error {
+			return outboxRelay.Run(ctx)
+		}, func(error) {
+			cancel()
+			if natsServer != nil {
+				natsServer.Shutdown()
+			}
+		})
+	}
 	logger.Log("exit", g.Run())
 }
@@ -0,0 +1,45 @@
+-- Write your migrate up statements here
+CREATE TABLE search_query_results (
+    search_query_id UUID NOT NULL,
+    rank INTEGER NOT NULL,
+    image_id UUID NOT NULL,
+    distance DOUBLE PRECISION NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (search_query_id, rank),
+    FOREIGN KEY (search_query_id) REFERENCES search_queries(id) ON DELETE CASCADE,
+    FOREIGN KEY (image_id) REFERENCES images(id) ON DELETE CASCADE,
+    UNIQUE (search_query_id, image_id)
+);
+
+CREATE INDEX search_query_results_image_id_idx ON search_query_results (image_id);
+
+INSERT INTO search_query_results (search_query_id, rank, image_id, distance, score, created_at)
+SELECT id, 1, result_image_id, distance, 1 - distance, created_at
+FROM (
+    SELECT q.id, q.result_image_id, q.created_at, COALESCE((
+        SELECT MIN(e.embedding <=> q.query_embedding)
+        FROM image_embeddings e
+        WHERE e.image_id = q.result_image_id AND e.model_name = q.model_name
+    ), 1) AS distance
+    FROM search_queries q
+) q;
+
+ALTER TABLE search_queries DROP COLUMN result_image_id;
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+ALTER TABLE search_queries ADD COLUMN result_image_id UUID REFERENCES images(id) ON DELETE CASCADE;
+
+UPDATE search_queries q SET result_image_id = r.image_id
+FROM search_query_results r
+WHERE r.search_query_id = q.id AND r.rank = 1;
+
+DELETE FROM search_queries WHERE result_image_id IS NULL;
+
+ALTER TABLE search_queries ALTER COLUMN result_image_id SET NOT NULL;
+
+DROP INDEX IF EXISTS search_query_results_image_id_idx;
+DROP TABLE IF EXISTS search_query_results;
@@ -0,0 +1,8 @@
+-- Write your migrate up statements here
+ALTER TABLE search_queries ADD COLUMN max_distance DOUBLE PRECISION NOT NULL DEFAULT 2;
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+ALTER TABLE search_queries DROP COLUMN IF EXISTS max_distance;
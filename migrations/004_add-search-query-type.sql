@@ -0,0 +1,16 @@
+-- Write your migrate up statements here
+ALTER TABLE search_queries
+    ADD COLUMN query_type VARCHAR(20) NOT NULL DEFAULT 'TEXT' CHECK (query_type IN ('TEXT', 'IMAGE')),
+    ADD COLUMN query_storage_provider VARCHAR(30),
+    ADD COLUMN query_storage_key VARCHAR(255);
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DELETE FROM search_queries WHERE query_type <> 'TEXT';
+
+ALTER TABLE search_queries
+    DROP COLUMN IF EXISTS query_storage_key,
+    DROP COLUMN IF EXISTS query_storage_provider,
+    DROP COLUMN IF EXISTS query_type;
@@ -0,0 +1,18 @@
+-- Write your migrate up statements here
+ALTER TABLE search_queries DROP CONSTRAINT search_queries_query_type_check;
+
+ALTER TABLE search_queries
+    ADD CONSTRAINT search_queries_query_type_check CHECK (query_type IN ('TEXT', 'IMAGE', 'SIMILAR')),
+    ADD COLUMN query_image_id UUID REFERENCES images(id) ON DELETE CASCADE;
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DELETE FROM search_queries WHERE query_type = 'SIMILAR';
+
+ALTER TABLE search_queries DROP CONSTRAINT search_queries_query_type_check;
+
+ALTER TABLE search_queries
+    DROP COLUMN IF EXISTS query_image_id,
+    ADD CONSTRAINT search_queries_query_type_check CHECK (query_type IN ('TEXT', 'IMAGE'));
@@ -0,0 +1,18 @@
+-- Write your migrate up statements here
+ALTER TABLE search_queries DROP CONSTRAINT search_queries_query_type_check;
+
+ALTER TABLE search_queries
+    ADD CONSTRAINT search_queries_query_type_check CHECK (query_type IN ('TEXT', 'IMAGE', 'SIMILAR', 'COMPOSED')),
+    ADD COLUMN query_terms JSONB;
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DELETE FROM search_queries WHERE query_type = 'COMPOSED';
+
+ALTER TABLE search_queries DROP CONSTRAINT search_queries_query_type_check;
+
+ALTER TABLE search_queries
+    DROP COLUMN IF EXISTS query_terms,
+    ADD CONSTRAINT search_queries_query_type_check CHECK (query_type IN ('TEXT', 'IMAGE', 'SIMILAR'));
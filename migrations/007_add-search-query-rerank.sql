@@ -0,0 +1,12 @@
+-- Write your migrate up statements here
+ALTER TABLE search_queries
+    ADD COLUMN rerank_strategy VARCHAR(20) CHECK (rerank_strategy IN ('MMR')),
+    ADD COLUMN rerank_params JSONB;
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+ALTER TABLE search_queries
+    DROP COLUMN IF EXISTS rerank_params,
+    DROP COLUMN IF EXISTS rerank_strategy;
@@ -0,0 +1,39 @@
+-- Write your migrate up statements here
+ALTER TABLE search_queries DROP CONSTRAINT search_queries_query_type_check;
+
+ALTER TABLE search_queries
+    ADD CONSTRAINT search_queries_query_type_check CHECK (query_type IN ('TEXT', 'IMAGE', 'SIMILAR', 'COMPOSED', 'REFINED')),
+    ADD COLUMN parent_id UUID REFERENCES search_queries(id) ON DELETE SET NULL;
+
+CREATE INDEX search_queries_parent_id_idx ON search_queries (parent_id);
+
+-- Feedback can now rate a single result of a search; a NULL image_id rates the search as a whole.
+ALTER TABLE search_feedbacks DROP CONSTRAINT search_feedbacks_search_query_id_key;
+
+ALTER TABLE search_feedbacks
+    ADD COLUMN image_id UUID,
+    ADD CONSTRAINT search_feedbacks_search_query_id_image_id_key UNIQUE NULLS NOT DISTINCT (search_query_id, image_id),
+    ADD CONSTRAINT search_feedbacks_search_query_result_fkey FOREIGN KEY (search_query_id, image_id)
+        REFERENCES search_query_results(search_query_id, image_id) ON DELETE CASCADE;
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DELETE FROM search_feedbacks WHERE image_id IS NOT NULL;
+
+ALTER TABLE search_feedbacks
+    DROP CONSTRAINT search_feedbacks_search_query_result_fkey,
+    DROP CONSTRAINT search_feedbacks_search_query_id_image_id_key,
+    DROP COLUMN image_id,
+    ADD CONSTRAINT search_feedbacks_search_query_id_key UNIQUE (search_query_id);
+
+DELETE FROM search_queries WHERE query_type = 'REFINED';
+
+DROP INDEX IF EXISTS search_queries_parent_id_idx;
+
+ALTER TABLE search_queries DROP CONSTRAINT search_queries_query_type_check;
+
+ALTER TABLE search_queries
+    DROP COLUMN IF EXISTS parent_id,
+    ADD CONSTRAINT search_queries_query_type_check CHECK (query_type IN ('TEXT', 'IMAGE', 'SIMILAR', 'COMPOSED'));
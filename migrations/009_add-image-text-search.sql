@@ -0,0 +1,34 @@
+-- Write your migrate up statements here
+ALTER TABLE images
+    ADD COLUMN original_filename VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN title TEXT NOT NULL DEFAULT '',
+    ADD COLUMN description TEXT NOT NULL DEFAULT '';
+
+-- Uploaded objects are keyed images/<id>/<original filename>.
+UPDATE images SET original_filename = regexp_replace(storage_key, '^.*/', '');
+
+-- The 'simple' configuration does no stemming, so codes and filenames match as typed. The
+-- filename is indexed both whole and split on punctuation so "IMG_0042.jpg" matches "0042".
+ALTER TABLE images ADD COLUMN search_document tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', original_filename || ' ' || regexp_replace(original_filename, '[^[:alnum:]]+', ' ', 'g')), 'A') ||
+    setweight(to_tsvector('simple', title), 'A') ||
+    setweight(to_tsvector('simple', description), 'B')
+) STORED;
+
+CREATE INDEX images_search_document_idx ON images USING gin (search_document);
+
+ALTER TABLE search_queries ADD COLUMN hybrid_params JSONB;
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+ALTER TABLE search_queries DROP COLUMN IF EXISTS hybrid_params;
+
+DROP INDEX IF EXISTS images_search_document_idx;
+
+ALTER TABLE images
+    DROP COLUMN IF EXISTS search_document,
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS title,
+    DROP COLUMN IF EXISTS original_filename;
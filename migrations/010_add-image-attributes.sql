@@ -0,0 +1,32 @@
+-- Write your migrate up statements here
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Attributes are recorded at ingest; images uploaded before this migration leave them NULL
+-- and never match a filter on them.
+ALTER TABLE images
+    ADD COLUMN content_type VARCHAR(255),
+    ADD COLUMN file_size BIGINT,
+    ADD COLUMN width INTEGER,
+    ADD COLUMN height INTEGER;
+
+CREATE INDEX images_created_at_idx ON images (created_at);
+CREATE INDEX images_content_type_idx ON images (content_type);
+CREATE INDEX images_original_filename_trgm_idx ON images USING gin (original_filename gin_trgm_ops);
+
+ALTER TABLE search_queries ADD COLUMN filters JSONB;
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+ALTER TABLE search_queries DROP COLUMN IF EXISTS filters;
+
+DROP INDEX IF EXISTS images_original_filename_trgm_idx;
+DROP INDEX IF EXISTS images_content_type_idx;
+DROP INDEX IF EXISTS images_created_at_idx;
+
+ALTER TABLE images
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS file_size,
+    DROP COLUMN IF EXISTS content_type;
@@ -0,0 +1,49 @@
+-- Write your migrate up statements here
+CREATE TABLE tags (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT tags_name_key UNIQUE (name)
+);
+
+CREATE TABLE collections (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT collections_name_key UNIQUE (name)
+);
+
+CREATE TABLE image_tags (
+    image_id UUID NOT NULL,
+    tag_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (image_id, tag_id),
+    CONSTRAINT image_tags_image_id_fkey FOREIGN KEY (image_id) REFERENCES images(id) ON DELETE CASCADE,
+    CONSTRAINT image_tags_tag_id_fkey FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX image_tags_tag_id_idx ON image_tags (tag_id, image_id);
+
+CREATE TABLE collection_images (
+    collection_id UUID NOT NULL,
+    image_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (collection_id, image_id),
+    CONSTRAINT collection_images_collection_id_fkey FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+    CONSTRAINT collection_images_image_id_fkey FOREIGN KEY (image_id) REFERENCES images(id) ON DELETE CASCADE
+);
+
+CREATE INDEX collection_images_image_id_idx ON collection_images (image_id);
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DROP INDEX IF EXISTS collection_images_image_id_idx;
+DROP TABLE IF EXISTS collection_images;
+DROP INDEX IF EXISTS image_tags_tag_id_idx;
+DROP TABLE IF EXISTS image_tags;
+DROP TABLE IF EXISTS collections;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,38 @@
+-- Write your migrate up statements here
+-- Tags attached by zero-shot classification record the similarity and the model behind it;
+-- tags added by hand leave both NULL and are never replaced by a re-tagging run.
+ALTER TABLE image_tags
+    ADD COLUMN score DOUBLE PRECISION,
+    ADD COLUMN model_name VARCHAR(30),
+    ADD CONSTRAINT image_tags_score_model_name_check CHECK ((score IS NULL) = (model_name IS NULL));
+
+CREATE TABLE autotag_runs (
+    id UUID PRIMARY KEY,
+    model_name VARCHAR(30) NOT NULL,
+    vocabulary_hash VARCHAR(64) NOT NULL,
+    status VARCHAR(20) CHECK (status IN ('RUNNING', 'COMPLETED', 'FAILED')) NOT NULL,
+    total_images INTEGER NOT NULL DEFAULT 0,
+    processed_images INTEGER NOT NULL DEFAULT 0,
+    tagged_images INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+-- At most one run re-tags the library at a time.
+CREATE UNIQUE INDEX autotag_runs_running_idx ON autotag_runs (status) WHERE status = 'RUNNING';
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DROP INDEX IF EXISTS autotag_runs_running_idx;
+DROP TABLE IF EXISTS autotag_runs;
+
+DELETE FROM image_tags WHERE model_name IS NOT NULL;
+
+ALTER TABLE image_tags
+    DROP CONSTRAINT IF EXISTS image_tags_score_model_name_check,
+    DROP COLUMN IF EXISTS model_name,
+    DROP COLUMN IF EXISTS score;
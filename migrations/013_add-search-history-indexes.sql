@@ -0,0 +1,13 @@
+-- Write your migrate up statements here
+-- Search history is listed newest first and paged by (created_at, id).
+CREATE INDEX search_queries_created_at_id_idx ON search_queries (created_at DESC, id DESC);
+CREATE INDEX search_queries_model_name_idx ON search_queries (model_name);
+CREATE INDEX search_queries_query_text_trgm_idx ON search_queries USING gin (query_text gin_trgm_ops);
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DROP INDEX IF EXISTS search_queries_query_text_trgm_idx;
+DROP INDEX IF EXISTS search_queries_model_name_idx;
+DROP INDEX IF EXISTS search_queries_created_at_id_idx;
@@ -0,0 +1,13 @@
+-- Write your migrate up statements here
+-- Completions match lower(query_text) LIKE 'prefix%', which text_pattern_ops serves regardless
+-- of the database collation.
+CREATE INDEX search_queries_query_text_prefix_idx ON search_queries (lower(query_text) text_pattern_ops) WHERE query_type = 'TEXT';
+
+CREATE INDEX search_queries_query_embedding_idx ON search_queries USING hnsw (query_embedding vector_cosine_ops);
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DROP INDEX IF EXISTS search_queries_query_embedding_idx;
+DROP INDEX IF EXISTS search_queries_query_text_prefix_idx;
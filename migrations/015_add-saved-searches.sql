@@ -0,0 +1,43 @@
+-- Write your migrate up statements here
+-- A saved search keeps the embedding it was created with, so later changes to the image or
+-- search it was created from do not change what it matches.
+CREATE TABLE saved_searches (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    model_name VARCHAR(30) NOT NULL,
+    query_type VARCHAR(20) CHECK (query_type IN ('TEXT', 'IMAGE', 'SEARCH')) NOT NULL,
+    query_text VARCHAR(255) NOT NULL DEFAULT '',
+    query_image_id UUID REFERENCES images(id) ON DELETE SET NULL,
+    query_search_id UUID REFERENCES search_queries(id) ON DELETE SET NULL,
+    query_embedding vector(512) NOT NULL,
+    min_score DOUBLE PRECISION NOT NULL,
+    callback_url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX saved_searches_model_name_idx ON saved_searches (model_name);
+
+CREATE TABLE saved_search_matches (
+    id UUID PRIMARY KEY,
+    saved_search_id UUID NOT NULL,
+    image_id UUID NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    notified_at TIMESTAMPTZ,
+    notify_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT saved_search_matches_saved_search_id_fkey FOREIGN KEY (saved_search_id) REFERENCES saved_searches(id) ON DELETE CASCADE,
+    CONSTRAINT saved_search_matches_image_id_fkey FOREIGN KEY (image_id) REFERENCES images(id) ON DELETE CASCADE,
+    UNIQUE (saved_search_id, image_id)
+);
+
+CREATE INDEX saved_search_matches_image_id_idx ON saved_search_matches (image_id);
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DROP INDEX IF EXISTS saved_search_matches_image_id_idx;
+DROP TABLE IF EXISTS saved_search_matches;
+DROP INDEX IF EXISTS saved_searches_model_name_idx;
+DROP TABLE IF EXISTS saved_searches;
@@ -0,0 +1,16 @@
+-- Write your migrate up statements here
+-- Deleting an image used to delete every search made with it as the query, along with that
+-- search's results and feedback. The query embedding is stored with the search, so the search
+-- stays meaningful without the image. Results and feedback on the deleted image itself still
+-- cascade.
+ALTER TABLE search_queries
+    DROP CONSTRAINT search_queries_query_image_id_fkey,
+    ADD CONSTRAINT search_queries_query_image_id_fkey FOREIGN KEY (query_image_id) REFERENCES images(id) ON DELETE SET NULL;
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+ALTER TABLE search_queries
+    DROP CONSTRAINT search_queries_query_image_id_fkey,
+    ADD CONSTRAINT search_queries_query_image_id_fkey FOREIGN KEY (query_image_id) REFERENCES images(id) ON DELETE CASCADE;
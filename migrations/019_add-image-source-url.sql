@@ -0,0 +1,8 @@
+-- Write your migrate up statements here
+ALTER TABLE images ADD COLUMN source_url TEXT;
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+ALTER TABLE images DROP COLUMN IF EXISTS source_url;
@@ -0,0 +1,27 @@
+-- Write your migrate up statements here
+-- A claimed job is RUNNING with next_attempt_at as its lease. A worker that dies mid-attempt
+-- leaves the lease to expire, after which the job is claimed again.
+CREATE TABLE jobs (
+    id UUID PRIMARY KEY,
+    type VARCHAR(50) NOT NULL,
+    status VARCHAR(20) CHECK (status IN ('QUEUED', 'RUNNING', 'SUCCEEDED', 'FAILED')) NOT NULL DEFAULT 'QUEUED',
+    payload JSONB NOT NULL,
+    result JSONB,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ DEFAULT now(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX jobs_claimable_idx ON jobs (next_attempt_at) WHERE status IN ('QUEUED', 'RUNNING');
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DROP INDEX IF EXISTS jobs_claimable_idx;
+DROP TABLE IF EXISTS jobs;
@@ -0,0 +1,13 @@
+-- Write your migrate up statements here
+-- Hex SHA-256 of the stored file. Images indexed before it was recorded have none until the
+-- backfill run at startup hashes them.
+ALTER TABLE images ADD COLUMN content_hash CHAR(64);
+
+CREATE UNIQUE INDEX images_content_hash_key ON images (content_hash);
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DROP INDEX IF EXISTS images_content_hash_key;
+ALTER TABLE images DROP COLUMN IF EXISTS content_hash;
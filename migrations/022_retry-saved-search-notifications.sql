@@ -0,0 +1,18 @@
+-- Write your migrate up statements here
+-- A match is notified while next_notify_at is set; claiming it pushes next_notify_at out as a
+-- lease. It is cleared once the match is notified or its attempts run out.
+ALTER TABLE saved_search_matches ADD COLUMN notify_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE saved_search_matches ADD COLUMN next_notify_at TIMESTAMPTZ;
+
+-- Notifications that failed or were lost in a restart before they were retried get retried.
+UPDATE saved_search_matches SET next_notify_at = now() WHERE notified_at IS NULL;
+
+CREATE INDEX saved_search_matches_next_notify_at_idx ON saved_search_matches (next_notify_at) WHERE next_notify_at IS NOT NULL;
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DROP INDEX IF EXISTS saved_search_matches_next_notify_at_idx;
+ALTER TABLE saved_search_matches DROP COLUMN IF EXISTS next_notify_at;
+ALTER TABLE saved_search_matches DROP COLUMN IF EXISTS notify_attempts;
@@ -0,0 +1,21 @@
+-- Write your migrate up statements here
+-- Images the content hash backfill left without a hash, so that later runs do not download them
+-- again: their file is missing, or their content is already indexed as duplicate_of. Deleting
+-- that image lifts the skip, letting the next run hash the image.
+CREATE TABLE image_content_hash_skips (
+    image_id UUID PRIMARY KEY,
+    reason TEXT NOT NULL,
+    duplicate_of UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT image_content_hash_skips_image_id_fkey FOREIGN KEY (image_id) REFERENCES images(id) ON DELETE CASCADE,
+    CONSTRAINT image_content_hash_skips_duplicate_of_fkey FOREIGN KEY (duplicate_of) REFERENCES images(id) ON DELETE CASCADE
+);
+
+CREATE INDEX image_content_hash_skips_duplicate_of_idx ON image_content_hash_skips (duplicate_of);
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DROP INDEX IF EXISTS image_content_hash_skips_duplicate_of_idx;
+DROP TABLE IF EXISTS image_content_hash_skips;
@@ -0,0 +1,15 @@
+-- Write your migrate up statements here
+-- Deleting an image used to delete every search result naming it, and through them the feedback
+-- on those results. The results now keep their image ID and rank, so the rankings and feedback of
+-- the searches that found the image survive. Reading a search back leaves out results whose image
+-- is gone.
+ALTER TABLE search_query_results DROP CONSTRAINT search_query_results_image_id_fkey;
+
+---- create above / drop below ----
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
+DELETE FROM search_query_results r WHERE NOT EXISTS (SELECT 1 FROM images i WHERE i.id = r.image_id);
+
+ALTER TABLE search_query_results
+    ADD CONSTRAINT search_query_results_image_id_fkey FOREIGN KEY (image_id) REFERENCES images(id) ON DELETE CASCADE;
@@ -62,11 +62,17 @@ func NewInternalError(err error) ServiceError {
 	}
 }
 
-func ErrorEncoder(ctx context.Context, err error, w http.ResponseWriter) {
+// AsServiceError returns err as a ServiceError, treating errors that are not as internal.
+func AsServiceError(err error) ServiceError {
 	var svcerror ServiceError
 	if !errors.As(err, &svcerror) {
 		svcerror = NewInternalError(err)
 	}
+	return svcerror
+}
+
+func ErrorEncoder(ctx context.Context, err error, w http.ResponseWriter) {
+	svcerror := AsServiceError(err)
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(svcerror.GetStatusCode())
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/models"
 )
 
 type ErrImageNotFound struct {
@@ -20,6 +21,22 @@ func NewErrImageNotFound(id uuid.UUID) ServiceError {
 	}
 }
 
+type ErrImageContentExists struct {
+	BusinessError
+	ContentHash string `json:"-"`
+}
+
+func NewErrImageContentExists(contentHash string) ServiceError {
+	return &ErrImageContentExists{
+		BusinessError: BusinessError{
+			StatusCode: 409,
+			Code:       "IMAGE_CONTENT_EXISTS",
+			Detail:     fmt.Sprintf("Image with content hash %s already exists", contentHash),
+		},
+		ContentHash: contentHash,
+	}
+}
+
 type ErrNoImageAvailable struct {
 	BusinessError
 }
@@ -61,3 +78,231 @@ func NewErrSearchFeedbackAlreadyExists(queryID uuid.UUID) ServiceError {
 		},
 	}
 }
+
+type ErrInvalidSearchParameter struct {
+	BusinessError
+	Parameter string `json:"-"`
+}
+
+func NewErrInvalidSearchParameter(parameter string, reason string) ServiceError {
+	return &ErrInvalidSearchParameter{
+		BusinessError: BusinessError{
+			StatusCode: 400,
+			Code:       "INVALID_SEARCH_PARAMETER",
+			Detail:     fmt.Sprintf("Invalid search parameter %s: %s", parameter, reason),
+		},
+		Parameter: parameter,
+	}
+}
+
+type ErrNoRelevantImage struct {
+	BusinessError
+	Search *models.SearchWithResults `json:"search"`
+}
+
+func NewErrNoRelevantImage(search *models.SearchWithResults) ServiceError {
+	return &ErrNoRelevantImage{
+		BusinessError: BusinessError{
+			StatusCode: 404,
+			Code:       "NO_RELEVANT_IMAGE",
+			Detail:     fmt.Sprintf("No image within distance %g of the query", search.MaxDistance),
+		},
+		Search: search,
+	}
+}
+
+type ErrImageEmbeddingNotFound struct {
+	BusinessError
+}
+
+func NewErrImageEmbeddingNotFound(id uuid.UUID) ServiceError {
+	return &ErrImageEmbeddingNotFound{
+		BusinessError: BusinessError{
+			StatusCode: 404,
+			Code:       "IMAGE_EMBEDDING_NOT_FOUND",
+			Detail:     fmt.Sprintf("No embedding stored for image with id %s", id),
+		},
+	}
+}
+
+type ErrSearchResultNotFound struct {
+	BusinessError
+}
+
+func NewErrSearchResultNotFound(queryID uuid.UUID, imageID uuid.UUID) ServiceError {
+	return &ErrSearchResultNotFound{
+		BusinessError: BusinessError{
+			StatusCode: 404,
+			Code:       "SEARCH_RESULT_NOT_FOUND",
+			Detail:     fmt.Sprintf("Image with id %s is not a result of search query with id %s", imageID, queryID),
+		},
+	}
+}
+
+type ErrSearchFeedbackMissing struct {
+	BusinessError
+}
+
+func NewErrSearchFeedbackMissing(queryID uuid.UUID) ServiceError {
+	return &ErrSearchFeedbackMissing{
+		BusinessError: BusinessError{
+			StatusCode: 400,
+			Code:       "SEARCH_FEEDBACK_MISSING",
+			Detail:     fmt.Sprintf("Search query with id %s has no rated results to refine with", queryID),
+		},
+	}
+}
+
+type ErrSearchFeedbackCancelsQuery struct {
+	BusinessError
+}
+
+func NewErrSearchFeedbackCancelsQuery(queryID uuid.UUID) ServiceError {
+	return &ErrSearchFeedbackCancelsQuery{
+		BusinessError: BusinessError{
+			StatusCode: 400,
+			Code:       "SEARCH_FEEDBACK_CANCELS_QUERY",
+			Detail:     fmt.Sprintf("Rated results of search query with id %s cancel out its query", queryID),
+		},
+	}
+}
+
+type ErrAutotagDisabled struct {
+	BusinessError
+}
+
+func NewErrAutotagDisabled() ServiceError {
+	return &ErrAutotagDisabled{
+		BusinessError: BusinessError{
+			StatusCode: 409,
+			Code:       "AUTOTAG_DISABLED",
+			Detail:     "No auto-tagging vocabulary is configured",
+		},
+	}
+}
+
+type ErrAutotagRunInProgress struct {
+	BusinessError
+}
+
+func NewErrAutotagRunInProgress() ServiceError {
+	return &ErrAutotagRunInProgress{
+		BusinessError: BusinessError{
+			StatusCode: 409,
+			Code:       "AUTOTAG_RUN_IN_PROGRESS",
+			Detail:     "Another auto-tagging run is in progress",
+		},
+	}
+}
+
+type ErrAutotagRunNotFound struct {
+	BusinessError
+}
+
+func NewErrAutotagRunNotFound(id uuid.UUID) ServiceError {
+	return &ErrAutotagRunNotFound{
+		BusinessError: BusinessError{
+			StatusCode: 404,
+			Code:       "AUTOTAG_RUN_NOT_FOUND",
+			Detail:     fmt.Sprintf("Auto-tagging run with id %s not found", id),
+		},
+	}
+}
+
+type ErrInvalidClassifyParameter struct {
+	BusinessError
+	Parameter string `json:"-"`
+}
+
+func NewErrInvalidClassifyParameter(parameter string, reason string) ServiceError {
+	return &ErrInvalidClassifyParameter{
+		BusinessError: BusinessError{
+			StatusCode: 400,
+			Code:       "INVALID_CLASSIFY_PARAMETER",
+			Detail:     fmt.Sprintf("Invalid classify parameter %s: %s", parameter, reason),
+		},
+		Parameter: parameter,
+	}
+}
+
+type ErrImageFileNotDeleted struct {
+	BusinessError
+	ImageID uuid.UUID `json:"-"`
+}
+
+// NewErrImageFileNotDeleted reports an image that was deleted while its file was left in storage.
+func NewErrImageFileNotDeleted(id uuid.UUID, provider string, key string) ServiceError {
+	return &ErrImageFileNotDeleted{
+		BusinessError: BusinessError{
+			StatusCode: 502,
+			Code:       "IMAGE_FILE_NOT_DELETED",
+			Detail:     fmt.Sprintf("Image with id %s was deleted but its file could not be removed from %s: %s", id, provider, key),
+		},
+		ImageID: id,
+	}
+}
+
+type ErrInvalidImageParameter struct {
+	BusinessError
+	Parameter string `json:"-"`
+}
+
+func NewErrInvalidImageParameter(parameter string, reason string) ServiceError {
+	return &ErrInvalidImageParameter{
+		BusinessError: BusinessError{
+			StatusCode: 400,
+			Code:       "INVALID_IMAGE_PARAMETER",
+			Detail:     fmt.Sprintf("Invalid image parameter %s: %s", parameter, reason),
+		},
+		Parameter: parameter,
+	}
+}
+
+type ErrInvalidImportEntry struct {
+	BusinessError
+	Filename string `json:"-"`
+}
+
+func NewErrInvalidImportEntry(filename string, reason string) ServiceError {
+	return &ErrInvalidImportEntry{
+		BusinessError: BusinessError{
+			StatusCode: 400,
+			Code:       "INVALID_IMPORT_ENTRY",
+			Detail:     fmt.Sprintf("Invalid import entry %s: %s", filename, reason),
+		},
+		Filename: filename,
+	}
+}
+
+type ErrImageURLNotAllowed struct {
+	BusinessError
+	URL string `json:"-"`
+}
+
+// NewErrImageURLNotAllowed reports a URL that resolves to an address images may not be fetched from.
+func NewErrImageURLNotAllowed(url string, reason string) ServiceError {
+	return &ErrImageURLNotAllowed{
+		BusinessError: BusinessError{
+			StatusCode: 400,
+			Code:       "IMAGE_URL_NOT_ALLOWED",
+			Detail:     fmt.Sprintf("Image URL %s is not allowed: %s", url, reason),
+		},
+		URL: url,
+	}
+}
+
+type ErrImageFetchFailed struct {
+	BusinessError
+	URL string `json:"-"`
+}
+
+func NewErrImageFetchFailed(url string, reason string) ServiceError {
+	return &ErrImageFetchFailed{
+		BusinessError: BusinessError{
+			StatusCode: 502,
+			Code:       "IMAGE_FETCH_FAILED",
+			Detail:     fmt.Sprintf("Failed to fetch image from %s: %s", url, reason),
+		},
+		URL: url,
+	}
+}
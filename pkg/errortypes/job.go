@@ -0,0 +1,35 @@
+package errortypes
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type ErrJobNotFound struct {
+	BusinessError
+}
+
+func NewErrJobNotFound(id uuid.UUID) ServiceError {
+	return &ErrJobNotFound{
+		BusinessError: BusinessError{
+			StatusCode: 404,
+			Code:       "JOB_NOT_FOUND",
+			Detail:     fmt.Sprintf("Job with id %s not found", id),
+		},
+	}
+}
+
+type ErrAsyncIngestionDisabled struct {
+	BusinessError
+}
+
+func NewErrAsyncIngestionDisabled() ServiceError {
+	return &ErrAsyncIngestionDisabled{
+		BusinessError: BusinessError{
+			StatusCode: 409,
+			Code:       "ASYNC_INGESTION_DISABLED",
+			Detail:     "No job queue is configured for asynchronous ingestion",
+		},
+	}
+}
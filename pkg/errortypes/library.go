@@ -0,0 +1,79 @@
+package errortypes
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type ErrTagNotFound struct {
+	BusinessError
+}
+
+func NewErrTagNotFound(id uuid.UUID) ServiceError {
+	return &ErrTagNotFound{
+		BusinessError: BusinessError{
+			StatusCode: 404,
+			Code:       "TAG_NOT_FOUND",
+			Detail:     fmt.Sprintf("Tag with id %s not found", id),
+		},
+	}
+}
+
+type ErrTagAlreadyExists struct {
+	BusinessError
+}
+
+func NewErrTagAlreadyExists(name string) ServiceError {
+	return &ErrTagAlreadyExists{
+		BusinessError: BusinessError{
+			StatusCode: 409,
+			Code:       "TAG_ALREADY_EXISTS",
+			Detail:     fmt.Sprintf("Tag %q already exists", name),
+		},
+	}
+}
+
+type ErrCollectionNotFound struct {
+	BusinessError
+}
+
+func NewErrCollectionNotFound(id uuid.UUID) ServiceError {
+	return &ErrCollectionNotFound{
+		BusinessError: BusinessError{
+			StatusCode: 404,
+			Code:       "COLLECTION_NOT_FOUND",
+			Detail:     fmt.Sprintf("Collection with id %s not found", id),
+		},
+	}
+}
+
+type ErrCollectionAlreadyExists struct {
+	BusinessError
+}
+
+func NewErrCollectionAlreadyExists(name string) ServiceError {
+	return &ErrCollectionAlreadyExists{
+		BusinessError: BusinessError{
+			StatusCode: 409,
+			Code:       "COLLECTION_ALREADY_EXISTS",
+			Detail:     fmt.Sprintf("Collection %q already exists", name),
+		},
+	}
+}
+
+type ErrInvalidLibraryParameter struct {
+	BusinessError
+	Parameter string `json:"-"`
+}
+
+func NewErrInvalidLibraryParameter(parameter string, reason string) ServiceError {
+	return &ErrInvalidLibraryParameter{
+		BusinessError: BusinessError{
+			StatusCode: 400,
+			Code:       "INVALID_LIBRARY_PARAMETER",
+			Detail:     fmt.Sprintf("Invalid library parameter %s: %s", parameter, reason),
+		},
+		Parameter: parameter,
+	}
+}
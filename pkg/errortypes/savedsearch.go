@@ -0,0 +1,37 @@
+package errortypes
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type ErrSavedSearchNotFound struct {
+	BusinessError
+}
+
+func NewErrSavedSearchNotFound(id uuid.UUID) ServiceError {
+	return &ErrSavedSearchNotFound{
+		BusinessError: BusinessError{
+			StatusCode: 404,
+			Code:       "SAVED_SEARCH_NOT_FOUND",
+			Detail:     fmt.Sprintf("Saved search with id %s not found", id),
+		},
+	}
+}
+
+type ErrInvalidSavedSearchParameter struct {
+	BusinessError
+	Parameter string `json:"-"`
+}
+
+func NewErrInvalidSavedSearchParameter(parameter string, reason string) ServiceError {
+	return &ErrInvalidSavedSearchParameter{
+		BusinessError: BusinessError{
+			StatusCode: 400,
+			Code:       "INVALID_SAVED_SEARCH_PARAMETER",
+			Detail:     fmt.Sprintf("Invalid saved search parameter %s: %s", parameter, reason),
+		},
+		Parameter: parameter,
+	}
+}
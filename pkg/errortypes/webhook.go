@@ -0,0 +1,51 @@
+package errortypes
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type ErrWebhookNotFound struct {
+	BusinessError
+}
+
+func NewErrWebhookNotFound(id uuid.UUID) ServiceError {
+	return &ErrWebhookNotFound{
+		BusinessError: BusinessError{
+			StatusCode: 404,
+			Code:       "WEBHOOK_NOT_FOUND",
+			Detail:     fmt.Sprintf("Webhook with id %s not found", id),
+		},
+	}
+}
+
+type ErrWebhookDeliveryNotFound struct {
+	BusinessError
+}
+
+func NewErrWebhookDeliveryNotFound(id uuid.UUID) ServiceError {
+	return &ErrWebhookDeliveryNotFound{
+		BusinessError: BusinessError{
+			StatusCode: 404,
+			Code:       "WEBHOOK_DELIVERY_NOT_FOUND",
+			Detail:     fmt.Sprintf("Webhook delivery with id %s not found", id),
+		},
+	}
+}
+
+type ErrInvalidWebhookParameter struct {
+	BusinessError
+	Parameter string `json:"-"`
+}
+
+func NewErrInvalidWebhookParameter(parameter string, reason string) ServiceError {
+	return &ErrInvalidWebhookParameter{
+		BusinessError: BusinessError{
+			StatusCode: 400,
+			Code:       "INVALID_WEBHOOK_PARAMETER",
+			Detail:     fmt.Sprintf("Invalid webhook parameter %s: %s", parameter, reason),
+		},
+		Parameter: parameter,
+	}
+}
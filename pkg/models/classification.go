@@ -0,0 +1,18 @@
+package models
+
+import "github.com/google/uuid"
+
+// Classification is a zero-shot classification of an image over caller-supplied labels.
+type Classification struct {
+	ModelName string       `json:"model_name"`
+	ImageID   *uuid.UUID   `json:"image_id,omitempty"`
+	Labels    []LabelScore `json:"labels"`
+}
+
+// LabelScore is one candidate label. Score is the cosine similarity between the image and the
+// label prompts; Probability is the softmax of the scaled scores over all candidates.
+type LabelScore struct {
+	Label       string  `json:"label"`
+	Score       float64 `json:"score"`
+	Probability float64 `json:"probability"`
+}
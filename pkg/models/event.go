@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventTypeImageCreated    EventType = "image.created"
+	EventTypeImageUpdated    EventType = "image.updated"
+	EventTypeImageDeleted    EventType = "image.deleted"
+	EventTypeSearchCreated   EventType = "search.created"
+	EventTypeFeedbackCreated EventType = "feedback.created"
+)
+
+var EventTypes = []EventType{
+	EventTypeImageCreated,
+	EventTypeImageUpdated,
+	EventTypeImageDeleted,
+	EventTypeSearchCreated,
+	EventTypeFeedbackCreated,
+}
+
+// Event is a change to the library that integrations can subscribe to. Data is the created,
+// updated or deleted resource as the API returns it.
+type Event struct {
+	ID        uuid.UUID `json:"id"`
+	Type      EventType `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+const (
+	AggregateTypeImage  = "image"
+	AggregateTypeSearch = "search"
+)
+
+// OutboxMessage is an event recorded in the same transaction as the change it describes and
+// waiting to be published. Payload is the marshalled Event. Messages of one aggregate, such as a
+// search and its feedback, are published in the order they were recorded.
+type OutboxMessage struct {
+	ID            int64
+	EventID       uuid.UUID
+	EventType     EventType
+	AggregateType string
+	AggregateID   uuid.UUID
+	Payload       json.RawMessage
+	Attempts      int
+	LastError     *string
+	NextAttemptAt *time.Time
+	PublishedAt   *time.Time
+	CreatedAt     time.Time
+}
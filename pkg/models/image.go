@@ -7,23 +7,173 @@ import (
 )
 
 type Image struct {
-	ID              uuid.UUID `json:"id"`
-	StorageProvider string    `json:"storage_provider"`
-	StorageKey      string    `json:"storage_key"`
-	CreatedAt       time.Time `json:"created_at"`
-	URL             string    `json:"url"`
+	ID               uuid.UUID  `json:"id"`
+	StorageProvider  string     `json:"storage_provider"`
+	StorageKey       string     `json:"storage_key"`
+	OriginalFilename string     `json:"original_filename"`
+	Title            string     `json:"title,omitempty"`
+	Description      string     `json:"description,omitempty"`
+	ContentType      *string    `json:"content_type,omitempty"`
+	FileSize         *int64     `json:"file_size,omitempty"`
+	Width            *int       `json:"width,omitempty"`
+	Height           *int       `json:"height,omitempty"`
+	Tags             []ImageTag `json:"tags,omitempty"`
+	SourceURL        *string    `json:"source_url,omitempty"`
+	ContentHash      *string    `json:"content_hash,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	URL              string     `json:"url"`
+	// Duplicate is set on the existing image returned for an upload of content that is
+	// already indexed.
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
+// ImageMetadata is the user-supplied text of an image, indexed for lexical search together
+// with its original filename.
+type ImageMetadata struct {
+	Title       string
+	Description string
+}
+
+type QueryType string
+
+const (
+	QueryTypeText  QueryType = "TEXT"
+	QueryTypeImage QueryType = "IMAGE"
+	// QueryTypeSimilar searches with the stored embedding of an indexed image.
+	QueryTypeSimilar QueryType = "SIMILAR"
+	// QueryTypeComposed combines several weighted text and image terms into one query.
+	QueryTypeComposed QueryType = "COMPOSED"
+	// QueryTypeRefined is a parent search moved towards its positively rated results.
+	QueryTypeRefined QueryType = "REFINED"
+)
+
+type QueryImage struct {
+	StorageProvider string `json:"storage_provider"`
+	StorageKey      string `json:"storage_key"`
+	URL             string `json:"url"`
+}
+
+// QueryTerm is one weighted part of a composed query. Exactly one of Text, ImageID or Upload
+// is set; a negative Weight steers the query away from the term.
+type QueryTerm struct {
+	Type    QueryType          `json:"type"`
+	Text    string             `json:"text,omitempty"`
+	ImageID *uuid.UUID         `json:"image_id,omitempty"`
+	Image   *QueryImage        `json:"image,omitempty"`
+	Weight  float64            `json:"weight"`
+	Upload  *StorageFileStream `json:"-"`
+}
+
+type RerankStrategy string
+
+const (
+	// RerankStrategyMMR re-ranks candidates by Maximal Marginal Relevance.
+	RerankStrategyMMR RerankStrategy = "MMR"
+)
+
+type Rerank struct {
+	Strategy   RerankStrategy `json:"strategy"`
+	Diversity  float64        `json:"diversity"`
+	Candidates int            `json:"candidates"`
+}
+
+type SearchMode string
+
+const (
+	SearchModeVector SearchMode = "vector"
+	// SearchModeHybrid fuses vector results with full-text matches on image filenames and text.
+	SearchModeHybrid SearchMode = "hybrid"
+)
+
+// Hybrid records how lexical and vector results were fused by reciprocal rank fusion: a result
+// scores weight / (K + rank) in each list it appears in, taking the Candidates best of each.
+type Hybrid struct {
+	TextQuery    string  `json:"text_query"`
+	TextWeight   float64 `json:"text_weight"`
+	VectorWeight float64 `json:"vector_weight"`
+	K            int     `json:"k"`
+	Candidates   int     `json:"candidates"`
+}
+
+// SearchFilters restricts a search to images with matching attributes. FilenamePattern is a
+// case-insensitive glob where * matches any run of characters and ? a single character.
+type SearchFilters struct {
+	CreatedAfter    *time.Time `json:"created_after,omitempty"`
+	CreatedBefore   *time.Time `json:"created_before,omitempty"`
+	ContentTypes    []string   `json:"content_types,omitempty"`
+	FilenamePattern string     `json:"filename_pattern,omitempty"`
+	MinFileSize     *int64     `json:"min_file_size,omitempty"`
+	MaxFileSize     *int64     `json:"max_file_size,omitempty"`
+	MinWidth        *int       `json:"min_width,omitempty"`
+	MaxWidth        *int       `json:"max_width,omitempty"`
+	MinHeight       *int       `json:"min_height,omitempty"`
+	MaxHeight       *int       `json:"max_height,omitempty"`
+	// CollectionIDs keeps images in any of the collections; Tags requires all of the tags
+	// and ExcludeTags rejects images with any of them.
+	CollectionIDs []uuid.UUID `json:"collection_ids,omitempty"`
+	Tags          []string    `json:"tags,omitempty"`
+	ExcludeTags   []string    `json:"exclude_tags,omitempty"`
+}
+
+func (f SearchFilters) IsEmpty() bool {
+	return f.CreatedAfter == nil && f.CreatedBefore == nil && len(f.ContentTypes) == 0 && f.FilenamePattern == "" &&
+		f.MinFileSize == nil && f.MaxFileSize == nil && f.MinWidth == nil && f.MaxWidth == nil && f.MinHeight == nil && f.MaxHeight == nil &&
+		len(f.CollectionIDs) == 0 && len(f.Tags) == 0 && len(f.ExcludeTags) == 0
 }
 
 type Search struct {
-	ID        uuid.UUID `json:"id"`
-	ModelName string    `json:"model_name"`
-	QueryText string    `json:"query_text"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           uuid.UUID      `json:"id"`
+	ModelName    string         `json:"model_name"`
+	QueryType    QueryType      `json:"query_type"`
+	QueryText    string         `json:"query_text"`
+	QueryImage   *QueryImage    `json:"query_image,omitempty"`
+	QueryImageID *uuid.UUID     `json:"query_image_id,omitempty"`
+	QueryTerms   []QueryTerm    `json:"query_terms,omitempty"`
+	MaxDistance  float64        `json:"max_distance"`
+	Rerank       *Rerank        `json:"rerank,omitempty"`
+	Hybrid       *Hybrid        `json:"hybrid,omitempty"`
+	Filters      *SearchFilters `json:"filters,omitempty"`
+	ParentID     *uuid.UUID     `json:"parent_id,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+type SearchOptions struct {
+	Limit        int
+	Cursor       string
+	MinScore     *float64
+	MaxDistance  *float64
+	Diversity    *float64
+	Mode         SearchMode
+	TextWeight   *float64
+	VectorWeight *float64
+	Filters      SearchFilters
+}
+
+// BatchSearchQuery is one text search of a batch, run as GET /images would run it.
+type BatchSearchQuery struct {
+	Query   string
+	Options SearchOptions
+}
+
+// BatchSearchResult is the outcome of the query at Index of a batch: either its search or the
+// error it failed with.
+type BatchSearchResult struct {
+	Index  int
+	Search *SearchWithResults
+	Err    error
 }
 
-type SearchWithImage struct {
+type SearchResult struct {
+	Rank     int     `json:"rank"`
+	Distance float64 `json:"distance"`
+	Score    float64 `json:"score"`
+	Image    Image   `json:"image"`
+}
+
+type SearchWithResults struct {
 	Search
-	Image Image `json:"image"`
+	Results    []SearchResult `json:"results"`
+	NextCursor string         `json:"next_cursor,omitempty"`
 }
 
 type Rating string
@@ -34,12 +184,78 @@ const (
 )
 
 type SearchFeedback struct {
-	ID        uuid.UUID `json:"id"`
-	Rating    Rating    `json:"rating"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uuid.UUID  `json:"id"`
+	ImageID   *uuid.UUID `json:"image_id,omitempty"`
+	Rating    Rating     `json:"rating"`
+	CreatedAt time.Time  `json:"created_at"`
 }
 
 type SearchFeedbackWithQuery struct {
 	SearchFeedback
 	Query Search `json:"query"`
 }
+
+// SearchRecord is a recorded search together with the results shown for it and the feedback
+// it received.
+type SearchRecord struct {
+	SearchWithResults
+	Feedbacks []SearchFeedback `json:"feedbacks"`
+}
+
+// SearchHistoryFilters selects recorded searches. HasFeedback keeps searches with or without any
+// feedback, Rating those with at least one feedback of that rating, and QueryText those whose
+// text contains it, ignoring case.
+type SearchHistoryFilters struct {
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	ModelName     string
+	HasFeedback   *bool
+	Rating        Rating
+	QueryText     string
+}
+
+type SearchHistory struct {
+	Searches   []SearchRecord `json:"searches"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// QuerySuggestion is a past text query offered as a completion or as a similar query. Queries
+// differing only in case count as one. PositiveRatio is the share of positive feedback smoothed
+// towards 1/2, so that a query without feedback is neutral; Score is the frequency weighted by
+// twice that ratio. Similarity is only set for similar queries.
+type QuerySuggestion struct {
+	QueryText     string   `json:"query_text"`
+	Frequency     int      `json:"frequency"`
+	PositiveRatio float64  `json:"positive_ratio"`
+	Score         float64  `json:"score"`
+	Similarity    *float64 `json:"similarity,omitempty"`
+}
+
+// ImageDeletion is the outcome of deleting the image at Index of a bulk deletion. Err is set when
+// the image was not found, or when its row was deleted but its file could not be.
+type ImageDeletion struct {
+	Index int
+	ID    uuid.UUID
+	Err   error
+}
+
+type ImageImportStatus string
+
+const (
+	ImageImportStatusCreated ImageImportStatus = "CREATED"
+	// ImageImportStatusSkipped marks entries that are not images, such as metadata files.
+	ImageImportStatusSkipped ImageImportStatus = "SKIPPED"
+	ImageImportStatusFailed  ImageImportStatus = "FAILED"
+)
+
+// ImageImport is the outcome of the file at Index of an imported archive: the image created from
+// it, the Reason it was skipped along with the image it duplicates if that is why, or the error it
+// failed with.
+type ImageImport struct {
+	Index    int
+	Filename string
+	Status   ImageImportStatus
+	Image    *Image
+	Reason   string
+	Err      error
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type JobType string
+
+const (
+	// JobTypeCreateImage indexes an image uploaded or linked with POST /images?async=true.
+	JobTypeCreateImage JobType = "image.create"
+)
+
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "QUEUED"
+	JobStatusRunning   JobStatus = "RUNNING"
+	JobStatusSucceeded JobStatus = "SUCCEEDED"
+	JobStatusFailed    JobStatus = "FAILED"
+)
+
+// Job is background work queued in the database. Payload is what its handler is given and
+// Result what the handler returned once it succeeded. A failed attempt is retried at
+// NextAttemptAt until MaxAttempts is reached.
+type Job struct {
+	ID            uuid.UUID       `json:"id"`
+	Type          JobType         `json:"type"`
+	Status        JobStatus       `json:"status"`
+	Payload       json.RawMessage `json:"-"`
+	Result        json.RawMessage `json:"result,omitempty"`
+	Attempts      int             `json:"attempts"`
+	MaxAttempts   int             `json:"max_attempts"`
+	LastError     *string         `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	StartedAt     *time.Time      `json:"started_at,omitempty"`
+	FinishedAt    *time.Time      `json:"finished_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Tag struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ImageTag is a tag on an image. Tags attached by zero-shot classification carry the similarity
+// that qualified them and the model that computed it; tags added by hand carry neither.
+type ImageTag struct {
+	Name      string   `json:"name"`
+	Score     *float64 `json:"score,omitempty"`
+	ModelName *string  `json:"model_name,omitempty"`
+}
+
+type AutotagRunStatus string
+
+const (
+	AutotagRunStatusRunning   AutotagRunStatus = "RUNNING"
+	AutotagRunStatusCompleted AutotagRunStatus = "COMPLETED"
+	AutotagRunStatusFailed    AutotagRunStatus = "FAILED"
+)
+
+// AutotagRun re-tags every indexed image against the configured label vocabulary, identified
+// by VocabularyHash.
+type AutotagRun struct {
+	ID              uuid.UUID        `json:"id"`
+	ModelName       string           `json:"model_name"`
+	VocabularyHash  string           `json:"vocabulary_hash"`
+	Status          AutotagRunStatus `json:"status"`
+	TotalImages     int              `json:"total_images"`
+	ProcessedImages int              `json:"processed_images"`
+	TaggedImages    int              `json:"tagged_images"`
+	Error           *string          `json:"error,omitempty"`
+	StartedAt       time.Time        `json:"started_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+	FinishedAt      *time.Time       `json:"finished_at,omitempty"`
+}
+
+type Collection struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ImageList is a page of images ordered by ID. NextAfter is passed as after to fetch the next page.
+type ImageList struct {
+	Images    []Image    `json:"images"`
+	NextAfter *uuid.UUID `json:"next_after,omitempty"`
+}
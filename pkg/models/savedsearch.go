@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SavedSearchQueryType string
+
+const (
+	SavedSearchQueryTypeText SavedSearchQueryType = "TEXT"
+	// SavedSearchQueryTypeImage matches images similar to an indexed image.
+	SavedSearchQueryTypeImage SavedSearchQueryType = "IMAGE"
+	// SavedSearchQueryTypeSearch reuses the query embedding of a recorded search, such as a
+	// refined or composed one.
+	SavedSearchQueryTypeSearch SavedSearchQueryType = "SEARCH"
+)
+
+// SavedSearch is a query that newly ingested images are matched against. An image matches when
+// the cosine similarity of its embedding to the query reaches MinScore.
+type SavedSearch struct {
+	ID            uuid.UUID            `json:"id"`
+	Name          string               `json:"name"`
+	ModelName     string               `json:"model_name"`
+	QueryType     SavedSearchQueryType `json:"query_type"`
+	QueryText     string               `json:"query_text,omitempty"`
+	QueryImageID  *uuid.UUID           `json:"query_image_id,omitempty"`
+	QuerySearchID *uuid.UUID           `json:"query_search_id,omitempty"`
+	MinScore      float64              `json:"min_score"`
+	CallbackURL   string               `json:"callback_url,omitempty"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
+
+// SavedSearchQuery is what a saved search is created from: exactly one of a text, an indexed
+// image or a recorded search.
+type SavedSearchQuery struct {
+	Text     string
+	ImageID  *uuid.UUID
+	SearchID *uuid.UUID
+}
+
+// SavedSearchMatch records an ingested image that matched a saved search. NotifiedAt is set once
+// the match was handed to the notifier, and NotifyError holds the failure of the last attempt.
+// NextNotifyAt is set while another attempt is due.
+type SavedSearchMatch struct {
+	ID             uuid.UUID  `json:"id"`
+	SavedSearchID  uuid.UUID  `json:"saved_search_id"`
+	Score          float64    `json:"score"`
+	Image          Image      `json:"image"`
+	NotifyAttempts int        `json:"notify_attempts"`
+	NotifiedAt     *time.Time `json:"notified_at,omitempty"`
+	NotifyError    *string    `json:"notify_error,omitempty"`
+	NextNotifyAt   *time.Time `json:"next_notify_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type SavedSearchMatchList struct {
+	Matches   []SavedSearchMatch `json:"matches"`
+	NextAfter *uuid.UUID         `json:"next_after,omitempty"`
+}
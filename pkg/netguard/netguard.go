@@ -0,0 +1,161 @@
+// Package netguard keeps outgoing requests made on behalf of API callers, such as fetching an
+// image by URL or delivering a webhook, from reaching internal systems.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const dialTimeout = 10 * time.Second
+
+// blockedPrefixes are the special-purpose ranges that netip.Addr has no predicate for. Loopback,
+// private, link-local, multicast and unspecified addresses are checked with the predicates.
+// 6to4 and Teredo addresses embed an IPv4 address that relays would forward to.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001::/32"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// Guard only lets connections through to public addresses, unless a host or range is
+// allowlisted. The check is made on the address actually dialed, so a hostname that resolves
+// or redirects to an internal address is refused as well.
+type Guard struct {
+	hosts    map[string]bool
+	prefixes []netip.Prefix
+	dialer   *net.Dialer
+}
+
+// New returns a Guard. Each allowlist entry is either a hostname or a CIDR range; blank entries
+// are ignored.
+func New(allowlist []string) (*Guard, error) {
+	g := &Guard{hosts: map[string]bool{}}
+	for _, entry := range allowlist {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowlist range %q: %w", entry, err)
+			}
+			g.prefixes = append(g.prefixes, prefix.Masked())
+			continue
+		}
+		g.hosts[strings.ToLower(entry)] = true
+	}
+
+	g.dialer = &net.Dialer{Timeout: dialTimeout, Control: g.control}
+
+	return g, nil
+}
+
+// ErrAddressBlocked is returned by the dialer for an address that may not be connected to.
+type ErrAddressBlocked struct {
+	Addr netip.Addr
+}
+
+func (e *ErrAddressBlocked) Error() string {
+	return fmt.Sprintf("address %s is not allowed", e.Addr)
+}
+
+// ErrResponseStatus fails a request that was answered with a status other than 2xx. Peer names
+// what answered, such as "webhook".
+type ErrResponseStatus struct {
+	Peer       string
+	StatusCode int
+}
+
+func (e *ErrResponseStatus) Error() string {
+	return fmt.Sprintf("%s responded with status %d", e.Peer, e.StatusCode)
+}
+
+// Reason names the kind of failure of a request made through a Guard: the response status of an
+// ErrResponseStatus, "address not allowed", "request timed out" or "request failed". It is meant
+// for errors shown to whoever chose the URL, who should not learn from them how hosts behind it
+// answer.
+func Reason(err error) string {
+	var status *ErrResponseStatus
+	var blocked *ErrAddressBlocked
+	var netErr net.Error
+	switch {
+	case errors.As(err, &status):
+		return status.Error()
+	case errors.As(err, &blocked):
+		return "address not allowed"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "request timed out"
+	default:
+		return "request failed"
+	}
+}
+
+// DialContext connects like net.Dialer.DialContext, refusing addresses that are not allowed.
+// Allowlisted hostnames are dialed without the check.
+func (g *Guard) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if host, _, err := net.SplitHostPort(addr); err == nil && g.hosts[strings.ToLower(host)] {
+		return (&net.Dialer{Timeout: dialTimeout}).DialContext(ctx, network, addr)
+	}
+	return g.dialer.DialContext(ctx, network, addr)
+}
+
+// Transport returns an http.Transport that dials through the guard. Proxy is left unset: a
+// proxy would dial on our behalf, out of reach of the address check.
+func (g *Guard) Transport() *http.Transport {
+	return &http.Transport{
+		DialContext:         g.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// control runs after a hostname has been resolved and before the connection is made.
+func (g *Guard) control(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+
+	addr := addrPort.Addr().Unmap()
+	if g.Allowed(addr) {
+		return nil
+	}
+	return &ErrAddressBlocked{Addr: addr}
+}
+
+// Allowed reports whether addr may be connected to.
+func (g *Guard) Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	for _, prefix := range g.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
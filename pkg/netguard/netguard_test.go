@@ -0,0 +1,174 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestAllowed(t *testing.T) {
+	guard, err := New([]string{"minio", "10.1.0.0/16", " ", "fd00:1::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.215.14", want: true},
+		{addr: "2606:2800:21f:cb07:6820:80da:af6b:8b2c", want: true},
+
+		// Loopback and unspecified.
+		{addr: "127.0.0.1", want: false},
+		{addr: "127.255.255.254", want: false},
+		{addr: "::1", want: false},
+		{addr: "0.0.0.0", want: false},
+		{addr: "::", want: false},
+
+		// Private ranges.
+		{addr: "10.0.0.1", want: false},
+		{addr: "172.16.5.4", want: false},
+		{addr: "192.168.1.1", want: false},
+		{addr: "fd12:3456::1", want: false},
+
+		// Link-local, where cloud metadata services live.
+		{addr: "169.254.169.254", want: false},
+		{addr: "fe80::1", want: false},
+		{addr: "fd00:ec2::254", want: false},
+
+		// Special-purpose ranges.
+		{addr: "100.64.0.1", want: false},
+		{addr: "198.18.0.1", want: false},
+		{addr: "255.255.255.255", want: false},
+		{addr: "224.0.0.1", want: false},
+		{addr: "ff02::1", want: false},
+
+		// IPv6 addresses that reach IPv4 ones.
+		{addr: "::ffff:127.0.0.1", want: false},
+		{addr: "::ffff:169.254.169.254", want: false},
+		{addr: "::ffff:93.184.215.14", want: true},
+		{addr: "64:ff9b::7f00:1", want: false},
+		{addr: "2002:7f00:1::1", want: false},
+		{addr: "2001:0:4136:e378:8000:63bf:3fff:fdd2", want: false},
+
+		// Allowlisted ranges.
+		{addr: "10.1.2.3", want: true},
+		{addr: "::ffff:10.1.2.3", want: true},
+		{addr: "10.2.0.1", want: false},
+		{addr: "fd00:1::5", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := guard.Allowed(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("Allowed(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInvalidRange(t *testing.T) {
+	if _, err := New([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("New() accepted an invalid range")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		host      string
+		blocked   bool
+	}{
+		{name: "loopback is refused", host: "127.0.0.1", blocked: true},
+		{name: "a name resolving to loopback is refused", host: "localhost", blocked: true},
+		{name: "an allowlisted range is dialed", allowlist: []string{"127.0.0.0/8"}, host: "127.0.0.1"},
+		{name: "an allowlisted host is dialed", allowlist: []string{"LOCALHOST"}, host: "localhost"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard, err := New(tt.allowlist)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client := &http.Client{Transport: guard.Transport()}
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://"+net.JoinHostPort(tt.host, port), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			var blocked *ErrAddressBlocked
+			if got := errors.As(err, &blocked); got != tt.blocked {
+				t.Errorf("Do() error = %v, want blocked %v", err, tt.blocked)
+			}
+			if !tt.blocked && err != nil {
+				t.Errorf("Do() error = %v", err)
+			}
+		})
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "blocked address",
+			err:  &url.Error{Op: "Post", URL: "http://10.0.0.1", Err: &net.OpError{Op: "dial", Err: &ErrAddressBlocked{Addr: netip.MustParseAddr("10.0.0.1")}}},
+			want: "address not allowed",
+		},
+		{
+			name: "response status",
+			err:  fmt.Errorf("attempt failed: %w", &ErrResponseStatus{Peer: "webhook", StatusCode: 503}),
+			want: "webhook responded with status 503",
+		},
+		{
+			name: "timeout",
+			err:  &url.Error{Op: "Post", URL: "http://example.com", Err: timeoutError{}},
+			want: "request timed out",
+		},
+		{
+			name: "refused connection",
+			err:  &url.Error{Op: "Post", URL: "http://example.com:22", Err: errors.New("connect: connection refused")},
+			want: "request failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Reason(tt.err); got != tt.want {
+				t.Errorf("Reason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
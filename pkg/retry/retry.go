@@ -0,0 +1,86 @@
+// Package retry works off the queues kept in the database, such as webhook deliveries and jobs.
+// It polls for due items and spaces out the attempts of those that keep failing.
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Backoff spaces out the attempts of an item that keeps failing. The wait after the first failed
+// attempt is Base, and it doubles with every further failure up to Max.
+type Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// WithDefaults fills in an unset Base, and a Max below Base, from defaults.
+func (b Backoff) WithDefaults(defaults Backoff) Backoff {
+	if b.Base <= 0 {
+		b.Base = defaults.Base
+	}
+	if b.Max < b.Base {
+		b.Max = max(defaults.Max, b.Base)
+	}
+	return b
+}
+
+// Delay returns the wait after the given number of failed attempts.
+func (b Backoff) Delay(attempts int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempts && delay < b.Max; i++ {
+		delay *= 2
+	}
+	return min(delay, b.Max)
+}
+
+// Lease is how long a claim holds an item whose attempt is bounded by timeout. It is a little
+// longer than the attempt can take, so an item is only claimed again once the process attempting
+// it has died.
+func Lease(timeout time.Duration) time.Duration {
+	return timeout + time.Minute
+}
+
+// Poller drives the loop of a queue: it works off a batch of due items, goes straight on to the
+// next while batches come back full, and otherwise waits until it is woken or the poll interval
+// has passed.
+type Poller struct {
+	interval time.Duration
+	wakeup   chan struct{}
+}
+
+func NewPoller(interval time.Duration) *Poller {
+	return &Poller{
+		interval: interval,
+		wakeup:   make(chan struct{}, 1),
+	}
+}
+
+// Wake makes Run look for due items right away, for example after new ones were queued. It does
+// not block.
+func (p *Poller) Wake() {
+	select {
+	case p.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// Run calls poll until ctx is done. poll works off a batch of at most batchSize due items and
+// returns how many there were.
+func (p *Poller) Run(ctx context.Context, batchSize int, poll func(ctx context.Context) int) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if poll(ctx) == batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.wakeup:
+		case <-ticker.C:
+		}
+	}
+}
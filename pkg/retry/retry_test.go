@@ -0,0 +1,47 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{Base: 10 * time.Second, Max: time.Minute}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 4, want: time.Minute},
+		{attempts: 100, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := backoff.Delay(tt.attempts); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffWithDefaults(t *testing.T) {
+	defaults := Backoff{Base: time.Second, Max: time.Minute}
+
+	tests := []struct {
+		name    string
+		backoff Backoff
+		want    Backoff
+	}{
+		{name: "unset", want: defaults},
+		{name: "base above the default max", backoff: Backoff{Base: time.Hour}, want: Backoff{Base: time.Hour, Max: time.Hour}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.backoff.WithDefaults(defaults); got != tt.want {
+				t.Errorf("WithDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
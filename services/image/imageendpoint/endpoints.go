@@ -2,6 +2,7 @@ package imageendpoint
 
 import (
 	"context"
+	"io"
 
 	"github.com/go-kit/log"
 	"github.com/google/uuid"
@@ -12,11 +13,28 @@ import (
 )
 
 type Endpoints struct {
-	logger                 log.Logger
-	CreateImageEndpoint    endpoint.Endpoint
-	GetImageEndpoint       endpoint.Endpoint
-	SearchImageEndpoint    endpoint.Endpoint
-	SearchFeedbackEndpoint endpoint.Endpoint
+	logger                          log.Logger
+	CreateImageEndpoint             endpoint.Endpoint
+	GetImageEndpoint                endpoint.Endpoint
+	SearchImageEndpoint             endpoint.Endpoint
+	SearchComposedEndpoint          endpoint.Endpoint
+	SearchSimilarEndpoint           endpoint.Endpoint
+	SearchFeedbackEndpoint          endpoint.Endpoint
+	RefineSearchEndpoint            endpoint.Endpoint
+	StartAutotagRunEndpoint         endpoint.Endpoint
+	GetAutotagRunEndpoint           endpoint.Endpoint
+	ClassifyEndpoint                endpoint.Endpoint
+	SearchBatchEndpoint             endpoint.Endpoint
+	GetSearchEndpoint               endpoint.Endpoint
+	ListSearchesEndpoint            endpoint.Endpoint
+	SuggestQueriesEndpoint          endpoint.Endpoint
+	DeleteImageEndpoint             endpoint.Endpoint
+	DeleteImagesEndpoint            endpoint.Endpoint
+	ReplaceImageContentEndpoint     endpoint.Endpoint
+	ImportImagesEndpoint            endpoint.Endpoint
+	CreateImageFromURLEndpoint      endpoint.Endpoint
+	CreateImageAsyncEndpoint        endpoint.Endpoint
+	CreateImageFromURLAsyncEndpoint endpoint.Endpoint
 }
 
 func New(svc imageservice.Service, logger log.Logger) Endpoints {
@@ -25,6 +43,16 @@ func New(svc imageservice.Service, logger log.Logger) Endpoints {
 		searchEndpoint = MakeSearchImageEndpoint(svc)
 	}
 
+	var searchComposedEndpoint endpoint.Endpoint
+	{
+		searchComposedEndpoint = MakeSearchComposedEndpoint(svc)
+	}
+
+	var searchSimilarEndpoint endpoint.Endpoint
+	{
+		searchSimilarEndpoint = MakeSearchSimilarEndpoint(svc)
+	}
+
 	var createImageEndpoint endpoint.Endpoint
 	{
 		createImageEndpoint = MakeCreateImageEndpoint(svc)
@@ -40,12 +68,104 @@ func New(svc imageservice.Service, logger log.Logger) Endpoints {
 		searchFeedbackEndpoint = MakeSearchFeedbackEndpoint(svc)
 	}
 
+	var refineSearchEndpoint endpoint.Endpoint
+	{
+		refineSearchEndpoint = MakeRefineSearchEndpoint(svc)
+	}
+
+	var startAutotagRunEndpoint endpoint.Endpoint
+	{
+		startAutotagRunEndpoint = MakeStartAutotagRunEndpoint(svc)
+	}
+
+	var getAutotagRunEndpoint endpoint.Endpoint
+	{
+		getAutotagRunEndpoint = MakeGetAutotagRunEndpoint(svc)
+	}
+
+	var classifyEndpoint endpoint.Endpoint
+	{
+		classifyEndpoint = MakeClassifyEndpoint(svc)
+	}
+
+	var searchBatchEndpoint endpoint.Endpoint
+	{
+		searchBatchEndpoint = MakeSearchBatchEndpoint(svc)
+	}
+
+	var getSearchEndpoint endpoint.Endpoint
+	{
+		getSearchEndpoint = MakeGetSearchEndpoint(svc)
+	}
+
+	var listSearchesEndpoint endpoint.Endpoint
+	{
+		listSearchesEndpoint = MakeListSearchesEndpoint(svc)
+	}
+
+	var suggestQueriesEndpoint endpoint.Endpoint
+	{
+		suggestQueriesEndpoint = MakeSuggestQueriesEndpoint(svc)
+	}
+
+	var deleteImageEndpoint endpoint.Endpoint
+	{
+		deleteImageEndpoint = MakeDeleteImageEndpoint(svc)
+	}
+
+	var deleteImagesEndpoint endpoint.Endpoint
+	{
+		deleteImagesEndpoint = MakeDeleteImagesEndpoint(svc)
+	}
+
+	var replaceImageContentEndpoint endpoint.Endpoint
+	{
+		replaceImageContentEndpoint = MakeReplaceImageContentEndpoint(svc)
+	}
+
+	var importImagesEndpoint endpoint.Endpoint
+	{
+		importImagesEndpoint = MakeImportImagesEndpoint(svc)
+	}
+
+	var createImageFromURLEndpoint endpoint.Endpoint
+	{
+		createImageFromURLEndpoint = MakeCreateImageFromURLEndpoint(svc)
+	}
+
+	var createImageAsyncEndpoint endpoint.Endpoint
+	{
+		createImageAsyncEndpoint = MakeCreateImageAsyncEndpoint(svc)
+	}
+
+	var createImageFromURLAsyncEndpoint endpoint.Endpoint
+	{
+		createImageFromURLAsyncEndpoint = MakeCreateImageFromURLAsyncEndpoint(svc)
+	}
+
 	return Endpoints{
-		logger:                 logger,
-		CreateImageEndpoint:    createImageEndpoint,
-		GetImageEndpoint:       getImageEndpoint,
-		SearchImageEndpoint:    searchEndpoint,
-		SearchFeedbackEndpoint: searchFeedbackEndpoint,
+		logger:                          logger,
+		CreateImageEndpoint:             createImageEndpoint,
+		GetImageEndpoint:                getImageEndpoint,
+		SearchImageEndpoint:             searchEndpoint,
+		SearchComposedEndpoint:          searchComposedEndpoint,
+		SearchSimilarEndpoint:           searchSimilarEndpoint,
+		SearchFeedbackEndpoint:          searchFeedbackEndpoint,
+		RefineSearchEndpoint:            refineSearchEndpoint,
+		StartAutotagRunEndpoint:         startAutotagRunEndpoint,
+		GetAutotagRunEndpoint:           getAutotagRunEndpoint,
+		ClassifyEndpoint:                classifyEndpoint,
+		SearchBatchEndpoint:             searchBatchEndpoint,
+		GetSearchEndpoint:               getSearchEndpoint,
+		ListSearchesEndpoint:            listSearchesEndpoint,
+		SuggestQueriesEndpoint:          suggestQueriesEndpoint,
+		DeleteImageEndpoint:             deleteImageEndpoint,
+		DeleteImagesEndpoint:            deleteImagesEndpoint,
+		ReplaceImageContentEndpoint:     replaceImageContentEndpoint,
+		ImportImagesEndpoint:            importImagesEndpoint,
+		CreateImageFromURLEndpoint:      createImageFromURLEndpoint,
+		CreateImageAsyncEndpoint:        createImageAsyncEndpoint,
+		CreateImageFromURLAsyncEndpoint: createImageFromURLAsyncEndpoint,
 	}
 }
 
@@ -54,7 +174,7 @@ func MakeCreateImageEndpoint(svc imageservice.Service) endpoint.Endpoint {
 		req := request.(CreateImageRequest)
 		defer req.Closer()
 
-		resp, err := svc.CreateImage(ctx, req.Image)
+		resp, err := svc.CreateImage(ctx, req.Image, req.Metadata)
 
 		return CreateImageResponse{
 			V:   resp,
@@ -77,7 +197,7 @@ func MakeGetImageEndpoint(svc imageservice.Service) endpoint.Endpoint {
 func MakeSearchImageEndpoint(svc imageservice.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(SearchImageRequest)
-		resp, err := svc.SearchImage(ctx, req.Query)
+		resp, err := svc.SearchImage(ctx, req.Query, req.Options)
 		return SearchImageResponse{
 			V:   resp,
 			Err: err,
@@ -85,10 +205,34 @@ func MakeSearchImageEndpoint(svc imageservice.Service) endpoint.Endpoint {
 	}
 }
 
+func MakeSearchComposedEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(SearchComposedRequest)
+		defer req.Closer()
+
+		resp, err := svc.SearchComposed(ctx, req.Terms, req.Options)
+		return SearchComposedResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeSearchSimilarEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(SearchSimilarRequest)
+		resp, err := svc.SearchSimilarImages(ctx, req.ID, req.Options)
+		return SearchSimilarResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
 func MakeSearchFeedbackEndpoint(svc imageservice.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(SearchFeedbackRequest)
-		resp, err := svc.SearchFeedback(ctx, req.QueryID, req.Rating)
+		resp, err := svc.SearchFeedback(ctx, req.QueryID, req.ImageID, req.Rating)
 		return SearchFeedbackResponse{
 			V:   resp,
 			Err: err,
@@ -96,10 +240,185 @@ func MakeSearchFeedbackEndpoint(svc imageservice.Service) endpoint.Endpoint {
 	}
 }
 
+func MakeRefineSearchEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RefineSearchRequest)
+		resp, err := svc.RefineSearch(ctx, req.ID, req.Options)
+		return RefineSearchResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeStartAutotagRunEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		resp, err := svc.StartAutotagRun(ctx)
+		return StartAutotagRunResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeGetAutotagRunEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetAutotagRunRequest)
+		resp, err := svc.GetAutotagRun(ctx, req.ID)
+		return GetAutotagRunResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeClassifyEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ClassifyRequest)
+		defer req.Closer()
+
+		resp, err := svc.Classify(ctx, req.Image, req.ImageID, req.Labels, req.Templates)
+		return ClassifyResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeSearchBatchEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(SearchBatchRequest)
+		resp, err := svc.SearchBatch(ctx, req.Queries)
+		return SearchBatchResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeGetSearchEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetSearchRequest)
+		resp, err := svc.GetSearch(ctx, req.ID)
+		return GetSearchResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeListSearchesEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ListSearchesRequest)
+		resp, err := svc.ListSearches(ctx, req.Filters, req.Cursor, req.Limit)
+		return ListSearchesResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeSuggestQueriesEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(SuggestQueriesRequest)
+		resp, err := svc.SuggestQueries(ctx, req.Prefix, req.SimilarTo, req.Limit)
+		return SuggestQueriesResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeDeleteImageEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteImageRequest)
+		err := svc.DeleteImage(ctx, req.ID)
+		return DeleteImageResponse{
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeDeleteImagesEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteImagesRequest)
+		resp, err := svc.DeleteImages(ctx, req.IDs)
+		return DeleteImagesResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeReplaceImageContentEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ReplaceImageContentRequest)
+		defer req.Closer()
+
+		resp, err := svc.ReplaceImageContent(ctx, req.ID, req.Image)
+		return ReplaceImageContentResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeImportImagesEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ImportImagesRequest)
+		defer req.Closer()
+
+		resp, err := svc.ImportImages(ctx, req.Archive, req.Size)
+		return ImportImagesResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeCreateImageFromURLEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateImageFromURLRequest)
+		resp, err := svc.CreateImageFromURL(ctx, req.URL, req.Metadata)
+		return CreateImageFromURLResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeCreateImageAsyncEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateImageAsyncRequest)
+		defer req.Closer()
+
+		resp, err := svc.CreateImageAsync(ctx, req.Image, req.Metadata)
+		return CreateImageAsyncResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeCreateImageFromURLAsyncEndpoint(svc imageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateImageFromURLAsyncRequest)
+		resp, err := svc.CreateImageFromURLAsync(ctx, req.URL, req.Metadata)
+		return CreateImageFromURLAsyncResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
 var _ imageservice.Service = (*Endpoints)(nil)
 
-func (e *Endpoints) CreateImage(ctx context.Context, image *models.StorageFileStream) (*models.Image, error) {
-	resp, err := e.CreateImageEndpoint(ctx, image)
+func (e *Endpoints) CreateImage(ctx context.Context, image *models.StorageFileStream, metadata models.ImageMetadata) (*models.Image, error) {
+	resp, err := e.CreateImageEndpoint(ctx, CreateImageRequest{
+		Image:    image,
+		Metadata: metadata,
+		Closer:   func() error { return nil },
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -116,8 +435,11 @@ func (e *Endpoints) GetImage(ctx context.Context, id uuid.UUID) (*models.Image,
 	return response.V, response.Err
 }
 
-func (e *Endpoints) SearchImage(ctx context.Context, query string) (*models.SearchWithImage, error) {
-	resp, err := e.SearchImageEndpoint(ctx, query)
+func (e *Endpoints) SearchImage(ctx context.Context, query string, options models.SearchOptions) (*models.SearchWithResults, error) {
+	resp, err := e.SearchImageEndpoint(ctx, SearchImageRequest{
+		Query:   query,
+		Options: options,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -125,9 +447,35 @@ func (e *Endpoints) SearchImage(ctx context.Context, query string) (*models.Sear
 	return response.V, response.Err
 }
 
-func (e *Endpoints) SearchFeedback(ctx context.Context, query_id uuid.UUID, rating models.Rating) (*models.SearchFeedbackWithQuery, error) {
+func (e *Endpoints) SearchComposed(ctx context.Context, terms []models.QueryTerm, options models.SearchOptions) (*models.SearchWithResults, error) {
+	resp, err := e.SearchComposedEndpoint(ctx, SearchComposedRequest{
+		Terms:   terms,
+		Options: options,
+		Closer:  func() error { return nil },
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(SearchComposedResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) SearchSimilarImages(ctx context.Context, id uuid.UUID, options models.SearchOptions) (*models.SearchWithResults, error) {
+	resp, err := e.SearchSimilarEndpoint(ctx, SearchSimilarRequest{
+		ID:      id,
+		Options: options,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(SearchSimilarResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) SearchFeedback(ctx context.Context, query_id uuid.UUID, imageID *uuid.UUID, rating models.Rating) (*models.SearchFeedbackWithQuery, error) {
 	resp, err := e.SearchFeedbackEndpoint(ctx, SearchFeedbackRequest{
 		QueryID: query_id,
+		ImageID: imageID,
 		Rating:  rating,
 	})
 	if err != nil {
@@ -137,16 +485,214 @@ func (e *Endpoints) SearchFeedback(ctx context.Context, query_id uuid.UUID, rati
 	return response.V, response.Err
 }
 
+func (e *Endpoints) RefineSearch(ctx context.Context, id uuid.UUID, options models.SearchOptions) (*models.SearchWithResults, error) {
+	resp, err := e.RefineSearchEndpoint(ctx, RefineSearchRequest{
+		ID:      id,
+		Options: options,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(RefineSearchResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) StartAutotagRun(ctx context.Context) (*models.AutotagRun, error) {
+	resp, err := e.StartAutotagRunEndpoint(ctx, StartAutotagRunRequest{})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(StartAutotagRunResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) GetAutotagRun(ctx context.Context, id uuid.UUID) (*models.AutotagRun, error) {
+	resp, err := e.GetAutotagRunEndpoint(ctx, GetAutotagRunRequest{
+		ID: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(GetAutotagRunResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) Classify(ctx context.Context, image *models.StorageFileStream, imageID *uuid.UUID, labels []string, templates []string) (*models.Classification, error) {
+	resp, err := e.ClassifyEndpoint(ctx, ClassifyRequest{
+		Image:     image,
+		ImageID:   imageID,
+		Labels:    labels,
+		Templates: templates,
+		Closer:    func() error { return nil },
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(ClassifyResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) SearchBatch(ctx context.Context, queries []models.BatchSearchQuery) ([]models.BatchSearchResult, error) {
+	resp, err := e.SearchBatchEndpoint(ctx, SearchBatchRequest{
+		Queries: queries,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(SearchBatchResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) GetSearch(ctx context.Context, id uuid.UUID) (*models.SearchRecord, error) {
+	resp, err := e.GetSearchEndpoint(ctx, GetSearchRequest{
+		ID: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(GetSearchResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) ListSearches(ctx context.Context, filters models.SearchHistoryFilters, cursor string, limit int) (*models.SearchHistory, error) {
+	resp, err := e.ListSearchesEndpoint(ctx, ListSearchesRequest{
+		Filters: filters,
+		Cursor:  cursor,
+		Limit:   limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(ListSearchesResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) SuggestQueries(ctx context.Context, prefix string, similarTo string, limit int) ([]models.QuerySuggestion, error) {
+	resp, err := e.SuggestQueriesEndpoint(ctx, SuggestQueriesRequest{
+		Prefix:    prefix,
+		SimilarTo: similarTo,
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(SuggestQueriesResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) DeleteImage(ctx context.Context, id uuid.UUID) error {
+	resp, err := e.DeleteImageEndpoint(ctx, DeleteImageRequest{
+		ID: id,
+	})
+	if err != nil {
+		return err
+	}
+	response := resp.(DeleteImageResponse)
+	return response.Err
+}
+
+func (e *Endpoints) DeleteImages(ctx context.Context, ids []uuid.UUID) ([]models.ImageDeletion, error) {
+	resp, err := e.DeleteImagesEndpoint(ctx, DeleteImagesRequest{
+		IDs: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(DeleteImagesResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) ReplaceImageContent(ctx context.Context, id uuid.UUID, image *models.StorageFileStream) (*models.Image, error) {
+	resp, err := e.ReplaceImageContentEndpoint(ctx, ReplaceImageContentRequest{
+		ID:     id,
+		Image:  image,
+		Closer: func() error { return nil },
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(ReplaceImageContentResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) ImportImages(ctx context.Context, archive io.ReaderAt, size int64) ([]models.ImageImport, error) {
+	resp, err := e.ImportImagesEndpoint(ctx, ImportImagesRequest{
+		Archive: archive,
+		Size:    size,
+		Closer:  func() error { return nil },
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(ImportImagesResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) CreateImageFromURL(ctx context.Context, url string, metadata models.ImageMetadata) (*models.Image, error) {
+	resp, err := e.CreateImageFromURLEndpoint(ctx, CreateImageFromURLRequest{
+		URL:      url,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(CreateImageFromURLResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) CreateImageAsync(ctx context.Context, image *models.StorageFileStream, metadata models.ImageMetadata) (*models.Job, error) {
+	resp, err := e.CreateImageAsyncEndpoint(ctx, CreateImageAsyncRequest{
+		Image:    image,
+		Metadata: metadata,
+		Closer:   func() error { return nil },
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(CreateImageAsyncResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) CreateImageFromURLAsync(ctx context.Context, url string, metadata models.ImageMetadata) (*models.Job, error) {
+	resp, err := e.CreateImageFromURLAsyncEndpoint(ctx, CreateImageFromURLAsyncRequest{
+		URL:      url,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(CreateImageFromURLAsyncResponse)
+	return response.V, response.Err
+}
+
 var (
 	_ endpoint.Failer = CreateImageResponse{}
 	_ endpoint.Failer = SearchImageResponse{}
+	_ endpoint.Failer = SearchComposedResponse{}
+	_ endpoint.Failer = SearchSimilarResponse{}
 	_ endpoint.Failer = GetImageResponse{}
 	_ endpoint.Failer = SearchFeedbackResponse{}
+	_ endpoint.Failer = RefineSearchResponse{}
+	_ endpoint.Failer = StartAutotagRunResponse{}
+	_ endpoint.Failer = GetAutotagRunResponse{}
+	_ endpoint.Failer = ClassifyResponse{}
+	_ endpoint.Failer = SearchBatchResponse{}
+	_ endpoint.Failer = GetSearchResponse{}
+	_ endpoint.Failer = ListSearchesResponse{}
+	_ endpoint.Failer = SuggestQueriesResponse{}
+	_ endpoint.Failer = DeleteImageResponse{}
+	_ endpoint.Failer = DeleteImagesResponse{}
+	_ endpoint.Failer = ReplaceImageContentResponse{}
+	_ endpoint.Failer = ImportImagesResponse{}
+	_ endpoint.Failer = CreateImageFromURLResponse{}
+	_ endpoint.Failer = CreateImageAsyncResponse{}
+	_ endpoint.Failer = CreateImageFromURLAsyncResponse{}
 )
 
 type CreateImageRequest struct {
-	Image  *models.StorageFileStream
-	Closer func() error
+	Image    *models.StorageFileStream
+	Metadata models.ImageMetadata
+	Closer   func() error
 }
 
 type CreateImageResponse struct {
@@ -172,11 +718,12 @@ func (r GetImageResponse) Failed() error {
 }
 
 type SearchImageRequest struct {
-	Query string
+	Query   string
+	Options models.SearchOptions
 }
 
 type SearchImageResponse struct {
-	V   *models.SearchWithImage
+	V   *models.SearchWithResults
 	Err error
 }
 
@@ -184,8 +731,38 @@ func (r SearchImageResponse) Failed() error {
 	return r.Err
 }
 
+type SearchComposedRequest struct {
+	Terms   []models.QueryTerm
+	Options models.SearchOptions
+	Closer  func() error
+}
+
+type SearchComposedResponse struct {
+	V   *models.SearchWithResults
+	Err error
+}
+
+func (r SearchComposedResponse) Failed() error {
+	return r.Err
+}
+
+type SearchSimilarRequest struct {
+	ID      uuid.UUID
+	Options models.SearchOptions
+}
+
+type SearchSimilarResponse struct {
+	V   *models.SearchWithResults
+	Err error
+}
+
+func (r SearchSimilarResponse) Failed() error {
+	return r.Err
+}
+
 type SearchFeedbackRequest struct {
 	QueryID uuid.UUID
+	ImageID *uuid.UUID
 	Rating  models.Rating
 }
 
@@ -197,3 +774,212 @@ type SearchFeedbackResponse struct {
 func (r SearchFeedbackResponse) Failed() error {
 	return r.Err
 }
+
+type RefineSearchRequest struct {
+	ID      uuid.UUID
+	Options models.SearchOptions
+}
+
+type RefineSearchResponse struct {
+	V   *models.SearchWithResults
+	Err error
+}
+
+func (r RefineSearchResponse) Failed() error {
+	return r.Err
+}
+
+type StartAutotagRunRequest struct{}
+
+type StartAutotagRunResponse struct {
+	V   *models.AutotagRun
+	Err error
+}
+
+func (r StartAutotagRunResponse) Failed() error {
+	return r.Err
+}
+
+type GetAutotagRunRequest struct {
+	ID uuid.UUID
+}
+
+type GetAutotagRunResponse struct {
+	V   *models.AutotagRun
+	Err error
+}
+
+func (r GetAutotagRunResponse) Failed() error {
+	return r.Err
+}
+
+type ClassifyRequest struct {
+	Image     *models.StorageFileStream
+	ImageID   *uuid.UUID
+	Labels    []string
+	Templates []string
+	Closer    func() error
+}
+
+type ClassifyResponse struct {
+	V   *models.Classification
+	Err error
+}
+
+func (r ClassifyResponse) Failed() error {
+	return r.Err
+}
+
+type SearchBatchRequest struct {
+	Queries []models.BatchSearchQuery
+}
+
+type SearchBatchResponse struct {
+	V   []models.BatchSearchResult
+	Err error
+}
+
+func (r SearchBatchResponse) Failed() error {
+	return r.Err
+}
+
+type GetSearchRequest struct {
+	ID uuid.UUID
+}
+
+type GetSearchResponse struct {
+	V   *models.SearchRecord
+	Err error
+}
+
+func (r GetSearchResponse) Failed() error {
+	return r.Err
+}
+
+type ListSearchesRequest struct {
+	Filters models.SearchHistoryFilters
+	Cursor  string
+	Limit   int
+}
+
+type ListSearchesResponse struct {
+	V   *models.SearchHistory
+	Err error
+}
+
+func (r ListSearchesResponse) Failed() error {
+	return r.Err
+}
+
+type SuggestQueriesRequest struct {
+	Prefix    string
+	SimilarTo string
+	Limit     int
+}
+
+type SuggestQueriesResponse struct {
+	V   []models.QuerySuggestion
+	Err error
+}
+
+func (r SuggestQueriesResponse) Failed() error {
+	return r.Err
+}
+
+type DeleteImageRequest struct {
+	ID uuid.UUID
+}
+
+type DeleteImageResponse struct {
+	Err error
+}
+
+func (r DeleteImageResponse) Failed() error {
+	return r.Err
+}
+
+type DeleteImagesRequest struct {
+	IDs []uuid.UUID
+}
+
+type DeleteImagesResponse struct {
+	V   []models.ImageDeletion
+	Err error
+}
+
+func (r DeleteImagesResponse) Failed() error {
+	return r.Err
+}
+
+type ReplaceImageContentRequest struct {
+	ID     uuid.UUID
+	Image  *models.StorageFileStream
+	Closer func() error
+}
+
+type ReplaceImageContentResponse struct {
+	V   *models.Image
+	Err error
+}
+
+func (r ReplaceImageContentResponse) Failed() error {
+	return r.Err
+}
+
+type ImportImagesRequest struct {
+	Archive io.ReaderAt
+	Size    int64
+	Closer  func() error
+}
+
+type ImportImagesResponse struct {
+	V   []models.ImageImport
+	Err error
+}
+
+func (r ImportImagesResponse) Failed() error {
+	return r.Err
+}
+
+type CreateImageFromURLRequest struct {
+	URL      string
+	Metadata models.ImageMetadata
+}
+
+type CreateImageFromURLResponse struct {
+	V   *models.Image
+	Err error
+}
+
+func (r CreateImageFromURLResponse) Failed() error {
+	return r.Err
+}
+
+type CreateImageAsyncRequest struct {
+	Image    *models.StorageFileStream
+	Metadata models.ImageMetadata
+	Closer   func() error
+}
+
+type CreateImageAsyncResponse struct {
+	V   *models.Job
+	Err error
+}
+
+func (r CreateImageAsyncResponse) Failed() error {
+	return r.Err
+}
+
+type CreateImageFromURLAsyncRequest struct {
+	URL      string
+	Metadata models.ImageMetadata
+}
+
+type CreateImageFromURLAsyncResponse struct {
+	V   *models.Job
+	Err error
+}
+
+func (r CreateImageFromURLAsyncResponse) Failed() error {
+	return r.Err
+}
@@ -0,0 +1,74 @@
+package imagemodel
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SearchCursor points at the last result a client has seen for a search.
+// It is handed out as an opaque string and resolved against the stored
+// query embedding, so paging never re-embeds the query.
+type SearchCursor struct {
+	SearchID uuid.UUID `json:"s"`
+	Rank     int       `json:"r"`
+	Distance float64   `json:"d"`
+}
+
+var ErrMalformedCursor = errors.New("malformed search cursor")
+
+func (c SearchCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeSearchCursor(s string) (*SearchCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrMalformedCursor
+	}
+
+	cursor := SearchCursor{}
+	if err := json.Unmarshal(b, &cursor); err != nil {
+		return nil, ErrMalformedCursor
+	}
+
+	if cursor.SearchID == uuid.Nil || cursor.Rank < 1 {
+		return nil, ErrMalformedCursor
+	}
+
+	return &cursor, nil
+}
+
+// SearchHistoryCursor points at the last search of a search history page, which is ordered by
+// creation time and then ID, newest first.
+type SearchHistoryCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        uuid.UUID `json:"i"`
+}
+
+func (c SearchHistoryCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeSearchHistoryCursor(s string) (*SearchHistoryCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrMalformedCursor
+	}
+
+	cursor := SearchHistoryCursor{}
+	if err := json.Unmarshal(b, &cursor); err != nil {
+		return nil, ErrMalformedCursor
+	}
+
+	if cursor.ID == uuid.Nil || cursor.CreatedAt.IsZero() {
+		return nil, ErrMalformedCursor
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,41 @@
+package imagemodel
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSearchHistoryCursorRoundTrip(t *testing.T) {
+	// Postgres keeps microseconds; the keyset comparison needs them back unchanged.
+	cursor := SearchHistoryCursor{CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC), ID: uuid.New()}
+
+	got, err := DecodeSearchHistoryCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("DecodeSearchHistoryCursor() error = %v", err)
+	}
+	if !got.CreatedAt.Equal(cursor.CreatedAt) || got.ID != cursor.ID {
+		t.Errorf("DecodeSearchHistoryCursor() = %+v, want %+v", *got, cursor)
+	}
+}
+
+func TestDecodeSearchHistoryCursorMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "missing ID", cursor: SearchHistoryCursor{CreatedAt: time.Now()}.Encode()},
+		{name: "missing time", cursor: SearchHistoryCursor{ID: uuid.New()}.Encode()},
+		{name: "search cursor", cursor: SearchCursor{SearchID: uuid.New(), Rank: 1}.Encode()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeSearchHistoryCursor(tt.cursor); !errors.Is(err, ErrMalformedCursor) {
+				t.Errorf("DecodeSearchHistoryCursor(%q) error = %v, want %v", tt.cursor, err, ErrMalformedCursor)
+			}
+		})
+	}
+}
@@ -24,3 +24,42 @@ type SearchQuery struct {
 	models.Search
 	Embedding []float32 `json:"embedding"`
 }
+
+// ExcludedImageIDs lists the indexed images a search was seeded with. They are never returned
+// as results of that search.
+func (q *SearchQuery) ExcludedImageIDs() []uuid.UUID {
+	ids := []uuid.UUID{}
+	if q.QueryImageID != nil {
+		ids = append(ids, *q.QueryImageID)
+	}
+	for _, term := range q.QueryTerms {
+		if term.ImageID != nil {
+			ids = append(ids, *term.ImageID)
+		}
+	}
+	return ids
+}
+
+// SearchCandidate is a nearest neighbour of a search query, optionally carrying its stored
+// embedding for re-ranking.
+type SearchCandidate struct {
+	models.SearchResult
+	Embedding []float32
+}
+
+// SearchPage selects which nearest neighbours of a search query to fetch.
+type SearchPage struct {
+	// After excludes the results already shown up to the cursor's rank.
+	After *SearchCursor
+	// MinDistance skips candidates nearer than this. It only applies together with After,
+	// and only makes sense when results are ordered by distance.
+	MinDistance    float64
+	Limit          int
+	WithEmbeddings bool
+}
+
+// RatedEmbedding is the embedding of a search result together with the feedback it received.
+type RatedEmbedding struct {
+	Rating    models.Rating
+	Embedding []float32
+}
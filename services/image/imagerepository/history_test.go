@@ -0,0 +1,28 @@
+package imagerepository
+
+import (
+	"testing"
+
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+func TestSearchHistoryFiltersQueryText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "cat*", want: "%cat*%"},
+		{text: "c?t", want: "%c?t%"},
+		{text: "100%_off", want: `%100\%\_off%`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			args := queryArgs{}
+			conditions := searchHistoryFilters(&args, models.SearchHistoryFilters{QueryText: tt.text}, nil)
+			if len(conditions) != 1 || conditions[0] != "query_text ILIKE $1" || args[0] != tt.want {
+				t.Errorf("searchHistoryFilters() = %v with %v, want query_text ILIKE %q", conditions, args, tt.want)
+			}
+		})
+	}
+}
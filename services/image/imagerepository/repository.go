@@ -10,8 +10,30 @@ import (
 
 type Repository interface {
 	CreateImage(ctx context.Context, image *models.Image, embedding *imagemodel.ImageEmbedding) (*models.Image, error)
+	CreateImages(ctx context.Context, images []*models.Image, embeddings []*imagemodel.ImageEmbedding) ([]int, error)
 	GetImage(ctx context.Context, id uuid.UUID) (*models.Image, error)
-	CreateSearchQuery(ctx context.Context, searchQuery *imagemodel.SearchQuery) (*models.SearchWithImage, error)
-	GetSearchQuery(ctx context.Context, id uuid.UUID) (*models.SearchWithImage, error)
+	GetImageByContentHash(ctx context.Context, contentHash string) (*models.Image, error)
+	ListImagesWithoutContentHash(ctx context.Context, after uuid.UUID, limit int) ([]models.Image, error)
+	SetImageContentHash(ctx context.Context, id uuid.UUID, contentHash string) error
+	SkipImageContentHash(ctx context.Context, id uuid.UUID, reason string, duplicateOf *uuid.UUID) error
+	ReplaceImageContent(ctx context.Context, image *models.Image, embedding *imagemodel.ImageEmbedding) (*models.StorageFile, error)
+	DeleteImages(ctx context.Context, ids []uuid.UUID) ([]models.Image, error)
+	GetImageEmbedding(ctx context.Context, imageID uuid.UUID, modelName string) (*imagemodel.ImageEmbedding, error)
+	FindNearestImages(ctx context.Context, searchQuery *imagemodel.SearchQuery, page imagemodel.SearchPage) ([]imagemodel.SearchCandidate, error)
+	CreateSearchQuery(ctx context.Context, searchQuery *imagemodel.SearchQuery, results []models.SearchResult) (*models.SearchWithResults, error)
+	AppendSearchResults(ctx context.Context, searchQueryID uuid.UUID, results []models.SearchResult) error
+	GetSearchQuery(ctx context.Context, id uuid.UUID) (*models.SearchWithResults, error)
+	GetSearchRecord(ctx context.Context, id uuid.UUID) (*models.SearchRecord, error)
+	ListSearchRecords(ctx context.Context, filters models.SearchHistoryFilters, after *imagemodel.SearchHistoryCursor, limit int) ([]models.SearchRecord, error)
+	SuggestQueries(ctx context.Context, prefix string, minFrequency int, limit int) ([]models.QuerySuggestion, error)
+	FindSimilarQueries(ctx context.Context, modelName string, embedding []float32, exclude string, minFrequency int, candidates int, limit int) ([]models.QuerySuggestion, error)
+	GetSearchQueryEmbedding(ctx context.Context, id uuid.UUID) (*imagemodel.SearchQuery, error)
+	ListSearchResultEmbeddings(ctx context.Context, searchQueryID uuid.UUID, maxRank int) ([][]float32, error)
+	ListRatedEmbeddings(ctx context.Context, searchQueryID uuid.UUID) ([]imagemodel.RatedEmbedding, error)
 	CreateSearchFeedback(ctx context.Context, feedback *models.SearchFeedbackWithQuery) (*models.SearchFeedbackWithQuery, error)
+	ListImageEmbeddings(ctx context.Context, modelName string, after uuid.UUID, limit int) ([]imagemodel.ImageEmbedding, error)
+	ReplaceAutoTags(ctx context.Context, imageIDs []uuid.UUID, tags map[uuid.UUID][]models.ImageTag) error
+	CreateAutotagRun(ctx context.Context, run *models.AutotagRun) (*models.AutotagRun, error)
+	UpdateAutotagRun(ctx context.Context, run *models.AutotagRun) error
+	GetAutotagRun(ctx context.Context, id uuid.UUID) (*models.AutotagRun, error)
 }
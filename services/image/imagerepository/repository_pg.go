@@ -2,7 +2,12 @@ package imagerepository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/google/uuid"
@@ -16,6 +21,10 @@ import (
 	"github.com/yckao/image-search-demo-go/services/image/imagemodel"
 )
 
+// autotagRunStaleAfter is how long a running auto-tagging run may go without progress before
+// it is presumed dead. Runs report progress after every batch.
+const autotagRunStaleAfter = "10 minutes"
+
 type PGRepository struct {
 	logger log.Logger
 	db     *pgxpool.Pool
@@ -36,9 +45,12 @@ func (r *PGRepository) CreateImage(ctx context.Context, image *models.Image, emb
 		image.ID = uuid.Must(uuid.NewV7())
 	}
 
-	if _, err = tx.Exec(ctx,
-		"INSERT INTO images (id, storage_provider, storage_key) VALUES ($1, $2, $3)",
-		image.ID, image.StorageProvider, image.StorageKey); err != nil {
+	if err = tx.QueryRow(ctx,
+		"INSERT INTO images (id, storage_provider, storage_key, original_filename, title, description, content_type, file_size, width, height, source_url, content_hash) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING created_at",
+		image.ID, image.StorageProvider, image.StorageKey, image.OriginalFilename, image.Title, image.Description, image.ContentType, image.FileSize, image.Width, image.Height, image.SourceURL, image.ContentHash).Scan(&image.CreatedAt); err != nil {
+		if isContentHashViolation(err) {
+			return nil, errortypes.NewErrImageContentExists(*image.ContentHash)
+		}
 		return nil, err
 	}
 
@@ -52,100 +64,919 @@ func (r *PGRepository) CreateImage(ctx context.Context, image *models.Image, emb
 		return nil, err
 	}
 
-	if err = tx.Commit(ctx); err != nil {
+	if err = insertImageTags(ctx, tx, image.ID, image.Tags); err != nil {
 		return nil, err
 	}
 
-	if err = r.db.QueryRow(ctx,
-		"SELECT created_at FROM images WHERE id = $1",
-		image.ID).Scan(&image.CreatedAt); err != nil {
+	if err = insertEvent(ctx, tx, models.AggregateTypeImage, image.ID, models.EventTypeImageCreated, image); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
 		return nil, err
 	}
 
 	return image, nil
 }
 
+// CreateImages indexes images with their embeddings in one transaction, sending the inserts as
+// pgx batches rather than a round trip each. An image whose content hash another image already
+// has is left out rather than failing the others; the indexes of those images are returned.
+func (r *PGRepository) CreateImages(ctx context.Context, images []*models.Image, embeddings []*imagemodel.ImageEmbedding) ([]int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var duplicates []int
+	batch := &pgx.Batch{}
+	for i, image := range images {
+		if image.ID == uuid.Nil {
+			image.ID = uuid.Must(uuid.NewV7())
+		}
+
+		batch.Queue("INSERT INTO images (id, storage_provider, storage_key, original_filename, title, description, content_type, file_size, width, height, source_url, content_hash) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) ON CONFLICT (content_hash) DO NOTHING RETURNING created_at",
+			image.ID, image.StorageProvider, image.StorageKey, image.OriginalFilename, image.Title, image.Description, image.ContentType, image.FileSize, image.Width, image.Height, image.SourceURL, image.ContentHash).QueryRow(func(row pgx.Row) error {
+			err := row.Scan(&image.CreatedAt)
+			if errors.Is(err, pgx.ErrNoRows) {
+				duplicates = append(duplicates, i)
+				return nil
+			}
+			return err
+		})
+	}
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, err
+	}
+
+	// The rest of each image goes in a second batch, for the images that were inserted, with
+	// events carrying the created_at the inserts returned.
+	batch = &pgx.Batch{}
+	for i, image := range images {
+		if slices.Contains(duplicates, i) {
+			continue
+		}
+
+		if embeddings[i].ID == uuid.Nil {
+			embeddings[i].ID = uuid.Must(uuid.NewV7())
+		}
+		batch.Queue("INSERT INTO image_embeddings (id, image_id, model_name, embedding) VALUES ($1, $2, $3, $4)",
+			embeddings[i].ID, image.ID, embeddings[i].ModelName, pgvector.NewVector(embeddings[i].Embedding))
+		queueImageTags(batch, image.ID, image.Tags)
+
+		args, err := eventArgs(models.AggregateTypeImage, image.ID, models.EventTypeImageCreated, image)
+		if err != nil {
+			return nil, err
+		}
+		batch.Queue(insertEventSQL, args...)
+	}
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return duplicates, nil
+}
+
 func (r *PGRepository) GetImage(ctx context.Context, id uuid.UUID) (*models.Image, error) {
 	image := models.Image{}
 
-	if err := r.db.QueryRow(ctx, "SELECT id, storage_provider, storage_key, created_at FROM images WHERE id = $1", id).
-		Scan(&image.ID, &image.StorageProvider, &image.StorageKey, &image.CreatedAt); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+	if err := r.db.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM images WHERE id = $1", ImageColumns("")), id).
+		Scan(ImageDest(&image)...); err != nil && errors.Is(err, pgx.ErrNoRows) {
 		return nil, errortypes.NewErrImageNotFound(id)
 	} else if err != nil {
 		return nil, err
 	}
 
+	var err error
+	if image.Tags, err = listImageTags(ctx, r.db, id); err != nil {
+		return nil, err
+	}
+
 	return &image, nil
 }
 
-func (r *PGRepository) CreateSearchQuery(ctx context.Context, searchQuery *imagemodel.SearchQuery) (*models.SearchWithImage, error) {
+// GetImageByContentHash returns the image whose file has the given SHA-256, or nil if there is
+// none.
+func (r *PGRepository) GetImageByContentHash(ctx context.Context, contentHash string) (*models.Image, error) {
+	image := models.Image{}
+
+	if err := r.db.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM images WHERE content_hash = $1", ImageColumns("")), contentHash).
+		Scan(ImageDest(&image)...); err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var err error
+	if image.Tags, err = listImageTags(ctx, r.db, image.ID); err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+// ListImagesWithoutContentHash pages through the images indexed before content hashes were
+// recorded, ordered by ID. Images the backfill skipped are left out.
+func (r *PGRepository) ListImagesWithoutContentHash(ctx context.Context, after uuid.UUID, limit int) ([]models.Image, error) {
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf(`SELECT %s FROM images i WHERE i.content_hash IS NULL AND i.id > $1
+			AND NOT EXISTS (SELECT 1 FROM image_content_hash_skips s WHERE s.image_id = i.id)
+			ORDER BY i.id ASC LIMIT $2`, ImageColumns("i.")),
+		after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Image, error) {
+		image := models.Image{}
+		err := row.Scan(ImageDest(&image)...)
+		return image, err
+	})
+}
+
+// SetImageContentHash records the hash of an image that has none. It fails with
+// ErrImageContentExists when another image already has the hash.
+func (r *PGRepository) SetImageContentHash(ctx context.Context, id uuid.UUID, contentHash string) error {
+	if _, err := r.db.Exec(ctx,
+		"UPDATE images SET content_hash = $2 WHERE id = $1 AND content_hash IS NULL",
+		id, contentHash); err != nil {
+		if isContentHashViolation(err) {
+			return errortypes.NewErrImageContentExists(contentHash)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// SkipImageContentHash records why an image is left without a content hash, and which image
+// already has its content if that is why.
+func (r *PGRepository) SkipImageContentHash(ctx context.Context, id uuid.UUID, reason string, duplicateOf *uuid.UUID) error {
+	_, err := r.db.Exec(ctx,
+		"INSERT INTO image_content_hash_skips (image_id, reason, duplicate_of) VALUES ($1, $2, $3) ON CONFLICT (image_id) DO UPDATE SET reason = EXCLUDED.reason, duplicate_of = EXCLUDED.duplicate_of",
+		id, reason, duplicateOf)
+	return err
+}
+
+// ReplaceImageContent points an image at a new file and swaps its embeddings and auto-attached
+// tags for ones computed from that file, keeping its ID, metadata and hand-added tags. image
+// carries the ID and the new file's attributes and is filled in with the rest of the row. The
+// file the image pointed at before is returned.
+func (r *PGRepository) ReplaceImageContent(ctx context.Context, image *models.Image, embedding *imagemodel.ImageEmbedding) (*models.StorageFile, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	previous := &models.StorageFile{}
+	if err := tx.QueryRow(ctx,
+		"SELECT storage_provider, storage_key FROM images WHERE id = $1 FOR UPDATE",
+		image.ID).Scan(&previous.Provider, &previous.Key); err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrImageNotFound(image.ID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := tx.QueryRow(ctx,
+		fmt.Sprintf("UPDATE images SET storage_provider = $2, storage_key = $3, original_filename = $4, content_type = $5, file_size = $6, width = $7, height = $8, source_url = $9, content_hash = $10 WHERE id = $1 RETURNING %s", ImageColumns("")),
+		image.ID, image.StorageProvider, image.StorageKey, image.OriginalFilename, image.ContentType, image.FileSize, image.Width, image.Height, image.SourceURL, image.ContentHash).
+		Scan(ImageDest(image)...); err != nil {
+		if isContentHashViolation(err) {
+			return nil, errortypes.NewErrImageContentExists(*image.ContentHash)
+		}
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM image_embeddings WHERE image_id = $1", image.ID); err != nil {
+		return nil, err
+	}
+
+	if embedding.ID == uuid.Nil {
+		embedding.ID = uuid.Must(uuid.NewV7())
+	}
+
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO image_embeddings (id, image_id, model_name, embedding) VALUES ($1, $2, $3, $4)",
+		embedding.ID, image.ID, embedding.ModelName, pgvector.NewVector(embedding.Embedding)); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM image_tags WHERE image_id = $1 AND model_name IS NOT NULL", image.ID); err != nil {
+		return nil, err
+	}
+
+	if err := insertImageTags(ctx, tx, image.ID, image.Tags); err != nil {
+		return nil, err
+	}
+
+	if image.Tags, err = listImageTags(ctx, tx, image.ID); err != nil {
+		return nil, err
+	}
+
+	if err := insertEvent(ctx, tx, models.AggregateTypeImage, image.ID, models.EventTypeImageUpdated, image); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return previous, nil
+}
+
+// DeleteImages deletes the images that exist among ids and returns them. Their embeddings and
+// tags go with them. Search results naming an image keep their rank and feedback but are no longer
+// read back, and searches made with an image as the query are kept without it.
+func (r *PGRepository) DeleteImages(ctx context.Context, ids []uuid.UUID) ([]models.Image, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, fmt.Sprintf("DELETE FROM images WHERE id = ANY($1) RETURNING %s", ImageColumns("")), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Image, error) {
+		image := models.Image{}
+		err := row.Scan(ImageDest(&image)...)
+		return image, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range images {
+		if err := insertEvent(ctx, tx, models.AggregateTypeImage, images[i].ID, models.EventTypeImageDeleted, &images[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// GetImageEmbedding returns the most recent embedding of an image by the given model.
+func (r *PGRepository) GetImageEmbedding(ctx context.Context, imageID uuid.UUID, modelName string) (*imagemodel.ImageEmbedding, error) {
+	var id *uuid.UUID
+	var embedding *pgvector.Vector
+	var createdAt *time.Time
+
+	if err := r.db.QueryRow(ctx,
+		"SELECT e.id, e.embedding, e.created_at FROM images i LEFT JOIN image_embeddings e ON e.image_id = i.id AND e.model_name = $2 WHERE i.id = $1 ORDER BY e.created_at DESC LIMIT 1", imageID, modelName).
+		Scan(&id, &embedding, &createdAt); err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrImageNotFound(imageID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if id == nil {
+		return nil, errortypes.NewErrImageEmbeddingNotFound(imageID)
+	}
+
+	return &imagemodel.ImageEmbedding{
+		ID:        *id,
+		Image:     models.Image{ID: imageID},
+		ModelName: modelName,
+		Embedding: embedding.Slice(),
+		CreatedAt: *createdAt,
+	}, nil
+}
+
+func (r *PGRepository) FindNearestImages(ctx context.Context, searchQuery *imagemodel.SearchQuery, page imagemodel.SearchPage) ([]imagemodel.SearchCandidate, error) {
+	args := queryArgs{}
+	vector := args.add(pgvector.NewVector(searchQuery.Embedding))
+	model := args.add(searchQuery.ModelName)
+	conditions := []string{
+		"e.model_name = " + model,
+		fmt.Sprintf("e.embedding <=> %s <= %s", vector, args.add(searchQuery.MaxDistance)),
+	}
+
+	if page.After != nil && searchQuery.Hybrid == nil {
+		conditions = append(conditions, fmt.Sprintf("e.embedding <=> %s >= %s", vector, args.add(page.MinDistance)))
+	}
+
+	filters := imageFilters(&args, searchQuery, page)
+	conditions = append(conditions, filters...)
+
+	columns := fmt.Sprintf("%s, e.embedding <=> %s AS distance", ImageColumns("i."), vector)
+
+	var query string
+	if searchQuery.Hybrid == nil {
+		columns += fmt.Sprintf(", 1 - (e.embedding <=> %s) AS score", vector)
+		if page.WithEmbeddings {
+			columns += ", e.embedding"
+		}
+
+		query = fmt.Sprintf("SELECT %s FROM image_embeddings e JOIN images i ON e.image_id = i.id WHERE %s ORDER BY distance ASC LIMIT %s",
+			columns, strings.Join(conditions, " AND "), args.add(page.Limit))
+	} else {
+		query = hybridQuery(&args, searchQuery.Hybrid, vector, model, columns, conditions, filters, page)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Post-filters would otherwise starve the index scan once ef_search candidates are consumed.
+	if _, err := tx.Exec(ctx, "SET LOCAL hnsw.iterative_scan = strict_order"); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (imagemodel.SearchCandidate, error) {
+		candidate := imagemodel.SearchCandidate{}
+		dest := append(ImageDest(&candidate.Image), &candidate.Distance, &candidate.Score)
+
+		var embedding pgvector.Vector
+		if page.WithEmbeddings {
+			dest = append(dest, &embedding)
+		}
+
+		if err := row.Scan(dest...); err != nil {
+			return candidate, err
+		}
+		candidate.Embedding = embedding.Slice()
+
+		return candidate, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 && page.After == nil {
+		var indexed bool
+		if err := r.db.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM image_embeddings WHERE model_name = $1)",
+			searchQuery.ModelName).Scan(&indexed); err != nil {
+			return nil, err
+		}
+
+		if !indexed {
+			return nil, errortypes.NewErrNoImageAvailable(searchQuery.ModelName)
+		}
+	}
+
+	return results, nil
+}
+
+// imageFilters returns the conditions on images i shared by the vector and lexical scans. They
+// are applied while the HNSW index is scanned, which the iterative scan keeps going until
+// enough rows pass them.
+func imageFilters(args *queryArgs, searchQuery *imagemodel.SearchQuery, page imagemodel.SearchPage) []string {
+	var filters []string
+
+	if excluded := searchQuery.ExcludedImageIDs(); len(excluded) > 0 {
+		filters = append(filters, "i.id <> ALL("+args.add(excluded)+")")
+	}
+
+	if page.After != nil && searchQuery.Hybrid == nil {
+		// Results already handed out up to the cursor are excluded by identity rather than
+		// by a (distance, id) keyset so the ORDER BY stays usable by the HNSW index.
+		filters = append(filters, shownFilter(args, page.After))
+	}
+
+	if !searchQuery.CreatedAt.IsZero() {
+		// Keep pages stable: images ingested after the search was issued are never shown.
+		filters = append(filters, "i.created_at <= "+args.add(searchQuery.CreatedAt))
+	}
+
+	if f := searchQuery.Filters; f != nil {
+		if f.CreatedAfter != nil {
+			filters = append(filters, "i.created_at >= "+args.add(*f.CreatedAfter))
+		}
+		if f.CreatedBefore != nil {
+			filters = append(filters, "i.created_at < "+args.add(*f.CreatedBefore))
+		}
+		if len(f.ContentTypes) > 0 {
+			filters = append(filters, "i.content_type = ANY("+args.add(f.ContentTypes)+")")
+		}
+		if f.FilenamePattern != "" {
+			filters = append(filters, "i.original_filename ILIKE "+args.add(likePattern(f.FilenamePattern)))
+		}
+		if f.MinFileSize != nil {
+			filters = append(filters, "i.file_size >= "+args.add(*f.MinFileSize))
+		}
+		if f.MaxFileSize != nil {
+			filters = append(filters, "i.file_size <= "+args.add(*f.MaxFileSize))
+		}
+		if f.MinWidth != nil {
+			filters = append(filters, "i.width >= "+args.add(*f.MinWidth))
+		}
+		if f.MaxWidth != nil {
+			filters = append(filters, "i.width <= "+args.add(*f.MaxWidth))
+		}
+		if f.MinHeight != nil {
+			filters = append(filters, "i.height >= "+args.add(*f.MinHeight))
+		}
+		if f.MaxHeight != nil {
+			filters = append(filters, "i.height <= "+args.add(*f.MaxHeight))
+		}
+		if len(f.CollectionIDs) > 0 {
+			filters = append(filters, "EXISTS (SELECT 1 FROM collection_images ci WHERE ci.image_id = i.id AND ci.collection_id = ANY("+args.add(f.CollectionIDs)+"))")
+		}
+		if len(f.Tags) > 0 {
+			filters = append(filters, fmt.Sprintf("(SELECT count(*) FROM image_tags it JOIN tags t ON it.tag_id = t.id WHERE it.image_id = i.id AND t.name = ANY(%s)) = %s", args.add(f.Tags), args.add(len(f.Tags))))
+		}
+		if len(f.ExcludeTags) > 0 {
+			filters = append(filters, "NOT EXISTS (SELECT 1 FROM image_tags it JOIN tags t ON it.tag_id = t.id WHERE it.image_id = i.id AND t.name = ANY("+args.add(f.ExcludeTags)+"))")
+		}
+	}
+
+	return filters
+}
+
+// likePattern translates a filename glob into a LIKE pattern, escaping LIKE's own wildcards.
+func likePattern(glob string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "*", "%", "?", "_").Replace(glob)
+}
+
+// likeEscape escapes LIKE's wildcards so that s matches only itself.
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// shownFilter excludes the results of a search that were handed out up to the cursor.
+func shownFilter(args *queryArgs, after *imagemodel.SearchCursor) string {
+	return fmt.Sprintf("i.id NOT IN (SELECT image_id FROM search_query_results WHERE search_query_id = %s AND rank <= %s)", args.add(after.SearchID), args.add(after.Rank))
+}
+
+// hybridQuery fuses the nearest images with the best full-text matches by reciprocal rank fusion.
+// The threshold only bounds the vector list, since exact text matches are what CLIP misses.
+// ts_rank normalised by document length stands in for BM25, which Postgres does not provide.
+// Results already shown are dropped after fusion, from candidate lists of a fixed length, so
+// that every page continues the same fused ranking.
+func hybridQuery(args *queryArgs, hybrid *models.Hybrid, vector, model, columns string, conditions, filters []string, page imagemodel.SearchPage) string {
+	candidates := args.add(hybrid.Candidates)
+	textQuery := "websearch_to_tsquery('simple', " + args.add(hybrid.TextQuery) + ")"
+	k := args.add(hybrid.K)
+
+	textConditions := append([]string{"i.search_document @@ q"}, filters...)
+
+	columns += fmt.Sprintf(", COALESCE(%s::float8 / (%s::int + v.rank), 0) + COALESCE(%s::float8 / (%s::int + l.rank), 0) AS score",
+		args.add(hybrid.VectorWeight), k, args.add(hybrid.TextWeight), k)
+	if page.WithEmbeddings {
+		columns += ", e.embedding"
+	}
+
+	shown := "true"
+	if page.After != nil {
+		shown = shownFilter(args, page.After)
+	}
+
+	return fmt.Sprintf(`WITH v AS (
+	SELECT image_id, row_number() OVER (ORDER BY distance ASC, image_id ASC) AS rank FROM (
+		SELECT e.image_id, e.embedding <=> %[1]s AS distance FROM image_embeddings e JOIN images i ON e.image_id = i.id WHERE %[2]s ORDER BY distance ASC LIMIT %[3]s
+	) nearest
+), l AS (
+	SELECT id AS image_id, row_number() OVER (ORDER BY text_rank DESC, id ASC) AS rank FROM (
+		SELECT i.id, ts_rank(i.search_document, q, 1) AS text_rank FROM images i, %[4]s q WHERE %[5]s ORDER BY text_rank DESC, i.id ASC LIMIT %[3]s
+	) matches
+)
+SELECT %[6]s
+FROM v FULL JOIN l ON v.image_id = l.image_id
+JOIN images i ON i.id = COALESCE(v.image_id, l.image_id)
+JOIN LATERAL (SELECT embedding FROM image_embeddings WHERE image_id = i.id AND model_name = %[7]s ORDER BY created_at DESC LIMIT 1) e ON true
+WHERE %[8]s
+ORDER BY score DESC, distance ASC, i.id ASC LIMIT %[9]s`,
+		vector, strings.Join(conditions, " AND "), candidates, textQuery, strings.Join(textConditions, " AND "), columns, model, shown, args.add(page.Limit))
+}
+
+func (r *PGRepository) CreateSearchQuery(ctx context.Context, searchQuery *imagemodel.SearchQuery, results []models.SearchResult) (*models.SearchWithResults, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
 	if searchQuery.ID == uuid.Nil {
 		searchQuery.ID = uuid.Must(uuid.NewV7())
 	}
 
-	var imageID uuid.UUID
+	var queryStorageProvider, queryStorageKey *string
+	if searchQuery.QueryImage != nil {
+		queryStorageProvider = &searchQuery.QueryImage.StorageProvider
+		queryStorageKey = &searchQuery.QueryImage.StorageKey
+	}
+
+	var queryTerms any
+	if len(searchQuery.QueryTerms) > 0 {
+		queryTerms = searchQuery.QueryTerms
+	}
+
+	var rerankStrategy, rerankParams any
+	if searchQuery.Rerank != nil {
+		rerankStrategy = string(searchQuery.Rerank.Strategy)
+		rerankParams = searchQuery.Rerank
+	}
+
+	var hybridParams any
+	if searchQuery.Hybrid != nil {
+		hybridParams = searchQuery.Hybrid
+	}
+
+	var filters any
+	if searchQuery.Filters != nil {
+		filters = searchQuery.Filters
+	}
+
+	if err := tx.QueryRow(ctx,
+		"INSERT INTO search_queries (id, model_name, query_type, query_text, query_storage_provider, query_storage_key, query_image_id, query_terms, query_embedding, max_distance, rerank_strategy, rerank_params, hybrid_params, filters, parent_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING created_at",
+		searchQuery.ID, searchQuery.ModelName, string(searchQuery.QueryType), searchQuery.QueryText, queryStorageProvider, queryStorageKey, searchQuery.QueryImageID, queryTerms, pgvector.NewVector(searchQuery.Embedding), searchQuery.MaxDistance, rerankStrategy, rerankParams, hybridParams, filters, searchQuery.ParentID).Scan(&searchQuery.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := insertSearchResults(ctx, tx, searchQuery.ID, results); err != nil {
+		return nil, err
+	}
+
+	searchWithResults := &models.SearchWithResults{
+		Search:  searchQuery.Search,
+		Results: results,
+	}
+
+	if err := insertEvent(ctx, tx, models.AggregateTypeSearch, searchQuery.ID, models.EventTypeSearchCreated, searchWithResults); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return searchWithResults, nil
+}
+
+func (r *PGRepository) AppendSearchResults(ctx context.Context, searchQueryID uuid.UUID, results []models.SearchResult) error {
+	return insertSearchResults(ctx, r.db, searchQueryID, results)
+}
+
+type batchSender interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+func insertSearchResults(ctx context.Context, db batchSender, searchQueryID uuid.UUID, results []models.SearchResult) error {
+	batch := &pgx.Batch{}
+	for _, result := range results {
+		// A page may be fetched more than once with the same cursor; the first fetch wins.
+		batch.Queue("INSERT INTO search_query_results (search_query_id, rank, image_id, distance, score) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING",
+			searchQueryID, result.Rank, result.Image.ID, result.Distance, result.Score)
+	}
+
+	return db.SendBatch(ctx, batch).Close()
+}
+
+func (r *PGRepository) GetSearchQuery(ctx context.Context, id uuid.UUID) (*models.SearchWithResults, error) {
+	searchQuery := models.SearchWithResults{}
+	scanner := newSearchScanner(&searchQuery.Search)
+
 	if err := r.db.QueryRow(ctx,
-		"SELECT image_id FROM image_embeddings WHERE model_name = $1 ORDER BY embedding <=> $2 ASC, created_at DESC LIMIT 1",
-		searchQuery.ModelName, pgvector.NewVector(searchQuery.Embedding)).Scan(&imageID); err != nil && errors.Is(err, pgx.ErrNoRows) {
-		return nil, errortypes.NewErrNoImageAvailable(searchQuery.ModelName)
+		fmt.Sprintf("SELECT %s FROM search_queries WHERE id = $1", searchColumns("")), id).
+		Scan(scanner.dest()...); err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrSearchQueryNotFound(id)
 	} else if err != nil {
 		return nil, err
 	}
+	scanner.finish()
 
-	if _, err := r.db.Exec(ctx,
-		"INSERT INTO search_queries (id, model_name, query_text, query_embedding, result_image_id) VALUES ($1, $2, $3, $4, $5)",
-		searchQuery.ID, searchQuery.ModelName, searchQuery.QueryText, pgvector.NewVector(searchQuery.Embedding), imageID); err != nil {
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf("SELECT r.rank, r.distance, r.score, %s FROM search_query_results r JOIN images i ON r.image_id = i.id WHERE r.search_query_id = $1 ORDER BY r.rank ASC", ImageColumns("i.")), id)
+	if err != nil {
 		return nil, err
 	}
 
-	image := models.Image{}
+	if searchQuery.Results, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SearchResult, error) {
+		result := models.SearchResult{}
+		err := row.Scan(append([]any{&result.Rank, &result.Distance, &result.Score}, ImageDest(&result.Image)...)...)
+		return result, err
+	}); err != nil {
+		return nil, err
+	}
 
-	if err := r.db.QueryRow(ctx,
-		"SELECT q.created_at, i.id, storage_provider, storage_key, i.created_at FROM search_queries q JOIN images i ON q.result_image_id = i.id WHERE q.id = $1",
-		searchQuery.ID).Scan(&searchQuery.CreatedAt, &image.ID, &image.StorageProvider, &image.StorageKey, &image.CreatedAt); err != nil {
+	return &searchQuery, nil
+}
+
+// GetSearchRecord returns a search with its results and the feedback it received.
+func (r *PGRepository) GetSearchRecord(ctx context.Context, id uuid.UUID) (*models.SearchRecord, error) {
+	search, err := r.GetSearchQuery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	feedbacks, err := r.listSearchFeedbacks(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SearchRecord{
+		SearchWithResults: *search,
+		Feedbacks:         append([]models.SearchFeedback{}, feedbacks[id]...),
+	}, nil
+}
+
+// ListSearchRecords pages through recorded searches newest first, with their results and feedback.
+func (r *PGRepository) ListSearchRecords(ctx context.Context, filters models.SearchHistoryFilters, after *imagemodel.SearchHistoryCursor, limit int) ([]models.SearchRecord, error) {
+	args := queryArgs{}
+	conditions := searchHistoryFilters(&args, filters, after)
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf("SELECT %s FROM search_queries %s ORDER BY created_at DESC, id DESC LIMIT %s", searchColumns(""), where, args.add(limit)),
+		args...)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SearchRecord, error) {
+		record := models.SearchRecord{}
+		scanner := newSearchScanner(&record.Search)
+		err := row.Scan(scanner.dest()...)
+		scanner.finish()
+		return record, err
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	searchWithImage := &models.SearchWithImage{
-		Search: models.Search{
-			ID:        searchQuery.ID,
-			ModelName: searchQuery.ModelName,
-			QueryText: searchQuery.QueryText,
-			CreatedAt: searchQuery.CreatedAt,
-		},
-		Image: models.Image{
-			ID:              image.ID,
-			StorageProvider: image.StorageProvider,
-			StorageKey:      image.StorageKey,
-			CreatedAt:       image.CreatedAt,
-		},
+	ids := make([]uuid.UUID, len(records))
+	for i := range records {
+		ids[i] = records[i].ID
 	}
 
-	return searchWithImage, nil
+	results, err := r.listSearchResults(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	feedbacks, err := r.listSearchFeedbacks(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		records[i].Results = append([]models.SearchResult{}, results[records[i].ID]...)
+		records[i].Feedbacks = append([]models.SearchFeedback{}, feedbacks[records[i].ID]...)
+	}
+
+	return records, nil
 }
 
-func (r *PGRepository) GetSearchQuery(ctx context.Context, id uuid.UUID) (*models.SearchWithImage, error) {
-	searchQuery := models.SearchWithImage{}
+// searchHistoryFilters returns the conditions on search_queries selecting the searches in filters
+// recorded before the cursor. Searches match the query text when they contain it literally.
+func searchHistoryFilters(args *queryArgs, filters models.SearchHistoryFilters, after *imagemodel.SearchHistoryCursor) []string {
+	var conditions []string
+
+	if after != nil {
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < (%s, %s)", args.add(after.CreatedAt), args.add(after.ID)))
+	}
+	if filters.CreatedAfter != nil {
+		conditions = append(conditions, "created_at >= "+args.add(*filters.CreatedAfter))
+	}
+	if filters.CreatedBefore != nil {
+		conditions = append(conditions, "created_at < "+args.add(*filters.CreatedBefore))
+	}
+	if filters.ModelName != "" {
+		conditions = append(conditions, "model_name = "+args.add(filters.ModelName))
+	}
+	if filters.HasFeedback != nil {
+		exists := "EXISTS (SELECT 1 FROM search_feedbacks f WHERE f.search_query_id = search_queries.id)"
+		if !*filters.HasFeedback {
+			exists = "NOT " + exists
+		}
+		conditions = append(conditions, exists)
+	}
+	if filters.Rating != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM search_feedbacks f WHERE f.search_query_id = search_queries.id AND f.rating = "+args.add(string(filters.Rating))+")")
+	}
+	if filters.QueryText != "" {
+		conditions = append(conditions, "query_text ILIKE "+args.add("%"+likeEscape(filters.QueryText)+"%"))
+	}
+
+	return conditions
+}
+
+func (r *PGRepository) listSearchResults(ctx context.Context, searchQueryIDs []uuid.UUID) (map[uuid.UUID][]models.SearchResult, error) {
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf("SELECT r.search_query_id, r.rank, r.distance, r.score, %s FROM search_query_results r JOIN images i ON r.image_id = i.id WHERE r.search_query_id = ANY($1) ORDER BY r.search_query_id, r.rank ASC", ImageColumns("i.")),
+		searchQueryIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := map[uuid.UUID][]models.SearchResult{}
+	var searchQueryID uuid.UUID
+	result := models.SearchResult{}
+	if _, err := pgx.ForEachRow(rows, append([]any{&searchQueryID, &result.Rank, &result.Distance, &result.Score}, ImageDest(&result.Image)...), func() error {
+		results[searchQueryID] = append(results[searchQueryID], result)
+		// Reset so that nullable columns of the next row are not scanned into the pointers just kept.
+		result = models.SearchResult{}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *PGRepository) listSearchFeedbacks(ctx context.Context, searchQueryIDs []uuid.UUID) (map[uuid.UUID][]models.SearchFeedback, error) {
+	rows, err := r.db.Query(ctx,
+		"SELECT search_query_id, id, image_id, rating, created_at FROM search_feedbacks WHERE search_query_id = ANY($1) ORDER BY search_query_id, created_at ASC",
+		searchQueryIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	feedbacks := map[uuid.UUID][]models.SearchFeedback{}
+	var searchQueryID uuid.UUID
+	var rating string
+	feedback := models.SearchFeedback{}
+	if _, err := pgx.ForEachRow(rows, []any{&searchQueryID, &feedback.ID, &feedback.ImageID, &rating, &feedback.CreatedAt}, func() error {
+		feedback.Rating = models.Rating(rating)
+		feedbacks[searchQueryID] = append(feedbacks[searchQueryID], feedback)
+		feedback = models.SearchFeedback{}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return feedbacks, nil
+}
+
+// suggestionColumns aggregates the text searches of q, one row per search with its feedback
+// counts, into a QuerySuggestion per case-insensitive query text.
+const suggestionColumns = "mode() WITHIN GROUP (ORDER BY q.query_text), count(*), (sum(q.positive) + 1)::float8 / (sum(q.feedbacks) + 2) AS positive_ratio, count(*) * 2 * (sum(q.positive) + 1)::float8 / (sum(q.feedbacks) + 2) AS score"
+
+// suggestionSearches lists text searches with their feedback counts, restricted by condition.
+func suggestionSearches(condition string) string {
+	return "SELECT sq.query_text, count(f.id) FILTER (WHERE f.rating = 'POSITIVE') AS positive, count(f.id) AS feedbacks FROM search_queries sq LEFT JOIN search_feedbacks f ON f.search_query_id = sq.id WHERE sq.query_type = 'TEXT' AND " + condition + " GROUP BY sq.id"
+}
+
+// SuggestQueries returns past text queries starting with prefix, ignoring case, that were
+// searched at least minFrequency times.
+func (r *PGRepository) SuggestQueries(ctx context.Context, prefix string, minFrequency int, limit int) ([]models.QuerySuggestion, error) {
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf("SELECT %s FROM (%s) q GROUP BY lower(q.query_text) HAVING count(*) >= $2 ORDER BY score DESC, 1 ASC LIMIT $3",
+			suggestionColumns, suggestionSearches("lower(sq.query_text) LIKE $1")),
+		strings.ToLower(likeEscape(prefix))+"%", minFrequency, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.QuerySuggestion, error) {
+		suggestion := models.QuerySuggestion{}
+		err := row.Scan(&suggestion.QueryText, &suggestion.Frequency, &suggestion.PositiveRatio, &suggestion.Score)
+		return suggestion, err
+	})
+}
+
+// FindSimilarQueries returns past text queries whose embeddings are nearest to embedding, other
+// than exclude and those searched fewer than minFrequency times. Only the candidates nearest
+// searches are considered, so the floor may leave fewer than limit suggestions.
+func (r *PGRepository) FindSimilarQueries(ctx context.Context, modelName string, embedding []float32, exclude string, minFrequency int, candidates int, limit int) ([]models.QuerySuggestion, error) {
+	query := fmt.Sprintf(`WITH nearest AS (
+	SELECT lower(query_text) AS text, min(distance) AS distance FROM (
+		SELECT query_text, query_embedding <=> $1 AS distance FROM search_queries
+		WHERE query_type = 'TEXT' AND model_name = $2 AND lower(query_text) <> lower($3)
+		ORDER BY distance ASC LIMIT $4
+	) n GROUP BY lower(query_text)
+)
+SELECT %s, 1 - n.distance AS similarity
+FROM nearest n JOIN (%s) q ON lower(q.query_text) = n.text
+GROUP BY n.text, n.distance HAVING count(*) >= $5
+ORDER BY n.distance ASC LIMIT $6`,
+		suggestionColumns, suggestionSearches("lower(sq.query_text) IN (SELECT text FROM nearest)"))
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SET LOCAL hnsw.iterative_scan = strict_order"); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, pgvector.NewVector(embedding), modelName, exclude, candidates, minFrequency, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.QuerySuggestion, error) {
+		suggestion := models.QuerySuggestion{}
+		err := row.Scan(&suggestion.QueryText, &suggestion.Frequency, &suggestion.PositiveRatio, &suggestion.Score, &suggestion.Similarity)
+		return suggestion, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+func (r *PGRepository) GetSearchQueryEmbedding(ctx context.Context, id uuid.UUID) (*imagemodel.SearchQuery, error) {
+	searchQuery := imagemodel.SearchQuery{}
+	scanner := newSearchScanner(&searchQuery.Search)
+	var embedding pgvector.Vector
 
 	if err := r.db.QueryRow(ctx,
-		"SELECT s.id, s.model_name, s.query_text, s.created_at, i.id, i.storage_provider, i.storage_key, i.created_at FROM search_queries s LEFT JOIN images i ON s.result_image_id = i.id WHERE s.id = $1", id).
-		Scan(&searchQuery.ID, &searchQuery.ModelName, &searchQuery.QueryText, &searchQuery.CreatedAt, &searchQuery.Image.ID, &searchQuery.Image.StorageProvider, &searchQuery.Image.StorageKey, &searchQuery.Image.CreatedAt); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		fmt.Sprintf("SELECT %s, query_embedding FROM search_queries WHERE id = $1", searchColumns("")), id).
+		Scan(append(scanner.dest(), &embedding)...); err != nil && errors.Is(err, pgx.ErrNoRows) {
 		return nil, errortypes.NewErrSearchQueryNotFound(id)
 	} else if err != nil {
 		return nil, err
 	}
+	scanner.finish()
+
+	searchQuery.Embedding = embedding.Slice()
 
 	return &searchQuery, nil
 }
 
+// ListSearchResultEmbeddings returns the embeddings of the results already shown for a search, up to maxRank.
+func (r *PGRepository) ListSearchResultEmbeddings(ctx context.Context, searchQueryID uuid.UUID, maxRank int) ([][]float32, error) {
+	rows, err := r.db.Query(ctx,
+		"SELECT DISTINCT ON (r.rank) e.embedding FROM search_query_results r JOIN search_queries q ON r.search_query_id = q.id JOIN image_embeddings e ON e.image_id = r.image_id AND e.model_name = q.model_name WHERE r.search_query_id = $1 AND r.rank <= $2 ORDER BY r.rank ASC, e.created_at DESC",
+		searchQueryID, maxRank)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) ([]float32, error) {
+		var embedding pgvector.Vector
+		err := row.Scan(&embedding)
+		return embedding.Slice(), err
+	})
+}
+
+// ListRatedEmbeddings returns the embeddings of the rated results of a search. Feedback on the
+// search as a whole applies to every result shown for it.
+func (r *PGRepository) ListRatedEmbeddings(ctx context.Context, searchQueryID uuid.UUID) ([]imagemodel.RatedEmbedding, error) {
+	rows, err := r.db.Query(ctx,
+		"SELECT f.rating, e.embedding FROM search_feedbacks f JOIN search_queries q ON f.search_query_id = q.id JOIN search_query_results r ON r.search_query_id = f.search_query_id AND (f.image_id IS NULL OR r.image_id = f.image_id) JOIN LATERAL (SELECT embedding FROM image_embeddings WHERE image_id = r.image_id AND model_name = q.model_name ORDER BY created_at DESC LIMIT 1) e ON true WHERE f.search_query_id = $1",
+		searchQueryID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (imagemodel.RatedEmbedding, error) {
+		var rating string
+		var embedding pgvector.Vector
+		err := row.Scan(&rating, &embedding)
+		return imagemodel.RatedEmbedding{
+			Rating:    models.Rating(rating),
+			Embedding: embedding.Slice(),
+		}, err
+	})
+}
+
 func (r *PGRepository) CreateSearchFeedback(ctx context.Context, feedback *models.SearchFeedbackWithQuery) (*models.SearchFeedbackWithQuery, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
 	if feedback.ID == uuid.Nil {
 		feedback.ID = uuid.Must(uuid.NewV7())
 	}
 
-	if _, err := r.db.Exec(ctx, "INSERT INTO search_feedbacks (id, search_query_id, rating) VALUES ($1, $2, $3)", feedback.ID, feedback.Query.ID, string(feedback.Rating)); err != nil {
+	if _, err := tx.Exec(ctx, "INSERT INTO search_feedbacks (id, search_query_id, image_id, rating) VALUES ($1, $2, $3, $4)", feedback.ID, feedback.Query.ID, feedback.ImageID, string(feedback.Rating)); err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
+			if pgErr.Code == pgerrcode.ForeignKeyViolation && pgErr.ConstraintName == "search_feedbacks_search_query_result_fkey" {
+				return nil, errortypes.NewErrSearchResultNotFound(feedback.Query.ID, *feedback.ImageID)
+			}
+
 			if pgErr.Code == pgerrcode.ForeignKeyViolation {
 				return nil, errortypes.NewErrSearchQueryNotFound(feedback.Query.ID)
 			}
@@ -157,11 +988,250 @@ func (r *PGRepository) CreateSearchFeedback(ctx context.Context, feedback *model
 		return nil, err
 	}
 
-	if err := r.db.QueryRow(ctx,
-		"SELECT f.created_at, sq.id, sq.model_name, sq.query_text, sq.created_at FROM search_feedbacks f JOIN search_queries sq ON f.search_query_id = sq.id WHERE f.id = $1", feedback.ID).
-		Scan(&feedback.CreatedAt, &feedback.Query.ID, &feedback.Query.ModelName, &feedback.Query.QueryText, &feedback.Query.CreatedAt); err != nil {
+	scanner := newSearchScanner(&feedback.Query)
+	if err := tx.QueryRow(ctx,
+		fmt.Sprintf("SELECT f.created_at, %s FROM search_feedbacks f JOIN search_queries sq ON f.search_query_id = sq.id WHERE f.id = $1", searchColumns("sq.")), feedback.ID).
+		Scan(append([]any{&feedback.CreatedAt}, scanner.dest()...)...); err != nil {
+		return nil, err
+	}
+	scanner.finish()
+
+	// Feedback belongs to the search aggregate so that it is published after the search.
+	if err := insertEvent(ctx, tx, models.AggregateTypeSearch, feedback.Query.ID, models.EventTypeFeedbackCreated, feedback); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
 
 	return feedback, nil
 }
+
+// insertEvent records an event in the outbox. It must run in the transaction of the change the
+// event describes, so that the event is published if and only if the change is committed.
+func insertEvent(ctx context.Context, tx pgx.Tx, aggregateType string, aggregateID uuid.UUID, eventType models.EventType, data any) error {
+	args, err := eventArgs(aggregateType, aggregateID, eventType, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, insertEventSQL, args...)
+	return err
+}
+
+const insertEventSQL = "INSERT INTO outbox (event_id, event_type, aggregate_type, aggregate_id, payload) VALUES ($1, $2, $3, $4, $5)"
+
+// eventArgs builds the arguments of insertEventSQL, for callers that queue the insert in a batch.
+func eventArgs(aggregateType string, aggregateID uuid.UUID, eventType models.EventType, data any) ([]any, error) {
+	event := models.Event{
+		ID:        uuid.Must(uuid.NewV7()),
+		Type:      eventType,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return []any{event.ID, string(event.Type), aggregateType, aggregateID, payload}, nil
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func listImageTags(ctx context.Context, db querier, imageID uuid.UUID) ([]models.ImageTag, error) {
+	rows, err := db.Query(ctx,
+		"SELECT t.name, it.score, it.model_name FROM image_tags it JOIN tags t ON it.tag_id = t.id WHERE it.image_id = $1 ORDER BY t.name ASC", imageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.ImageTag, error) {
+		tag := models.ImageTag{}
+		err := row.Scan(&tag.Name, &tag.Score, &tag.ModelName)
+		return tag, err
+	})
+}
+
+// insertImageTags attaches tags to an image by name, creating missing tags. A tag added by hand
+// is kept as is when auto-tagging attaches the same tag.
+func insertImageTags(ctx context.Context, db batchSender, imageID uuid.UUID, tags []models.ImageTag) error {
+	batch := &pgx.Batch{}
+	queueImageTags(batch, imageID, tags)
+
+	return db.SendBatch(ctx, batch).Close()
+}
+
+func queueImageTags(batch *pgx.Batch, imageID uuid.UUID, tags []models.ImageTag) {
+	for _, tag := range tags {
+		batch.Queue("INSERT INTO tags (id, name) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING",
+			uuid.Must(uuid.NewV7()), tag.Name)
+		batch.Queue("INSERT INTO image_tags (image_id, tag_id, score, model_name) SELECT $1, id, $3, $4 FROM tags WHERE name = $2 ON CONFLICT (image_id, tag_id) DO UPDATE SET score = EXCLUDED.score, model_name = EXCLUDED.model_name WHERE image_tags.model_name IS NOT NULL",
+			imageID, tag.Name, tag.Score, tag.ModelName)
+	}
+}
+
+// ListImageEmbeddings pages through the latest embedding of every image embedded by a model, in
+// image ID order.
+func (r *PGRepository) ListImageEmbeddings(ctx context.Context, modelName string, after uuid.UUID, limit int) ([]imagemodel.ImageEmbedding, error) {
+	rows, err := r.db.Query(ctx,
+		"SELECT DISTINCT ON (image_id) id, image_id, model_name, embedding, created_at FROM image_embeddings WHERE model_name = $1 AND image_id > $2 ORDER BY image_id ASC, created_at DESC LIMIT $3",
+		modelName, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (imagemodel.ImageEmbedding, error) {
+		embedding := imagemodel.ImageEmbedding{}
+		var vector pgvector.Vector
+		err := row.Scan(&embedding.ID, &embedding.Image.ID, &embedding.ModelName, &vector, &embedding.CreatedAt)
+		embedding.Embedding = vector.Slice()
+		return embedding, err
+	})
+}
+
+// ReplaceAutoTags swaps the auto-attached tags of each of imageIDs for the given ones.
+func (r *PGRepository) ReplaceAutoTags(ctx context.Context, imageIDs []uuid.UUID, tags map[uuid.UUID][]models.ImageTag) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		"DELETE FROM image_tags WHERE image_id = ANY($1) AND model_name IS NOT NULL",
+		imageIDs); err != nil {
+		return err
+	}
+
+	for _, imageID := range imageIDs {
+		if err := insertImageTags(ctx, tx, imageID, tags[imageID]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// CreateAutotagRun starts a run unless another is in progress. A run whose progress has not been
+// updated for autotagRunStaleAfter is taken to have died with its process and marked failed.
+func (r *PGRepository) CreateAutotagRun(ctx context.Context, run *models.AutotagRun) (*models.AutotagRun, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE autotag_runs SET status = 'FAILED', error = 'interrupted', finished_at = now() WHERE status = 'RUNNING' AND updated_at < now() - interval '"+autotagRunStaleAfter+"'"); err != nil {
+		return nil, err
+	}
+
+	if run.ID == uuid.Nil {
+		run.ID = uuid.Must(uuid.NewV7())
+	}
+	run.Status = models.AutotagRunStatusRunning
+
+	if err := tx.QueryRow(ctx,
+		"INSERT INTO autotag_runs (id, model_name, vocabulary_hash, status, total_images) SELECT $1, $2, $3, $4, count(DISTINCT image_id) FROM image_embeddings WHERE model_name = $2 RETURNING total_images, started_at, updated_at",
+		run.ID, run.ModelName, run.VocabularyHash, string(run.Status)).Scan(&run.TotalImages, &run.StartedAt, &run.UpdatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return nil, errortypes.NewErrAutotagRunInProgress()
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+func (r *PGRepository) UpdateAutotagRun(ctx context.Context, run *models.AutotagRun) error {
+	return r.db.QueryRow(ctx,
+		"UPDATE autotag_runs SET status = $2, processed_images = $3, tagged_images = $4, error = $5, updated_at = now(), finished_at = CASE WHEN $2 = 'RUNNING' THEN NULL ELSE now() END WHERE id = $1 RETURNING updated_at, finished_at",
+		run.ID, string(run.Status), run.ProcessedImages, run.TaggedImages, run.Error).Scan(&run.UpdatedAt, &run.FinishedAt)
+}
+
+func (r *PGRepository) GetAutotagRun(ctx context.Context, id uuid.UUID) (*models.AutotagRun, error) {
+	run := models.AutotagRun{}
+	var status string
+
+	if err := r.db.QueryRow(ctx,
+		"SELECT id, model_name, vocabulary_hash, status, total_images, processed_images, tagged_images, error, started_at, updated_at, finished_at FROM autotag_runs WHERE id = $1", id).
+		Scan(&run.ID, &run.ModelName, &run.VocabularyHash, &status, &run.TotalImages, &run.ProcessedImages, &run.TaggedImages, &run.Error, &run.StartedAt, &run.UpdatedAt, &run.FinishedAt); err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrAutotagRunNotFound(id)
+	} else if err != nil {
+		return nil, err
+	}
+	run.Status = models.AutotagRunStatus(status)
+
+	return &run, nil
+}
+
+func isContentHashViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "images_content_hash_key"
+}
+
+type queryArgs []any
+
+func (a *queryArgs) add(v any) string {
+	*a = append(*a, v)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// ImageColumns lists the images columns read by ImageDest, each prefixed with prefix. Other
+// repositories that join images use it too.
+func ImageColumns(prefix string) string {
+	columns := []string{"id", "storage_provider", "storage_key", "original_filename", "title", "description", "content_type", "file_size", "width", "height", "source_url", "content_hash", "created_at"}
+	for i := range columns {
+		columns[i] = prefix + columns[i]
+	}
+	return strings.Join(columns, ", ")
+}
+
+// ImageDest returns the scan destinations of the columns listed by ImageColumns.
+func ImageDest(image *models.Image) []any {
+	return []any{&image.ID, &image.StorageProvider, &image.StorageKey, &image.OriginalFilename, &image.Title, &image.Description, &image.ContentType, &image.FileSize, &image.Width, &image.Height, &image.SourceURL, &image.ContentHash, &image.CreatedAt}
+}
+
+// searchColumns lists the search_queries columns read by searchScanner, each prefixed with prefix.
+func searchColumns(prefix string) string {
+	columns := []string{"id", "model_name", "query_type", "query_text", "query_storage_provider", "query_storage_key", "query_image_id", "query_terms", "max_distance", "rerank_params", "hybrid_params", "filters", "parent_id", "created_at"}
+	for i := range columns {
+		columns[i] = prefix + columns[i]
+	}
+	return strings.Join(columns, ", ")
+}
+
+type searchScanner struct {
+	search               *models.Search
+	queryType            string
+	queryStorageProvider *string
+	queryStorageKey      *string
+}
+
+func newSearchScanner(search *models.Search) *searchScanner {
+	return &searchScanner{search: search}
+}
+
+func (s *searchScanner) dest() []any {
+	return []any{&s.search.ID, &s.search.ModelName, &s.queryType, &s.search.QueryText, &s.queryStorageProvider, &s.queryStorageKey, &s.search.QueryImageID, &s.search.QueryTerms, &s.search.MaxDistance, &s.search.Rerank, &s.search.Hybrid, &s.search.Filters, &s.search.ParentID, &s.search.CreatedAt}
+}
+
+func (s *searchScanner) finish() {
+	s.search.QueryType = models.QueryType(s.queryType)
+	if s.queryStorageProvider != nil && s.queryStorageKey != nil {
+		s.search.QueryImage = &models.QueryImage{
+			StorageProvider: *s.queryStorageProvider,
+			StorageKey:      *s.queryStorageKey,
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package imagerepository
+
+import "testing"
+
+func TestLikePattern(t *testing.T) {
+	tests := []struct {
+		glob string
+		want string
+	}{
+		{glob: "img_??.jpg", want: `img\___.jpg`},
+		{glob: "100%*", want: `100\%%`},
+		{glob: `back\slash*`, want: `back\\slash%`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.glob, func(t *testing.T) {
+			if got := likePattern(tt.glob); got != tt.want {
+				t.Errorf("likePattern(%q) = %q, want %q", tt.glob, got, tt.want)
+			}
+		})
+	}
+}
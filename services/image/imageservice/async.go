@@ -0,0 +1,144 @@
+package imageservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/job/jobservice"
+)
+
+// JobQueue runs work in the background, retrying it when it fails. jobservice.Worker is one.
+type JobQueue interface {
+	Enqueue(ctx context.Context, jobType models.JobType, payload any) (*models.Job, error)
+	Handle(jobType models.JobType, handler jobservice.Handler)
+}
+
+// createImageJob is the payload of a models.JobTypeCreateImage job. It either names an uploaded
+// file that has been stored already or a URL to fetch the image from.
+type createImageJob struct {
+	StorageProvider string `json:"storage_provider,omitempty"`
+	StorageKey      string `json:"storage_key,omitempty"`
+	Filename        string `json:"filename,omitempty"`
+	ContentType     string `json:"content_type,omitempty"`
+	URL             string `json:"url,omitempty"`
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+}
+
+// CreateImageAsync stores an uploaded image and queues a job that embeds and indexes it. The
+// stored file becomes the image's file once the job succeeds.
+func (s *imageService) CreateImageAsync(ctx context.Context, stream *models.StorageFileStream, metadata models.ImageMetadata) (*models.Job, error) {
+	if s.config.Jobs == nil {
+		return nil, errortypes.NewErrAsyncIngestionDisabled()
+	}
+
+	storageFile, err := s.storageService.Upload(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.config.Jobs.Enqueue(ctx, models.JobTypeCreateImage, createImageJob{
+		StorageProvider: storageFile.Provider,
+		StorageKey:      storageFile.Key,
+		Filename:        stream.Filename,
+		ContentType:     stream.ContentType,
+		Title:           metadata.Title,
+		Description:     metadata.Description,
+	})
+	if err != nil {
+		s.deleteFile(ctx, &models.Image{StorageProvider: storageFile.Provider, StorageKey: storageFile.Key})
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// CreateImageFromURLAsync queues a job that fetches an image and indexes it.
+func (s *imageService) CreateImageFromURLAsync(ctx context.Context, url string, metadata models.ImageMetadata) (*models.Job, error) {
+	if s.config.Jobs == nil {
+		return nil, errortypes.NewErrAsyncIngestionDisabled()
+	}
+
+	if _, err := parseImageURL(url); err != nil {
+		return nil, err
+	}
+
+	return s.config.Jobs.Enqueue(ctx, models.JobTypeCreateImage, createImageJob{
+		URL:         url,
+		Title:       metadata.Title,
+		Description: metadata.Description,
+	})
+}
+
+// runCreateImageJob is the handler of models.JobTypeCreateImage jobs. The stored upload is
+// removed once the job fails for good.
+func (s *imageService) runCreateImageJob(ctx context.Context, job *models.Job) (any, error) {
+	var payload createImageJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, err
+	}
+
+	metadata := models.ImageMetadata{Title: payload.Title, Description: payload.Description}
+	if payload.URL != "" {
+		return s.CreateImageFromURL(ctx, payload.URL, metadata)
+	}
+
+	storageFile := &models.StorageFile{Provider: payload.StorageProvider, Key: payload.StorageKey}
+	image, err := s.createStoredImage(ctx, storageFile, payload, metadata)
+	if err != nil && jobservice.IsFinalAttempt(job, err) {
+		s.deleteFile(ctx, &models.Image{StorageProvider: storageFile.Provider, StorageKey: storageFile.Key})
+	}
+
+	return image, err
+}
+
+// createStoredImage embeds and indexes an image whose file is already in storage. The file is
+// removed when its content turns out to be indexed already.
+func (s *imageService) createStoredImage(ctx context.Context, storageFile *models.StorageFile, payload createImageJob, metadata models.ImageMetadata) (*models.Image, error) {
+	download, err := s.storageService.Download(ctx, storageFile)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := download.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	imageBytes, err := io.ReadAll(download.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := contentHash(imageBytes)
+	if duplicate, err := s.findDuplicate(ctx, hash); err != nil {
+		return nil, err
+	} else if duplicate != nil && duplicate.StorageKey == storageFile.Key {
+		// An earlier attempt indexed the image but could not record that it had.
+		duplicate.Duplicate = false
+		return duplicate, nil
+	} else if duplicate != nil {
+		s.deleteFile(ctx, &models.Image{StorageProvider: storageFile.Provider, StorageKey: storageFile.Key})
+		return duplicate, nil
+	}
+
+	embedding, err := s.clipService.ImageEmbedding(ctx, bytes.NewReader(imageBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &models.StorageFileStream{
+		Filename:      payload.Filename,
+		ContentType:   payload.ContentType,
+		ContentLength: int64(len(imageBytes)),
+	}
+	img, err := s.describeImage(ctx, stream, imageBytes, metadata, storageFile, embedding)
+	if err != nil {
+		return nil, err
+	}
+	img.ContentHash = &hash
+
+	return s.indexImage(ctx, img, embedding)
+}
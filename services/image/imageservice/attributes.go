@@ -0,0 +1,31 @@
+package imageservice
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+// setImageAttributes records the filterable attributes of an uploaded image. The content type is
+// sniffed from the bytes, falling back to the declared one, and dimensions are left unset for
+// formats the standard library cannot decode.
+func setImageAttributes(img *models.Image, stream *models.StorageFileStream, imageBytes []byte) {
+	contentType := http.DetectContentType(imageBytes)
+	if contentType == "application/octet-stream" && stream.ContentType != "" {
+		contentType = stream.ContentType
+	}
+	img.ContentType = &contentType
+
+	size := int64(len(imageBytes))
+	img.FileSize = &size
+
+	if config, _, err := image.DecodeConfig(bytes.NewReader(imageBytes)); err == nil {
+		img.Width = &config.Width
+		img.Height = &config.Height
+	}
+}
@@ -0,0 +1,275 @@
+package imageservice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/clients/clip"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultAutotagThreshold suits CLIP ViT-B/32, whose matching image and caption pairs
+	// typically score a cosine similarity between 0.25 and 0.35.
+	DefaultAutotagThreshold = 0.25
+	AutotagBatchSize        = 500
+
+	maxLabelLength = 100
+)
+
+// AutotagVocabulary is the label set images are zero-shot tagged with. Each label is embedded
+// as the mean of its prompts, which default to Templates with {label} replaced by the name.
+//
+//	default_threshold: 0.25
+//	templates:
+//	  - "a photo of a {label}."
+//	labels:
+//	  - name: cat
+//	  - name: night
+//	    threshold: 0.22
+//	    prompts: ["a photo taken at night."]
+type AutotagVocabulary struct {
+	DefaultThreshold float64        `yaml:"default_threshold" json:"default_threshold"`
+	Templates        []string       `yaml:"templates" json:"templates"`
+	Labels           []AutotagLabel `yaml:"labels" json:"labels"`
+}
+
+type AutotagLabel struct {
+	Name      string   `yaml:"name" json:"name"`
+	Threshold float64  `yaml:"threshold" json:"threshold"`
+	Prompts   []string `yaml:"prompts" json:"prompts"`
+}
+
+// LoadAutotagVocabulary reads a vocabulary from a YAML file and resolves label defaults.
+func LoadAutotagVocabulary(path string) (*AutotagVocabulary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vocabulary := &AutotagVocabulary{}
+	if err := yaml.Unmarshal(data, vocabulary); err != nil {
+		return nil, fmt.Errorf("failed to parse autotag vocabulary %s: %w", path, err)
+	}
+
+	if vocabulary.DefaultThreshold == 0 {
+		vocabulary.DefaultThreshold = DefaultAutotagThreshold
+	}
+	if len(vocabulary.Templates) == 0 {
+		vocabulary.Templates = []string{DefaultLabelTemplate}
+	}
+
+	seen := map[string]bool{}
+	for i := range vocabulary.Labels {
+		label := &vocabulary.Labels[i]
+		label.Name = strings.TrimSpace(label.Name)
+		if label.Name == "" || len(label.Name) > maxLabelLength {
+			return nil, fmt.Errorf("autotag label %d must have a name of 1 to %d characters", i, maxLabelLength)
+		}
+		if seen[label.Name] {
+			return nil, fmt.Errorf("autotag label %q is defined more than once", label.Name)
+		}
+		seen[label.Name] = true
+
+		if label.Threshold == 0 {
+			label.Threshold = vocabulary.DefaultThreshold
+		}
+		if len(label.Prompts) == 0 {
+			for _, template := range vocabulary.Templates {
+				label.Prompts = append(label.Prompts, strings.ReplaceAll(template, "{label}", label.Name))
+			}
+		}
+	}
+
+	return vocabulary, nil
+}
+
+// autotagger holds the text embeddings of a vocabulary. They are computed on first use rather
+// than at startup so that the service does not depend on the CLIP service being up first.
+type autotagger struct {
+	vocabulary  *AutotagVocabulary
+	clipService clip.Service
+
+	mu         sync.Mutex
+	modelName  string
+	hash       string
+	embeddings [][]float32
+}
+
+func newAutotagger(vocabulary *AutotagVocabulary, clipService clip.Service) *autotagger {
+	if vocabulary == nil || len(vocabulary.Labels) == 0 {
+		return nil
+	}
+	return &autotagger{vocabulary: vocabulary, clipService: clipService}
+}
+
+func (t *autotagger) load(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.embeddings != nil {
+		return nil
+	}
+
+	modelName := ""
+	embeddings := make([][]float32, len(t.vocabulary.Labels))
+	for i, label := range t.vocabulary.Labels {
+		vectors := make([][]float32, len(label.Prompts))
+		weights := make([]float64, len(label.Prompts))
+		for j, prompt := range label.Prompts {
+			e, err := t.clipService.TextEmbedding(ctx, prompt)
+			if err != nil {
+				return err
+			}
+			if modelName != "" && e.Model != modelName {
+				return errors.New("autotag prompts were embedded by different models")
+			}
+			modelName = e.Model
+			vectors[j] = e.Embedding
+			weights[j] = 1
+		}
+
+		if embeddings[i] = combineEmbeddings(vectors, weights); embeddings[i] == nil {
+			return fmt.Errorf("autotag label %q has no usable prompt embedding", label.Name)
+		}
+	}
+
+	// The hash identifies what a run tagged with, so a changed vocabulary or model is visible
+	// when comparing runs.
+	data, err := json.Marshal(struct {
+		ModelName string         `json:"model_name"`
+		Labels    []AutotagLabel `json:"labels"`
+	}{modelName, t.vocabulary.Labels})
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+
+	t.modelName = modelName
+	t.hash = hex.EncodeToString(sum[:])
+	t.embeddings = embeddings
+
+	return nil
+}
+
+// tags returns the labels whose similarity to an image embedding reaches their threshold. An
+// embedding from a model other than the vocabulary's is not comparable and gets no tags.
+func (t *autotagger) tags(ctx context.Context, modelName string, embedding []float32) ([]models.ImageTag, error) {
+	if err := t.load(ctx); err != nil {
+		return nil, err
+	}
+
+	if modelName != t.modelName {
+		return nil, nil
+	}
+
+	embedding = normalize(embedding)
+	if embedding == nil {
+		return nil, nil
+	}
+
+	var tags []models.ImageTag
+	for i, label := range t.vocabulary.Labels {
+		score := dot(embedding, t.embeddings[i])
+		if score >= label.Threshold {
+			tags = append(tags, models.ImageTag{
+				Name:      label.Name,
+				Score:     &score,
+				ModelName: &modelName,
+			})
+		}
+	}
+
+	return tags, nil
+}
+
+// StartAutotagRun re-tags every indexed image against the current vocabulary in the background
+// and returns the run, whose progress can be followed with GetAutotagRun.
+func (s *imageService) StartAutotagRun(ctx context.Context) (*models.AutotagRun, error) {
+	if s.autotagger == nil {
+		return nil, errortypes.NewErrAutotagDisabled()
+	}
+
+	if err := s.autotagger.load(ctx); err != nil {
+		return nil, err
+	}
+
+	run, err := s.imageRepository.CreateAutotagRun(ctx, &models.AutotagRun{
+		ModelName:      s.autotagger.modelName,
+		VocabularyHash: s.autotagger.hash,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	progress := *run
+	go s.runAutotag(context.WithoutCancel(ctx), &progress)
+
+	return run, nil
+}
+
+func (s *imageService) GetAutotagRun(ctx context.Context, id uuid.UUID) (*models.AutotagRun, error) {
+	return s.imageRepository.GetAutotagRun(ctx, id)
+}
+
+func (s *imageService) runAutotag(ctx context.Context, run *models.AutotagRun) {
+	err := s.autotagBatches(ctx, run)
+
+	run.Status = models.AutotagRunStatusCompleted
+	if err != nil {
+		message := err.Error()
+		run.Status = models.AutotagRunStatusFailed
+		run.Error = &message
+		s.logger.Log("autotag", "run", run.ID, "error", err)
+	}
+
+	if err := s.imageRepository.UpdateAutotagRun(ctx, run); err != nil {
+		s.logger.Log("autotag", "run", run.ID, "error", err)
+	}
+}
+
+func (s *imageService) autotagBatches(ctx context.Context, run *models.AutotagRun) error {
+	after := uuid.Nil
+	for {
+		embeddings, err := s.imageRepository.ListImageEmbeddings(ctx, run.ModelName, after, AutotagBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(embeddings) == 0 {
+			return nil
+		}
+
+		imageIDs := make([]uuid.UUID, len(embeddings))
+		tags := make(map[uuid.UUID][]models.ImageTag, len(embeddings))
+		for i, embedding := range embeddings {
+			imageIDs[i] = embedding.Image.ID
+			if tags[embedding.Image.ID], err = s.autotagger.tags(ctx, embedding.ModelName, embedding.Embedding); err != nil {
+				return err
+			}
+			if len(tags[embedding.Image.ID]) > 0 {
+				run.TaggedImages++
+			}
+		}
+
+		if err := s.imageRepository.ReplaceAutoTags(ctx, imageIDs, tags); err != nil {
+			return err
+		}
+
+		run.ProcessedImages += len(embeddings)
+		if err := s.imageRepository.UpdateAutotagRun(ctx, run); err != nil {
+			return err
+		}
+
+		after = imageIDs[len(imageIDs)-1]
+	}
+}
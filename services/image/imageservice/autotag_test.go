@@ -0,0 +1,71 @@
+package imageservice
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadAutotagVocabulary(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    *AutotagVocabulary
+		wantErr string
+	}{
+		{
+			name: "labels inherit the vocabulary's threshold and templates",
+			yaml: `default_threshold: 0.3
+templates: ["a photo of a {label}.", "a drawing of a {label}."]
+labels:
+  - name: " dog "
+  - name: night
+    threshold: 0.22
+    prompts: ["a photo taken at night."]
+`,
+			want: &AutotagVocabulary{
+				DefaultThreshold: 0.3,
+				Templates:        []string{"a photo of a {label}.", "a drawing of a {label}."},
+				Labels: []AutotagLabel{
+					{Name: "dog", Threshold: 0.3, Prompts: []string{"a photo of a dog.", "a drawing of a dog."}},
+					{Name: "night", Threshold: 0.22, Prompts: []string{"a photo taken at night."}},
+				},
+			},
+		},
+		{
+			name:    "label without a name",
+			yaml:    "labels:\n  - name: \"  \"\n",
+			wantErr: "must have a name",
+		},
+		{
+			name:    "label defined twice",
+			yaml:    "labels:\n  - name: cat\n  - name: \"cat \"\n",
+			wantErr: "defined more than once",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "vocabulary.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := LoadAutotagVocabulary(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadAutotagVocabulary() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadAutotagVocabulary() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadAutotagVocabulary() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
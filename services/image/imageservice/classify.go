@@ -0,0 +1,236 @@
+package imageservice
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	MaxClassifyLabels    = 100
+	MaxClassifyTemplates = 8
+
+	// ClassifyLogitScale is the temperature CLIP was trained with. Cosine similarities of matching
+	// and unrelated captions differ by only a few hundredths, so they are scaled before the softmax.
+	ClassifyLogitScale = 100
+
+	// DefaultLabelTemplate turns a bare label into the kind of caption CLIP was trained on.
+	DefaultLabelTemplate = "a photo of a {label}."
+
+	classifyConcurrency = 8
+	// classifyCacheSize bounds how many prompt embeddings are kept; at 512 dimensions each takes 2 KiB.
+	classifyCacheSize = 4096
+)
+
+// Classify scores an uploaded or stored image against candidate labels. Each label is embedded
+// as the mean of its prompts, one per template, and the scores are turned into a probability
+// distribution over the labels. A stored image is not re-embedded.
+func (s *imageService) Classify(ctx context.Context, stream *models.StorageFileStream, imageID *uuid.UUID, labels []string, templates []string) (*models.Classification, error) {
+	labels, templates, err := validateClassify(stream, imageID, labels, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	var image *models.Embedding
+	prompts := make([][]*models.Embedding, len(labels))
+
+	errGroup, errCtx := errgroup.WithContext(ctx)
+	errGroup.SetLimit(classifyConcurrency)
+
+	errGroup.Go(func() error {
+		if imageID != nil {
+			e, err := s.imageRepository.GetImageEmbedding(errCtx, *imageID, s.config.ModelName)
+			if err != nil {
+				return err
+			}
+			image = &models.Embedding{Model: e.ModelName, Embedding: e.Embedding}
+			return nil
+		}
+
+		e, err := s.clipService.ImageEmbedding(errCtx, stream.Reader)
+		if err != nil {
+			return err
+		}
+		image = e
+		return nil
+	})
+
+	for i, label := range labels {
+		prompts[i] = make([]*models.Embedding, len(templates))
+		for j, template := range templates {
+			errGroup.Go(func() error {
+				e, err := s.promptEmbedding(errCtx, strings.ReplaceAll(template, "{label}", label))
+				if err != nil {
+					return err
+				}
+				prompts[i][j] = e
+				return nil
+			})
+		}
+	}
+
+	if err := errGroup.Wait(); err != nil {
+		return nil, err
+	}
+
+	embedding := normalize(image.Embedding)
+	if embedding == nil {
+		return nil, fmt.Errorf("image has no usable embedding")
+	}
+
+	scores := make([]models.LabelScore, len(labels))
+	for i, label := range labels {
+		vectors := make([][]float32, len(templates))
+		weights := make([]float64, len(templates))
+		for j, e := range prompts[i] {
+			if e.Model != image.Model {
+				if imageID != nil {
+					return nil, errortypes.NewErrInvalidClassifyParameter("image_id", fmt.Sprintf("was embedded by %s, labels are embedded by %s", image.Model, e.Model))
+				}
+				return nil, fmt.Errorf("image and labels were embedded by different models")
+			}
+			vectors[j] = e.Embedding
+			weights[j] = 1
+		}
+
+		labelEmbedding := combineEmbeddings(vectors, weights)
+		if labelEmbedding == nil {
+			return nil, fmt.Errorf("label %q has no usable prompt embedding", label)
+		}
+
+		scores[i] = models.LabelScore{
+			Label: label,
+			Score: dot(embedding, labelEmbedding),
+		}
+	}
+
+	softmax(scores)
+	slices.SortStableFunc(scores, func(a, b models.LabelScore) int {
+		return cmp.Compare(b.Probability, a.Probability)
+	})
+
+	return &models.Classification{
+		ModelName: image.Model,
+		ImageID:   imageID,
+		Labels:    scores,
+	}, nil
+}
+
+// promptEmbedding embeds a classification prompt, reusing the embedding by the configured model
+// when the prompt has been embedded before.
+func (s *imageService) promptEmbedding(ctx context.Context, prompt string) (*models.Embedding, error) {
+	if e, ok := s.promptCache.get(s.config.ModelName, prompt); ok {
+		return &models.Embedding{Model: s.config.ModelName, Embedding: e}, nil
+	}
+
+	e, err := s.clipService.TextEmbedding(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	s.promptCache.put(e.Model, prompt, e.Embedding)
+
+	return e, nil
+}
+
+// promptCache keeps text embeddings by model and prompt, like the autotagger keeps those of its
+// vocabulary. Prompts come from requests, so it holds at most size of them and drops an
+// arbitrary one when full.
+type promptCache struct {
+	mu         sync.Mutex
+	size       int
+	embeddings map[promptKey][]float32
+}
+
+type promptKey struct {
+	modelName string
+	prompt    string
+}
+
+func newPromptCache(size int) *promptCache {
+	return &promptCache{size: size, embeddings: map[promptKey][]float32{}}
+}
+
+func (c *promptCache) get(modelName, prompt string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.embeddings[promptKey{modelName, prompt}]
+	return e, ok
+}
+
+func (c *promptCache) put(modelName, prompt string, embedding []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := promptKey{modelName, prompt}
+	if _, ok := c.embeddings[key]; !ok && len(c.embeddings) >= c.size {
+		for k := range c.embeddings {
+			delete(c.embeddings, k)
+			break
+		}
+	}
+	c.embeddings[key] = embedding
+}
+
+// softmax sets the probabilities of the labels from their scaled scores, subtracting the highest
+// logit first so that the exponentials cannot overflow.
+func softmax(scores []models.LabelScore) {
+	maxLogit := math.Inf(-1)
+	for _, s := range scores {
+		maxLogit = max(maxLogit, ClassifyLogitScale*s.Score)
+	}
+
+	var sum float64
+	for i := range scores {
+		scores[i].Probability = math.Exp(ClassifyLogitScale*scores[i].Score - maxLogit)
+		sum += scores[i].Probability
+	}
+
+	for i := range scores {
+		scores[i].Probability /= sum
+	}
+}
+
+func validateClassify(stream *models.StorageFileStream, imageID *uuid.UUID, labels []string, templates []string) ([]string, []string, error) {
+	if (stream == nil) == (imageID == nil) {
+		return nil, nil, errortypes.NewErrInvalidClassifyParameter("image_id", "exactly one of file and image_id is required")
+	}
+
+	if len(labels) < 2 || len(labels) > MaxClassifyLabels {
+		return nil, nil, errortypes.NewErrInvalidClassifyParameter("labels", fmt.Sprintf("must have between 2 and %d labels", MaxClassifyLabels))
+	}
+
+	trimmed := make([]string, len(labels))
+	for i, label := range labels {
+		trimmed[i] = strings.TrimSpace(label)
+		if trimmed[i] == "" || len(trimmed[i]) > maxLabelLength {
+			return nil, nil, errortypes.NewErrInvalidClassifyParameter("labels", fmt.Sprintf("each label must be between 1 and %d characters", maxLabelLength))
+		}
+		if slices.Contains(trimmed[:i], trimmed[i]) {
+			return nil, nil, errortypes.NewErrInvalidClassifyParameter("labels", fmt.Sprintf("label %q is given more than once", trimmed[i]))
+		}
+	}
+
+	if len(templates) == 0 {
+		templates = []string{DefaultLabelTemplate}
+	}
+	if len(templates) > MaxClassifyTemplates {
+		return nil, nil, errortypes.NewErrInvalidClassifyParameter("templates", fmt.Sprintf("must have at most %d templates", MaxClassifyTemplates))
+	}
+	for _, template := range templates {
+		if !strings.Contains(template, "{label}") {
+			return nil, nil, errortypes.NewErrInvalidClassifyParameter("templates", "each template must contain {label}")
+		}
+	}
+
+	return trimmed, templates, nil
+}
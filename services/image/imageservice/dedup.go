@@ -0,0 +1,141 @@
+package imageservice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/image/imagerepository"
+	"github.com/yckao/image-search-demo-go/services/storage/storageservice"
+)
+
+const ContentHashBackfillBatchSize = 100
+
+// contentHash is the hex SHA-256 of an image's file, by which uploads are deduplicated.
+func contentHash(imageBytes []byte) string {
+	sum := sha256.Sum256(imageBytes)
+	return hex.EncodeToString(sum[:])
+}
+
+// findDuplicate returns the indexed image with the given content hash, marked as a duplicate,
+// or nil if there is none.
+func (s *imageService) findDuplicate(ctx context.Context, contentHash string) (*models.Image, error) {
+	img, err := s.imageRepository.GetImageByContentHash(ctx, contentHash)
+	if err != nil || img == nil {
+		return nil, err
+	}
+
+	if img.URL, err = s.storageService.FormatURL(ctx, &models.StorageFile{
+		Provider: img.StorageProvider,
+		Key:      img.StorageKey,
+	}); err != nil {
+		return nil, err
+	}
+	img.Duplicate = true
+
+	return img, nil
+}
+
+// duplicateOf resolves the conflict of an image whose content was indexed by a concurrent upload
+// after it was checked for. The image's file is removed and the other image returned.
+func (s *imageService) duplicateOf(ctx context.Context, img *models.Image, err error) (*models.Image, error) {
+	var exists *errortypes.ErrImageContentExists
+	if !errors.As(err, &exists) {
+		return nil, err
+	}
+
+	s.deleteFile(ctx, img)
+
+	duplicate, findErr := s.findDuplicate(ctx, exists.ContentHash)
+	if findErr != nil {
+		return nil, findErr
+	}
+	if duplicate == nil {
+		// The other image was deleted in the meantime.
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+// Reasons recorded for images the content hash backfill skips.
+const (
+	ContentHashSkipFileNotFound = "file not found"
+	ContentHashSkipDuplicate    = "duplicate"
+)
+
+// BackfillContentHashes hashes the files of the images indexed before content hashes were
+// recorded. Files are streamed from storage rather than read into memory. An image whose file is
+// missing, or whose content duplicates another's, is left without a hash and recorded as skipped
+// so that later runs do not download it again; duplicates are logged with the image they
+// duplicate. An image that fails otherwise is tried again on the next run.
+func BackfillContentHashes(ctx context.Context, logger log.Logger, storageService storageservice.Service, imageRepository imagerepository.Repository) error {
+	after := uuid.Nil
+	for {
+		images, err := imageRepository.ListImagesWithoutContentHash(ctx, after, ContentHashBackfillBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(images) == 0 {
+			return nil
+		}
+
+		for _, image := range images {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := backfillContentHash(ctx, logger, storageService, imageRepository, &image); err != nil {
+				logger.Log("content_hash", "backfill", "image", image.ID, "error", err)
+			}
+		}
+
+		after = images[len(images)-1].ID
+	}
+}
+
+func backfillContentHash(ctx context.Context, logger log.Logger, storageService storageservice.Service, imageRepository imagerepository.Repository, image *models.Image) error {
+	download, err := storageService.Download(ctx, &models.StorageFile{
+		Provider: image.StorageProvider,
+		Key:      image.StorageKey,
+	})
+	var notFound *errortypes.ErrStorageFileNotFound
+	if errors.As(err, &notFound) {
+		logger.Log("content_hash", "backfill", "image", image.ID, "skipped", ContentHashSkipFileNotFound)
+		return imageRepository.SkipImageContentHash(ctx, image.ID, ContentHashSkipFileNotFound, nil)
+	} else if err != nil {
+		return err
+	}
+	if closer, ok := download.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, download.Reader); err != nil {
+		return err
+	}
+	contentHash := hex.EncodeToString(hash.Sum(nil))
+
+	err = imageRepository.SetImageContentHash(ctx, image.ID, contentHash)
+	var exists *errortypes.ErrImageContentExists
+	if !errors.As(err, &exists) {
+		return err
+	}
+
+	duplicate, findErr := imageRepository.GetImageByContentHash(ctx, contentHash)
+	if findErr != nil {
+		return findErr
+	}
+	if duplicate == nil {
+		// The other image was deleted in the meantime; the next run hashes this one.
+		return err
+	}
+
+	logger.Log("content_hash", "backfill", "image", image.ID, "skipped", ContentHashSkipDuplicate, "duplicate_of", duplicate.ID)
+	return imageRepository.SkipImageContentHash(ctx, image.ID, ContentHashSkipDuplicate, &duplicate.ID)
+}
@@ -0,0 +1,202 @@
+package imageservice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/image/imagemodel"
+	"github.com/yckao/image-search-demo-go/services/image/imagerepository"
+	"github.com/yckao/image-search-demo-go/services/storage/storageservice"
+)
+
+type contentHashSkip struct {
+	reason      string
+	duplicateOf *uuid.UUID
+}
+
+// dedupRepository keeps images by content hash in memory, enforcing the unique index the way
+// the Postgres repository does.
+type dedupRepository struct {
+	imagerepository.Repository
+	unhashed   []models.Image
+	hashed     map[string]models.Image
+	skips      map[uuid.UUID]contentHashSkip
+	duplicates []int
+}
+
+func (r *dedupRepository) ListImagesWithoutContentHash(ctx context.Context, after uuid.UUID, limit int) ([]models.Image, error) {
+	var page []models.Image
+	for _, image := range r.unhashed {
+		if _, skipped := r.skips[image.ID]; skipped || image.ContentHash != nil || slices.Compare(image.ID[:], after[:]) <= 0 {
+			continue
+		}
+		if len(page) == limit {
+			break
+		}
+		page = append(page, image)
+	}
+	return page, nil
+}
+
+func (r *dedupRepository) SetImageContentHash(ctx context.Context, id uuid.UUID, contentHash string) error {
+	if _, ok := r.hashed[contentHash]; ok {
+		return errortypes.NewErrImageContentExists(contentHash)
+	}
+	for i := range r.unhashed {
+		if r.unhashed[i].ID == id {
+			r.unhashed[i].ContentHash = &contentHash
+			r.hashed[contentHash] = r.unhashed[i]
+		}
+	}
+	return nil
+}
+
+func (r *dedupRepository) GetImageByContentHash(ctx context.Context, contentHash string) (*models.Image, error) {
+	image, ok := r.hashed[contentHash]
+	if !ok {
+		return nil, nil
+	}
+	return &image, nil
+}
+
+func (r *dedupRepository) SkipImageContentHash(ctx context.Context, id uuid.UUID, reason string, duplicateOf *uuid.UUID) error {
+	r.skips[id] = contentHashSkip{reason: reason, duplicateOf: duplicateOf}
+	return nil
+}
+
+func (r *dedupRepository) CreateImages(ctx context.Context, images []*models.Image, embeddings []*imagemodel.ImageEmbedding) ([]int, error) {
+	return r.duplicates, nil
+}
+
+// memoryStorage serves files from memory. The key "unavailable" fails as if storage were down.
+type memoryStorage struct {
+	storageservice.Service
+	files   map[string][]byte
+	deleted []string
+}
+
+func (s *memoryStorage) Download(ctx context.Context, file *models.StorageFile) (*models.StorageFileStream, error) {
+	if file.Key == "unavailable" {
+		return nil, errors.New("storage unavailable")
+	}
+	data, ok := s.files[file.Key]
+	if !ok {
+		return nil, errortypes.NewErrStorageFileNotFound(file.Provider, file.Key)
+	}
+	return &models.StorageFileStream{Reader: bytes.NewReader(data), ContentLength: int64(len(data))}, nil
+}
+
+func (s *memoryStorage) FormatURL(ctx context.Context, file *models.StorageFile) (string, error) {
+	return "https://storage.example.com/" + file.Key, nil
+}
+
+func (s *memoryStorage) Delete(ctx context.Context, file *models.StorageFile) error {
+	s.deleted = append(s.deleted, file.Key)
+	return nil
+}
+
+func TestBackfillContentHashes(t *testing.T) {
+	indexed := models.Image{ID: uuid.Must(uuid.NewV7()), StorageKey: "indexed.png"}
+	indexedHash := contentHash([]byte("indexed"))
+	indexed.ContentHash = &indexedHash
+
+	tests := []struct {
+		name       string
+		key        string
+		wantHash   string
+		wantSkip   *contentHashSkip
+		wantListed bool
+	}{
+		{name: "file is hashed", key: "new.png", wantHash: contentHash([]byte("new"))},
+		{name: "missing file is skipped", key: "missing.png", wantSkip: &contentHashSkip{reason: ContentHashSkipFileNotFound}},
+		{name: "duplicate is skipped and linked", key: "copy.png", wantSkip: &contentHashSkip{reason: ContentHashSkipDuplicate, duplicateOf: &indexed.ID}},
+		{name: "unavailable storage is tried again", key: "unavailable", wantListed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := models.Image{ID: uuid.Must(uuid.NewV7()), StorageKey: tt.key}
+			repository := &dedupRepository{
+				unhashed: []models.Image{image},
+				hashed:   map[string]models.Image{indexedHash: indexed},
+				skips:    map[uuid.UUID]contentHashSkip{},
+			}
+			storage := &memoryStorage{files: map[string][]byte{"new.png": []byte("new"), "copy.png": []byte("indexed")}}
+
+			if err := BackfillContentHashes(context.Background(), log.NewNopLogger(), storage, repository); err != nil {
+				t.Fatalf("BackfillContentHashes() error = %v", err)
+			}
+
+			var gotHash string
+			if hash := repository.unhashed[0].ContentHash; hash != nil {
+				gotHash = *hash
+			}
+			if gotHash != tt.wantHash {
+				t.Errorf("content hash = %q, want %q", gotHash, tt.wantHash)
+			}
+
+			skip, skipped := repository.skips[image.ID]
+			switch {
+			case tt.wantSkip == nil && skipped:
+				t.Errorf("image skipped as %q, want it not skipped", skip.reason)
+			case tt.wantSkip != nil && !skipped:
+				t.Errorf("image not skipped, want it skipped as %q", tt.wantSkip.reason)
+			case tt.wantSkip != nil && (skip.reason != tt.wantSkip.reason || fmt.Sprint(skip.duplicateOf) != fmt.Sprint(tt.wantSkip.duplicateOf)):
+				t.Errorf("image skipped as %q, duplicate of %v, want %q, duplicate of %v", skip.reason, skip.duplicateOf, tt.wantSkip.reason, tt.wantSkip.duplicateOf)
+			}
+
+			// A second run downloads only what is worth trying again.
+			listed, _ := repository.ListImagesWithoutContentHash(context.Background(), uuid.Nil, ContentHashBackfillBatchSize)
+			if (len(listed) > 0) != tt.wantListed {
+				t.Errorf("image listed for the next run = %t, want %t", len(listed) > 0, tt.wantListed)
+			}
+		})
+	}
+}
+
+func TestInsertImportBatchDuplicate(t *testing.T) {
+	indexed := models.Image{ID: uuid.Must(uuid.NewV7()), StorageKey: "indexed.png"}
+	indexedHash := contentHash([]byte("indexed"))
+	indexed.ContentHash = &indexedHash
+
+	var batch []importedImage
+	imports := make([]models.ImageImport, 3)
+	for i, content := range []string{"first", "indexed", "third"} {
+		hash := contentHash([]byte(content))
+		batch = append(batch, importedImage{
+			index:     i,
+			image:     &models.Image{ID: uuid.Must(uuid.NewV7()), StorageKey: "upload-" + content + ".png", ContentHash: &hash},
+			embedding: &models.Embedding{},
+		})
+	}
+
+	// The second image's content was indexed by another upload after the import checked for it.
+	repository := &dedupRepository{hashed: map[string]models.Image{indexedHash: indexed}, duplicates: []int{1}}
+	storage := &memoryStorage{}
+	s := New(log.NewNopLogger(), Config{}, nil, storage, repository).(*imageService)
+
+	s.insertImportBatch(context.Background(), batch, imports)
+
+	for i, want := range []models.ImageImportStatus{models.ImageImportStatusCreated, models.ImageImportStatusSkipped, models.ImageImportStatusCreated} {
+		if imports[i].Status != want {
+			t.Errorf("file %d status = %s, want %s", i, imports[i].Status, want)
+		}
+	}
+	if got := imports[1].Image; got == nil || got.ID != indexed.ID || !got.Duplicate {
+		t.Errorf("duplicate file image = %+v, want the indexed image marked as a duplicate", got)
+	}
+	if want := fmt.Sprintf("duplicate of image %s", indexed.ID); imports[1].Reason != want {
+		t.Errorf("duplicate file reason = %q, want %q", imports[1].Reason, want)
+	}
+	if !slices.Equal(storage.deleted, []string{"upload-indexed.png"}) {
+		t.Errorf("deleted files = %v, want only the duplicate's", storage.deleted)
+	}
+}
@@ -0,0 +1,153 @@
+package imageservice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/pkg/netguard"
+)
+
+const (
+	DefaultFetchTimeout = 30 * time.Second
+	DefaultFetchMaxSize = 10 << 20
+
+	maxFetchRedirects = 5
+)
+
+type URLFetcherConfig struct {
+	// Timeout bounds a whole fetch, redirects and body included. Zero uses DefaultFetchTimeout.
+	Timeout time.Duration
+	// MaxSize is the largest image fetched, in bytes. Zero uses DefaultFetchMaxSize.
+	MaxSize int64
+	// Allowlist names the hosts and CIDR ranges that may be fetched from even though they
+	// resolve to loopback, private or other internal addresses.
+	Allowlist []string
+}
+
+// URLFetcher downloads images for ingestion by URL. To keep the service from being used to
+// reach internal systems, it connects through a netguard.Guard, which only lets public addresses
+// and allowlisted hosts and ranges through. Callers only learn the kind of a failed fetch; the
+// full error, which may name internal addresses, is logged.
+type URLFetcher struct {
+	logger log.Logger
+	config URLFetcherConfig
+	client *http.Client
+}
+
+func NewURLFetcher(logger log.Logger, config URLFetcherConfig) (*URLFetcher, error) {
+	if config.Timeout == 0 {
+		config.Timeout = DefaultFetchTimeout
+	}
+	if config.MaxSize == 0 {
+		config.MaxSize = DefaultFetchMaxSize
+	}
+
+	guard, err := netguard.New(config.Allowlist)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := guard.Transport()
+	transport.ResponseHeaderTimeout = config.Timeout
+
+	return &URLFetcher{
+		logger: logger,
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxFetchRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxFetchRedirects)
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("redirected to unsupported scheme %s", req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+	}, nil
+}
+
+// Fetch downloads the image at rawURL. The response must declare an image content type, or a
+// generic binary one whose bytes are sniffed as an image, and fit in URLFetcherConfig.MaxSize.
+func (f *URLFetcher) Fetch(ctx context.Context, rawURL string) (*models.StorageFileStream, error) {
+	u, err := parseImageURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, errortypes.NewErrInvalidImageParameter("url", "must be an absolute http or https URL")
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		f.logger.Log("url", rawURL, "error", err)
+		var blocked *netguard.ErrAddressBlocked
+		if errors.As(err, &blocked) {
+			return nil, errortypes.NewErrImageURLNotAllowed(rawURL, netguard.Reason(err))
+		}
+		return nil, errortypes.NewErrImageFetchFailed(rawURL, netguard.Reason(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, errortypes.NewErrImageFetchFailed(rawURL, fmt.Sprintf("server responded with status %d", resp.StatusCode))
+	}
+
+	if resp.ContentLength > f.config.MaxSize {
+		return nil, errortypes.NewErrInvalidImageParameter("url", fmt.Sprintf("must point to an image of at most %d bytes", f.config.MaxSize))
+	}
+
+	imageBytes, err := io.ReadAll(io.LimitReader(resp.Body, f.config.MaxSize+1))
+	if err != nil {
+		f.logger.Log("url", rawURL, "error", err)
+		return nil, errortypes.NewErrImageFetchFailed(rawURL, netguard.Reason(err))
+	}
+	if int64(len(imageBytes)) > f.config.MaxSize {
+		return nil, errortypes.NewErrInvalidImageParameter("url", fmt.Sprintf("must point to an image of at most %d bytes", f.config.MaxSize))
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = http.DetectContentType(imageBytes)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, errortypes.NewErrInvalidImageParameter("url", "must point to an image")
+	}
+
+	filename := path.Base(resp.Request.URL.Path)
+	if filename == "/" || filename == "." {
+		filename = "image"
+	}
+
+	return &models.StorageFileStream{
+		Reader:        bytes.NewReader(imageBytes),
+		Filename:      filename,
+		ContentType:   contentType,
+		ContentLength: int64(len(imageBytes)),
+	}, nil
+}
+
+func parseImageURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errortypes.NewErrInvalidImageParameter("url", "must be an absolute http or https URL")
+	}
+	return u, nil
+}
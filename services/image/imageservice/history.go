@@ -0,0 +1,92 @@
+package imageservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/image/imagemodel"
+)
+
+const (
+	DefaultSearchHistoryLimit = 20
+	MaxSearchHistoryLimit     = 100
+	MaxQueryTextFilterLength  = 255
+)
+
+// GetSearch returns a recorded search with every result shown for it and its feedback.
+func (s *imageService) GetSearch(ctx context.Context, id uuid.UUID) (*models.SearchRecord, error) {
+	record, err := s.imageRepository.GetSearchRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.formatSearchURLs(ctx, &record.SearchWithResults); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// ListSearches pages through recorded searches, newest first. The cursor is the next_cursor of
+// the previous page and must be used with the same filters.
+func (s *imageService) ListSearches(ctx context.Context, filters models.SearchHistoryFilters, cursor string, limit int) (*models.SearchHistory, error) {
+	if err := validateSearchHistoryFilters(filters); err != nil {
+		return nil, err
+	}
+
+	if limit == 0 {
+		limit = DefaultSearchHistoryLimit
+	}
+	if limit < 0 || limit > MaxSearchHistoryLimit {
+		return nil, errortypes.NewErrInvalidSearchParameter("limit", fmt.Sprintf("must be between 1 and %d", MaxSearchHistoryLimit))
+	}
+
+	var after *imagemodel.SearchHistoryCursor
+	if cursor != "" {
+		var err error
+		if after, err = imagemodel.DecodeSearchHistoryCursor(cursor); err != nil {
+			return nil, errortypes.NewErrInvalidSearchParameter("cursor", err.Error())
+		}
+	}
+
+	records, err := s.imageRepository.ListSearchRecords(ctx, filters, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		if err := s.formatSearchURLs(ctx, &records[i].SearchWithResults); err != nil {
+			return nil, err
+		}
+	}
+
+	history := &models.SearchHistory{Searches: records}
+	if len(records) == limit {
+		last := records[len(records)-1]
+		history.NextCursor = imagemodel.SearchHistoryCursor{
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		}.Encode()
+	}
+
+	return history, nil
+}
+
+func validateSearchHistoryFilters(f models.SearchHistoryFilters) error {
+	if f.CreatedAfter != nil && f.CreatedBefore != nil && !f.CreatedAfter.Before(*f.CreatedBefore) {
+		return errortypes.NewErrInvalidSearchParameter("created_before", "must be after created_after")
+	}
+	if f.Rating != "" && f.Rating != models.RatingPositive && f.Rating != models.RatingNegative {
+		return errortypes.NewErrInvalidSearchParameter("rating", fmt.Sprintf("must be %s or %s", models.RatingPositive, models.RatingNegative))
+	}
+	if f.Rating != "" && f.HasFeedback != nil && !*f.HasFeedback {
+		return errortypes.NewErrInvalidSearchParameter("rating", "cannot be combined with has_feedback=false")
+	}
+	if len(f.QueryText) > MaxQueryTextFilterLength {
+		return errortypes.NewErrInvalidSearchParameter("text", fmt.Sprintf("must be at most %d characters", MaxQueryTextFilterLength))
+	}
+	return nil
+}
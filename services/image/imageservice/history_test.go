@@ -0,0 +1,107 @@
+package imageservice
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/image/imagemodel"
+	"github.com/yckao/image-search-demo-go/services/image/imagerepository"
+)
+
+// historyRepository serves ListSearchRecords from memory with the keyset the Postgres
+// repository uses: newest first by (created_at, id).
+type historyRepository struct {
+	imagerepository.Repository
+	records []models.SearchRecord
+}
+
+func (r *historyRepository) ListSearchRecords(ctx context.Context, filters models.SearchHistoryFilters, after *imagemodel.SearchHistoryCursor, limit int) ([]models.SearchRecord, error) {
+	var page []models.SearchRecord
+	for _, record := range r.records {
+		if after != nil && compareKeyset(record.CreatedAt, record.ID, after.CreatedAt, after.ID) >= 0 {
+			continue
+		}
+		if len(page) == limit {
+			break
+		}
+		page = append(page, record)
+	}
+	return page, nil
+}
+
+// compareKeyset compares (createdAt, id) rows the way Postgres compares row values.
+func compareKeyset(aCreatedAt time.Time, aID uuid.UUID, bCreatedAt time.Time, bID uuid.UUID) int {
+	if c := aCreatedAt.Compare(bCreatedAt); c != 0 {
+		return c
+	}
+	return slices.Compare(aID[:], bID[:])
+}
+
+func TestListSearchesPagesWithinEqualTimestamps(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repository := &historyRepository{}
+	// Searches recorded within the same microsecond are told apart by ID.
+	for _, c := range []time.Time{base.Add(2 * time.Second), base.Add(time.Second), base.Add(time.Second), base.Add(time.Second)} {
+		record := models.SearchRecord{}
+		record.ID = uuid.Must(uuid.NewV7())
+		record.CreatedAt = c
+		repository.records = append(repository.records, record)
+	}
+	slices.SortFunc(repository.records, func(a, b models.SearchRecord) int {
+		return compareKeyset(b.CreatedAt, b.ID, a.CreatedAt, a.ID)
+	})
+
+	s := New(log.NewNopLogger(), Config{}, nil, nil, repository)
+
+	var seen []uuid.UUID
+	cursor := ""
+	for range len(repository.records) {
+		history, err := s.ListSearches(context.Background(), models.SearchHistoryFilters{}, cursor, 3)
+		if err != nil {
+			t.Fatalf("ListSearches() error = %v", err)
+		}
+		for _, record := range history.Searches {
+			seen = append(seen, record.ID)
+		}
+		if history.NextCursor == "" {
+			break
+		}
+		cursor = history.NextCursor
+	}
+
+	want := make([]uuid.UUID, len(repository.records))
+	for i, record := range repository.records {
+		want[i] = record.ID
+	}
+	if !slices.Equal(seen, want) {
+		t.Errorf("searches = %v, want each once in order %v", seen, want)
+	}
+}
+
+func TestListSearchesInvalidParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+		limit  int
+	}{
+		{name: "search result cursor", cursor: imagemodel.SearchCursor{SearchID: uuid.New(), Rank: 1}.Encode(), limit: 10},
+		{name: "limit above the maximum", limit: MaxSearchHistoryLimit + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(log.NewNopLogger(), Config{}, nil, nil, &historyRepository{})
+
+			_, err := s.ListSearches(context.Background(), models.SearchHistoryFilters{}, tt.cursor, tt.limit)
+			if _, ok := err.(*errortypes.ErrInvalidSearchParameter); !ok {
+				t.Errorf("ListSearches() error = %v, want ErrInvalidSearchParameter", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package imageservice
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/image/imagemodel"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	DefaultImportConcurrency = 8
+	// ImportBatchSize is how many imported images are inserted per transaction.
+	ImportBatchSize = 100
+	// MaxImportEntrySize matches the upload limit of POST /images. It is checked against the
+	// uncompressed size so that a small archive cannot expand without bound.
+	MaxImportEntrySize = 10 << 20
+)
+
+// importedImage is an archive entry that has been embedded and stored and waits to be inserted.
+type importedImage struct {
+	index     int
+	image     *models.Image
+	embedding *models.Embedding
+}
+
+// ImportImages indexes every image in a ZIP archive. Entries are embedded and uploaded by
+// Config.ImportConcurrency workers as the archive is read, and inserted ImportBatchSize at a
+// time. Directories are left out of the report and files that are not images are skipped; an
+// entry that fails does not fail the others.
+func (s *imageService) ImportImages(ctx context.Context, archive io.ReaderAt, size int64) ([]models.ImageImport, error) {
+	reader, err := zip.NewReader(archive, size)
+	if err != nil {
+		return nil, errortypes.NewErrInvalidImageParameter("file", "must be a ZIP archive")
+	}
+
+	var files []*zip.File
+	for _, file := range reader.File {
+		if !file.FileInfo().IsDir() {
+			files = append(files, file)
+		}
+	}
+
+	concurrency := s.config.ImportConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultImportConcurrency
+	}
+
+	imports := make([]models.ImageImport, len(files))
+	imported := make(chan importedImage)
+
+	go func() {
+		defer close(imported)
+
+		errGroup := errgroup.Group{}
+		errGroup.SetLimit(concurrency)
+
+		for i, file := range files {
+			imports[i] = models.ImageImport{Index: i, Filename: file.Name}
+
+			errGroup.Go(func() error {
+				img, embedding, reason, err := s.importEntry(ctx, file)
+				switch {
+				case err != nil:
+					imports[i].Status = models.ImageImportStatusFailed
+					imports[i].Err = err
+				case reason != "":
+					imports[i].Status = models.ImageImportStatusSkipped
+					imports[i].Reason = reason
+					imports[i].Image = img
+				default:
+					imported <- importedImage{index: i, image: img, embedding: embedding}
+				}
+				return nil
+			})
+		}
+
+		errGroup.Wait()
+	}()
+
+	// Entries are checked against the index before they are embedded, but not against each
+	// other, so an entry whose content appeared earlier in the archive is dropped here.
+	seen := map[string]int{}
+	batch := make([]importedImage, 0, ImportBatchSize)
+	for image := range imported {
+		if first, ok := seen[*image.image.ContentHash]; ok {
+			imports[image.index].Status = models.ImageImportStatusSkipped
+			imports[image.index].Reason = fmt.Sprintf("duplicate of %s", imports[first].Filename)
+			s.deleteFile(ctx, image.image)
+			continue
+		}
+		seen[*image.image.ContentHash] = image.index
+
+		if batch = append(batch, image); len(batch) == ImportBatchSize {
+			s.insertImportBatch(ctx, batch, imports)
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		s.insertImportBatch(ctx, batch, imports)
+	}
+
+	return imports, nil
+}
+
+// importEntry embeds, stores and auto-tags one archive entry, or returns why it was skipped
+// together with the indexed image it duplicates, if any.
+func (s *imageService) importEntry(ctx context.Context, file *zip.File) (*models.Image, *models.Embedding, string, error) {
+	filename := path.Base(file.Name)
+	if strings.HasPrefix(file.Name, "__MACOSX/") || strings.HasPrefix(filename, ".") {
+		return nil, nil, "hidden or metadata file", nil
+	}
+
+	if file.UncompressedSize64 > MaxImportEntrySize {
+		return nil, nil, "", errortypes.NewErrInvalidImportEntry(file.Name, fmt.Sprintf("must be at most %d bytes", MaxImportEntrySize))
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	defer rc.Close()
+
+	// The header's size is not to be trusted, so the read is capped as well.
+	imageBytes, err := io.ReadAll(io.LimitReader(rc, MaxImportEntrySize+1))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if len(imageBytes) > MaxImportEntrySize {
+		return nil, nil, "", errortypes.NewErrInvalidImportEntry(file.Name, fmt.Sprintf("must be at most %d bytes", MaxImportEntrySize))
+	}
+
+	contentType := http.DetectContentType(imageBytes)
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, nil, "not an image", nil
+	}
+	if byExtension := mime.TypeByExtension(path.Ext(filename)); strings.HasPrefix(byExtension, "image/") {
+		contentType = byExtension
+	}
+
+	hash := contentHash(imageBytes)
+	if duplicate, err := s.findDuplicate(ctx, hash); err != nil {
+		return nil, nil, "", err
+	} else if duplicate != nil {
+		return duplicate, nil, fmt.Sprintf("duplicate of image %s", duplicate.ID), nil
+	}
+
+	img, embedding, err := s.newImage(ctx, &models.StorageFileStream{
+		Filename:      filename,
+		ContentType:   contentType,
+		ContentLength: int64(len(imageBytes)),
+	}, imageBytes, models.ImageMetadata{})
+	if err != nil {
+		return nil, nil, "", err
+	}
+	img.ContentHash = &hash
+
+	return img, embedding, "", nil
+}
+
+// insertImportBatch indexes a batch of imported images and records the outcome of each. An image
+// whose content was indexed since it was checked for is skipped as a duplicate of that image, as
+// POST /images returns it. When the insert fails, every image of the batch fails with it and
+// their files are removed.
+func (s *imageService) insertImportBatch(ctx context.Context, batch []importedImage, imports []models.ImageImport) {
+	images := make([]*models.Image, len(batch))
+	embeddings := make([]*imagemodel.ImageEmbedding, len(batch))
+	for i, imported := range batch {
+		images[i] = imported.image
+		embeddings[i] = &imagemodel.ImageEmbedding{
+			ModelName: imported.embedding.Model,
+			Embedding: imported.embedding.Embedding,
+		}
+	}
+
+	duplicates, err := s.imageRepository.CreateImages(ctx, images, embeddings)
+	if err != nil {
+		for _, imported := range batch {
+			imports[imported.index].Status = models.ImageImportStatusFailed
+			imports[imported.index].Err = err
+			s.deleteFile(ctx, imported.image)
+		}
+		return
+	}
+
+	for i, imported := range batch {
+		if slices.Contains(duplicates, i) {
+			s.importDuplicate(ctx, imported, &imports[imported.index])
+			continue
+		}
+
+		imports[imported.index].Status = models.ImageImportStatusCreated
+		imports[imported.index].Image = imported.image
+	}
+}
+
+// importDuplicate reports an imported image that was left out because another image has its
+// content, removing its file.
+func (s *imageService) importDuplicate(ctx context.Context, imported importedImage, result *models.ImageImport) {
+	duplicate, err := s.duplicateOf(ctx, imported.image, errortypes.NewErrImageContentExists(*imported.image.ContentHash))
+	if err != nil {
+		result.Status = models.ImageImportStatusFailed
+		result.Err = err
+		return
+	}
+
+	result.Status = models.ImageImportStatusSkipped
+	result.Reason = fmt.Sprintf("duplicate of image %s", duplicate.ID)
+	result.Image = duplicate
+}
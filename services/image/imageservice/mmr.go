@@ -0,0 +1,67 @@
+package imageservice
+
+import (
+	"math"
+
+	"github.com/yckao/image-search-demo-go/services/image/imagemodel"
+)
+
+// mmr picks up to k candidates by Maximal Marginal Relevance. Each step selects the candidate
+// maximizing (1-diversity)*sim(query, c) - diversity*max sim(c, s) over everything selected so
+// far, including the embeddings of results shown on earlier pages. Candidates must carry their
+// embeddings and be ordered by distance to the query.
+func mmr(candidates []imagemodel.SearchCandidate, shown [][]float32, diversity float64, k int) []imagemodel.SearchCandidate {
+	units := make([][]float32, len(candidates))
+	for i, candidate := range candidates {
+		units[i] = normalize(candidate.Embedding)
+	}
+
+	// redundancy[i] tracks the highest similarity of candidate i to anything selected so far.
+	redundancy := make([]float64, len(candidates))
+	for i := range redundancy {
+		redundancy[i] = math.Inf(-1)
+	}
+
+	penalize := func(selected []float32) {
+		for i, unit := range units {
+			redundancy[i] = max(redundancy[i], dot(unit, selected))
+		}
+	}
+
+	for _, embedding := range shown {
+		if unit := normalize(embedding); unit != nil {
+			penalize(unit)
+		}
+	}
+
+	picked := make([]bool, len(candidates))
+	selected := make([]imagemodel.SearchCandidate, 0, min(k, len(candidates)))
+
+	for len(selected) < k {
+		best, bestScore := -1, math.Inf(-1)
+		for i, candidate := range candidates {
+			if picked[i] {
+				continue
+			}
+
+			score := (1 - diversity) * (1 - candidate.Distance)
+			if !math.IsInf(redundancy[i], -1) {
+				score -= diversity * redundancy[i]
+			}
+
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		if best < 0 {
+			break
+		}
+
+		picked[best] = true
+		selected = append(selected, candidates[best])
+		penalize(units[best])
+	}
+
+	return selected
+}
@@ -0,0 +1,73 @@
+package imageservice
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/image/imagemodel"
+)
+
+func TestMMR(t *testing.T) {
+	// b is a near-duplicate of a; c is less relevant but unlike either.
+	candidates := []imagemodel.SearchCandidate{
+		{SearchResult: models.SearchResult{Distance: 0.10, Image: models.Image{StorageKey: "a"}}, Embedding: []float32{1, 0}},
+		{SearchResult: models.SearchResult{Distance: 0.12, Image: models.Image{StorageKey: "b"}}, Embedding: []float32{1, 0.01}},
+		{SearchResult: models.SearchResult{Distance: 0.30, Image: models.Image{StorageKey: "c"}}, Embedding: []float32{0, 1}},
+	}
+	ties := []imagemodel.SearchCandidate{
+		{SearchResult: models.SearchResult{Distance: 0.20, Image: models.Image{StorageKey: "x"}}, Embedding: []float32{1, 0}},
+		{SearchResult: models.SearchResult{Distance: 0.20, Image: models.Image{StorageKey: "y"}}, Embedding: []float32{0, 1}},
+	}
+
+	tests := []struct {
+		name       string
+		candidates []imagemodel.SearchCandidate
+		shown      [][]float32
+		diversity  float64
+		k          int
+		want       []string
+	}{
+		{
+			name:       "diversity moves the near-duplicate down",
+			candidates: candidates,
+			diversity:  0.5,
+			k:          10,
+			want:       []string{"a", "c", "b"},
+		},
+		{
+			name:       "results shown on earlier pages count as selected",
+			candidates: candidates,
+			shown:      [][]float32{{1, 0}},
+			diversity:  0.5,
+			k:          3,
+			want:       []string{"c", "a", "b"},
+		},
+		{
+			name:       "ties keep the similarity order",
+			candidates: ties,
+			diversity:  0.5,
+			k:          2,
+			want:       []string{"x", "y"},
+		},
+		{
+			name:       "no candidates",
+			candidates: nil,
+			diversity:  0.5,
+			k:          3,
+			want:       []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, c := range mmr(tt.candidates, tt.shown, tt.diversity, tt.k) {
+				got = append(got, c.Image.StorageKey)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("mmr() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package imageservice
+
+import (
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/image/imagemodel"
+)
+
+// Rocchio weights for the original query and the centroids of positively and negatively rated results.
+const (
+	RocchioAlpha = 1.0
+	RocchioBeta  = 0.75
+	RocchioGamma = 0.15
+)
+
+// rocchio moves query towards the centroid of the positively rated embeddings and away from
+// the centroid of the negatively rated ones. It returns nil if the result degenerates to a
+// zero vector.
+func rocchio(query []float32, rated []imagemodel.RatedEmbedding) []float32 {
+	var positive, negative [][]float32
+	for _, r := range rated {
+		if r.Rating == models.RatingPositive {
+			positive = append(positive, r.Embedding)
+		} else {
+			negative = append(negative, r.Embedding)
+		}
+	}
+
+	vectors := [][]float32{query}
+	weights := []float64{RocchioAlpha}
+
+	for _, v := range positive {
+		vectors = append(vectors, v)
+		weights = append(weights, RocchioBeta/float64(len(positive)))
+	}
+	for _, v := range negative {
+		vectors = append(vectors, v)
+		weights = append(weights, -RocchioGamma/float64(len(negative)))
+	}
+
+	return combineEmbeddings(vectors, weights)
+}
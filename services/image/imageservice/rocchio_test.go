@@ -0,0 +1,50 @@
+package imageservice
+
+import (
+	"testing"
+
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/image/imagemodel"
+)
+
+func TestRocchio(t *testing.T) {
+	query := []float32{1, 0}
+
+	tests := []struct {
+		name  string
+		rated []imagemodel.RatedEmbedding
+		want  []float32
+	}{
+		{
+			name: "positive and negative feedback combine",
+			rated: []imagemodel.RatedEmbedding{
+				{Rating: models.RatingPositive, Embedding: []float32{0, 2}},
+				{Rating: models.RatingNegative, Embedding: []float32{0, -1}},
+			},
+			want: normalize([]float32{RocchioAlpha, RocchioBeta + RocchioGamma}),
+		},
+		{
+			name: "results of a rating are averaged",
+			rated: []imagemodel.RatedEmbedding{
+				{Rating: models.RatingPositive, Embedding: []float32{0, 1}},
+				{Rating: models.RatingPositive, Embedding: []float32{0, -1}},
+			},
+			want: []float32{1, 0},
+		},
+		{
+			name: "embeddings of another dimension",
+			rated: []imagemodel.RatedEmbedding{
+				{Rating: models.RatingPositive, Embedding: []float32{0, 1, 0}},
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rocchio(query, tt.rated); !approxEqual(got, tt.want) {
+				t.Errorf("rocchio() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
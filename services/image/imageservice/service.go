@@ -3,12 +3,17 @@ package imageservice
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/google/uuid"
 	"github.com/yckao/image-search-demo-go/pkg/clients/clip"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
 	"github.com/yckao/image-search-demo-go/pkg/models"
 	"github.com/yckao/image-search-demo-go/services/image/imagemodel"
 	"github.com/yckao/image-search-demo-go/services/image/imagerepository"
@@ -17,34 +22,216 @@ import (
 )
 
 type Service interface {
-	CreateImage(ctx context.Context, image *models.StorageFileStream) (*models.Image, error)
+	CreateImage(ctx context.Context, image *models.StorageFileStream, metadata models.ImageMetadata) (*models.Image, error)
+	CreateImageFromURL(ctx context.Context, url string, metadata models.ImageMetadata) (*models.Image, error)
+	CreateImageAsync(ctx context.Context, image *models.StorageFileStream, metadata models.ImageMetadata) (*models.Job, error)
+	CreateImageFromURLAsync(ctx context.Context, url string, metadata models.ImageMetadata) (*models.Job, error)
 	GetImage(ctx context.Context, id uuid.UUID) (*models.Image, error)
-	SearchImage(ctx context.Context, query string) (*models.SearchWithImage, error)
-	SearchFeedback(ctx context.Context, query_id uuid.UUID, rating models.Rating) (*models.SearchFeedbackWithQuery, error)
+	ReplaceImageContent(ctx context.Context, id uuid.UUID, image *models.StorageFileStream) (*models.Image, error)
+	ImportImages(ctx context.Context, archive io.ReaderAt, size int64) ([]models.ImageImport, error)
+	DeleteImage(ctx context.Context, id uuid.UUID) error
+	DeleteImages(ctx context.Context, ids []uuid.UUID) ([]models.ImageDeletion, error)
+	SearchImage(ctx context.Context, query string, options models.SearchOptions) (*models.SearchWithResults, error)
+	SearchComposed(ctx context.Context, terms []models.QueryTerm, options models.SearchOptions) (*models.SearchWithResults, error)
+	SearchBatch(ctx context.Context, queries []models.BatchSearchQuery) ([]models.BatchSearchResult, error)
+	SearchSimilarImages(ctx context.Context, id uuid.UUID, options models.SearchOptions) (*models.SearchWithResults, error)
+	SearchFeedback(ctx context.Context, query_id uuid.UUID, imageID *uuid.UUID, rating models.Rating) (*models.SearchFeedbackWithQuery, error)
+	RefineSearch(ctx context.Context, id uuid.UUID, options models.SearchOptions) (*models.SearchWithResults, error)
+	GetSearch(ctx context.Context, id uuid.UUID) (*models.SearchRecord, error)
+	ListSearches(ctx context.Context, filters models.SearchHistoryFilters, cursor string, limit int) (*models.SearchHistory, error)
+	SuggestQueries(ctx context.Context, prefix string, similarTo string, limit int) ([]models.QuerySuggestion, error)
+	StartAutotagRun(ctx context.Context) (*models.AutotagRun, error)
+	GetAutotagRun(ctx context.Context, id uuid.UUID) (*models.AutotagRun, error)
+	Classify(ctx context.Context, image *models.StorageFileStream, imageID *uuid.UUID, labels []string, templates []string) (*models.Classification, error)
+}
+
+const (
+	DefaultSearchLimit = 10
+	MaxSearchLimit     = 100
+	MaxQueryTerms      = 8
+
+	MaxBatchSearchQueries         = 100
+	DefaultBatchSearchConcurrency = 8
+
+	MaxDeleteImages = 100
+	// fileDeleteConcurrency bounds how many files of a bulk deletion are removed at once.
+	fileDeleteConcurrency = 8
+
+	MaxFilenamePatternLength = 255
+
+	// RerankCandidateFactor is how many candidates per requested result are fetched for re-ranking.
+	RerankCandidateFactor = 5
+	MaxRerankCandidates   = 500
+
+	// HybridRRFK damps the influence of top ranks in reciprocal rank fusion; 60 is the usual choice.
+	HybridRRFK = 60
+)
+
+type Config struct {
+	// ModelName is the CLIP model that embeds images and queries. Searches that start from a
+	// stored image use its embedding by this model.
+	ModelName string
+	// DefaultMinScore is applied when a search sets neither min_score nor max_distance.
+	// A score is the cosine similarity 1 - distance, so it must be between -1 and 1, and -1
+	// admits every image.
+	DefaultMinScore float64
+	// StoreQueryImages keeps images uploaded for image-to-image search in storage so the
+	// search record can point back at what was searched with.
+	StoreQueryImages bool
+	// BatchSearchConcurrency bounds how many queries of a batch search run at once. Zero uses
+	// DefaultBatchSearchConcurrency.
+	BatchSearchConcurrency int
+	// SuggestMinFrequency is how many times a query must have been searched before it is
+	// suggested, by anyone, since searches are not tied to clients. Zero uses
+	// DefaultSuggestMinFrequency.
+	SuggestMinFrequency int
+	// Autotag is the label vocabulary new images are zero-shot tagged with. Nil disables
+	// auto-tagging.
+	Autotag *AutotagVocabulary
+	// ImportConcurrency bounds how many entries of an imported archive are embedded and
+	// uploaded at once. Zero uses DefaultImportConcurrency.
+	ImportConcurrency int
+	// RetainReplacedFiles keeps the old file of an image whose content is replaced in storage
+	// instead of deleting it.
+	RetainReplacedFiles bool
+	// URLFetcher downloads images ingested by URL. Nil uses a fetcher with default limits and no
+	// allowlist.
+	URLFetcher *URLFetcher
+	// Jobs runs asynchronous ingestion. Nil disables it.
+	Jobs JobQueue
 }
 
 type imageService struct {
 	logger          log.Logger
+	config          Config
 	clipService     clip.Service
 	storageService  storageservice.Service
 	imageRepository imagerepository.Repository
+	autotagger      *autotagger
+	promptCache     *promptCache
+	urlFetcher      *URLFetcher
 }
 
-func New(logger log.Logger, clipService clip.Service, storageService storageservice.Service, imageRepository imagerepository.Repository) Service {
-	return &imageService{
+func New(logger log.Logger, config Config, clipService clip.Service, storageService storageservice.Service, imageRepository imagerepository.Repository) Service {
+	urlFetcher := config.URLFetcher
+	if urlFetcher == nil {
+		urlFetcher, _ = NewURLFetcher(logger, URLFetcherConfig{})
+	}
+
+	s := &imageService{
 		logger:          logger,
+		config:          config,
 		clipService:     clipService,
 		storageService:  storageService,
 		imageRepository: imageRepository,
+		autotagger:      newAutotagger(config.Autotag, clipService),
+		promptCache:     newPromptCache(classifyCacheSize),
+		urlFetcher:      urlFetcher,
+	}
+
+	if config.Jobs != nil {
+		config.Jobs.Handle(models.JobTypeCreateImage, s.runCreateImageJob)
+	}
+
+	return s
+}
+
+func (s *imageService) CreateImage(ctx context.Context, stream *models.StorageFileStream, metadata models.ImageMetadata) (*models.Image, error) {
+	return s.createImage(ctx, stream, metadata, nil)
+}
+
+// CreateImageFromURL fetches an image with Config.URLFetcher and creates it as if it had been
+// uploaded, recording where it came from.
+func (s *imageService) CreateImageFromURL(ctx context.Context, url string, metadata models.ImageMetadata) (*models.Image, error) {
+	stream, err := s.urlFetcher.Fetch(ctx, url)
+	if err != nil {
+		return nil, err
 	}
+
+	return s.createImage(ctx, stream, metadata, &url)
 }
 
-func (s *imageService) CreateImage(ctx context.Context, stream *models.StorageFileStream) (*models.Image, error) {
+func (s *imageService) createImage(ctx context.Context, stream *models.StorageFileStream, metadata models.ImageMetadata, sourceURL *string) (*models.Image, error) {
 	imageBytes, err := io.ReadAll(stream.Reader)
 	if err != nil {
 		return nil, err
 	}
 
+	// Content that is already indexed is neither embedded nor stored again.
+	hash := contentHash(imageBytes)
+	if duplicate, err := s.findDuplicate(ctx, hash); err != nil || duplicate != nil {
+		return duplicate, err
+	}
+
+	img, embedding, err := s.newImage(ctx, stream, imageBytes, metadata)
+	if err != nil {
+		return nil, err
+	}
+	img.SourceURL = sourceURL
+	img.ContentHash = &hash
+
+	return s.indexImage(ctx, img, embedding)
+}
+
+// indexImage inserts a stored and embedded image. An image whose content was indexed since it was
+// checked for gives way to the one already indexed.
+func (s *imageService) indexImage(ctx context.Context, img *models.Image, embedding *models.Embedding) (*models.Image, error) {
+	image, err := s.imageRepository.CreateImage(ctx, img, &imagemodel.ImageEmbedding{
+		ModelName: embedding.Model,
+		Embedding: embedding.Embedding,
+	})
+	if err != nil {
+		return s.duplicateOf(ctx, img, err)
+	}
+
+	return image, nil
+}
+
+// newImage embeds, stores and auto-tags an uploaded image, ready to be indexed.
+func (s *imageService) newImage(ctx context.Context, stream *models.StorageFileStream, imageBytes []byte, metadata models.ImageMetadata) (*models.Image, *models.Embedding, error) {
+	embedding, storageFile, err := s.embedAndUpload(ctx, stream, imageBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	img, err := s.describeImage(ctx, stream, imageBytes, metadata, storageFile, embedding)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return img, embedding, nil
+}
+
+// describeImage builds and auto-tags the image of a file that is already embedded and stored.
+func (s *imageService) describeImage(ctx context.Context, stream *models.StorageFileStream, imageBytes []byte, metadata models.ImageMetadata, storageFile *models.StorageFile, embedding *models.Embedding) (*models.Image, error) {
+	url, err := s.storageService.FormatURL(ctx, storageFile)
+	if err != nil {
+		return nil, err
+	}
+
+	img := &models.Image{
+		StorageProvider:  storageFile.Provider,
+		StorageKey:       storageFile.Key,
+		OriginalFilename: stream.Filename,
+		Title:            metadata.Title,
+		Description:      metadata.Description,
+		CreatedAt:        time.Now(),
+		URL:              url,
+	}
+	setImageAttributes(img, stream, imageBytes)
+
+	if s.autotagger != nil {
+		// Auto-tagging is best effort; an image is still worth indexing without its tags.
+		if img.Tags, err = s.autotagger.tags(ctx, embedding.Model, embedding.Embedding); err != nil {
+			s.logger.Log("autotag", "error", err)
+		}
+	}
+
+	return img, nil
+}
+
+// embedAndUpload embeds an uploaded image and stores it, side by side.
+func (s *imageService) embedAndUpload(ctx context.Context, stream *models.StorageFileStream, imageBytes []byte) (*models.Embedding, *models.StorageFile, error) {
 	var embedding *models.Embedding
 	var storageFile *models.StorageFile
 
@@ -74,28 +261,68 @@ func (s *imageService) CreateImage(ctx context.Context, stream *models.StorageFi
 	})
 
 	if err := errGroup.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	return embedding, storageFile, nil
+}
+
+// deleteFile removes the file of an image that could not be indexed. It is best effort; the
+// file is only left over.
+func (s *imageService) deleteFile(ctx context.Context, img *models.Image) {
+	if err := s.storageService.Delete(context.WithoutCancel(ctx), &models.StorageFile{
+		Provider: img.StorageProvider,
+		Key:      img.StorageKey,
+	}); err != nil {
+		s.logger.Log("storage_key", img.StorageKey, "error", err)
+	}
+}
+
+// ReplaceImageContent swaps the file of an image for a new one and re-embeds and re-tags it,
+// keeping its ID, title, description and hand-added tags. The replaced file is deleted unless
+// Config.RetainReplacedFiles is set. Content that another image already has is refused.
+func (s *imageService) ReplaceImageContent(ctx context.Context, id uuid.UUID, stream *models.StorageFileStream) (*models.Image, error) {
+	if _, err := s.imageRepository.GetImage(ctx, id); err != nil {
 		return nil, err
 	}
 
-	url, err := s.storageService.FormatURL(ctx, storageFile)
+	imageBytes, err := io.ReadAll(stream.Reader)
 	if err != nil {
 		return nil, err
 	}
 
-	image, err := s.imageRepository.CreateImage(ctx, &models.Image{
-		StorageProvider: storageFile.Provider,
-		StorageKey:      storageFile.Key,
-		CreatedAt:       time.Now(),
-		URL:             url,
-	}, &imagemodel.ImageEmbedding{
+	hash := contentHash(imageBytes)
+	if duplicate, err := s.imageRepository.GetImageByContentHash(ctx, hash); err != nil {
+		return nil, err
+	} else if duplicate != nil && duplicate.ID != id {
+		return nil, errortypes.NewErrImageContentExists(hash)
+	}
+
+	img, embedding, err := s.newImage(ctx, stream, imageBytes, models.ImageMetadata{})
+	if err != nil {
+		return nil, err
+	}
+	img.ID = id
+	img.ContentHash = &hash
+
+	previous, err := s.imageRepository.ReplaceImageContent(ctx, img, &imagemodel.ImageEmbedding{
 		ModelName: embedding.Model,
 		Embedding: embedding.Embedding,
 	})
 	if err != nil {
+		// The image still points at its old file, so the new one is not needed.
+		s.deleteFile(ctx, img)
 		return nil, err
 	}
 
-	return image, nil
+	if !s.config.RetainReplacedFiles {
+		// The image already points at the new file, so a failed delete must not fail the replacement.
+		if err := s.storageService.Delete(ctx, previous); err != nil {
+			s.logger.Log("image", id, "storage_key", previous.Key, "error", err)
+		}
+	}
+
+	return img, nil
 }
 
 func (s *imageService) GetImage(ctx context.Context, id uuid.UUID) (*models.Image, error) {
@@ -114,40 +341,735 @@ func (s *imageService) GetImage(ctx context.Context, id uuid.UUID) (*models.Imag
 	return img, nil
 }
 
-func (s *imageService) SearchImage(ctx context.Context, query string) (*models.SearchWithImage, error) {
+func (s *imageService) DeleteImage(ctx context.Context, id uuid.UUID) error {
+	deletions, err := s.DeleteImages(ctx, []uuid.UUID{id})
+	if err != nil {
+		return err
+	}
+
+	return deletions[0].Err
+}
+
+// DeleteImages deletes images and then their files. The rows go first, in one transaction, so a
+// failure can at worst leave a file without an image rather than an image without a file. Such
+// a failure is reported on the image as ErrImageFileNotDeleted.
+func (s *imageService) DeleteImages(ctx context.Context, ids []uuid.UUID) ([]models.ImageDeletion, error) {
+	if len(ids) == 0 || len(ids) > MaxDeleteImages {
+		return nil, errortypes.NewErrInvalidImageParameter("ids", fmt.Sprintf("must have between 1 and %d ids", MaxDeleteImages))
+	}
+
+	images, err := s.imageRepository.DeleteImages(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	fileErrs := make([]error, len(images))
+
+	errGroup := errgroup.Group{}
+	errGroup.SetLimit(fileDeleteConcurrency)
+
+	for i, image := range images {
+		errGroup.Go(func() error {
+			if err := s.storageService.Delete(ctx, &models.StorageFile{
+				Provider: image.StorageProvider,
+				Key:      image.StorageKey,
+			}); err != nil {
+				s.logger.Log("image", image.ID, "storage_key", image.StorageKey, "error", err)
+				fileErrs[i] = errortypes.NewErrImageFileNotDeleted(image.ID, image.StorageProvider, image.StorageKey)
+			}
+			return nil
+		})
+	}
+
+	errGroup.Wait()
+
+	deleted := make(map[uuid.UUID]error, len(images))
+	for i, image := range images {
+		deleted[image.ID] = fileErrs[i]
+	}
+
+	deletions := make([]models.ImageDeletion, len(ids))
+	for i, id := range ids {
+		deletions[i] = models.ImageDeletion{Index: i, ID: id}
+		if err, ok := deleted[id]; !ok {
+			deletions[i].Err = errortypes.NewErrImageNotFound(id)
+		} else {
+			deletions[i].Err = err
+		}
+	}
+
+	return deletions, nil
+}
+
+func (s *imageService) SearchImage(ctx context.Context, query string, options models.SearchOptions) (*models.SearchWithResults, error) {
+	if err := validateSearchOptions(&options); err != nil {
+		return nil, err
+	}
+
+	if options.Cursor != "" {
+		return s.searchNextPage(ctx, options)
+	}
+
+	maxDistance, err := s.maxDistance(options)
+	if err != nil {
+		return nil, err
+	}
+
 	embedding, err := s.clipService.TextEmbedding(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 
-	searchWithImage, err := s.imageRepository.CreateSearchQuery(ctx, &imagemodel.SearchQuery{
+	return s.search(ctx, &imagemodel.SearchQuery{
 		Search: models.Search{
-			ModelName: embedding.Model,
-			QueryText: query,
+			ModelName:   embedding.Model,
+			QueryType:   models.QueryTypeText,
+			QueryText:   query,
+			MaxDistance: maxDistance,
 		},
 		Embedding: embedding.Embedding,
+	}, options)
+}
+
+// SearchBatch runs text searches concurrently on a bounded pool of workers. A failing query
+// does not fail the batch; its error is reported in its result instead.
+func (s *imageService) SearchBatch(ctx context.Context, queries []models.BatchSearchQuery) ([]models.BatchSearchResult, error) {
+	if len(queries) == 0 || len(queries) > MaxBatchSearchQueries {
+		return nil, errortypes.NewErrInvalidSearchParameter("queries", fmt.Sprintf("must have between 1 and %d queries", MaxBatchSearchQueries))
+	}
+
+	concurrency := s.config.BatchSearchConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchSearchConcurrency
+	}
+
+	results := make([]models.BatchSearchResult, len(queries))
+
+	errGroup := errgroup.Group{}
+	errGroup.SetLimit(concurrency)
+
+	for i, query := range queries {
+		errGroup.Go(func() error {
+			search, err := s.SearchImage(ctx, query.Query, query.Options)
+			results[i] = models.BatchSearchResult{
+				Index:  i,
+				Search: search,
+				Err:    err,
+			}
+			return nil
+		})
+	}
+
+	errGroup.Wait()
+
+	return results, nil
+}
+
+func (s *imageService) SearchComposed(ctx context.Context, terms []models.QueryTerm, options models.SearchOptions) (_ *models.SearchWithResults, err error) {
+	if err := validateSearchOptions(&options); err != nil {
+		return nil, err
+	}
+
+	maxDistance, err := s.maxDistance(options)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(terms) == 0 || len(terms) > MaxQueryTerms {
+		return nil, errortypes.NewErrInvalidSearchParameter("terms", fmt.Sprintf("must contain between 1 and %d terms", MaxQueryTerms))
+	}
+
+	for i := range terms {
+		sources := 0
+		if terms[i].Text != "" {
+			terms[i].Type = models.QueryTypeText
+			sources++
+		}
+		if terms[i].ImageID != nil {
+			terms[i].Type = models.QueryTypeSimilar
+			sources++
+		}
+		if terms[i].Upload != nil {
+			terms[i].Type = models.QueryTypeImage
+			sources++
+		}
+		if sources != 1 {
+			return nil, errortypes.NewErrInvalidSearchParameter("terms", fmt.Sprintf("term %d must set exactly one of text, image_id or file", i))
+		}
+		if terms[i].Weight == 0 {
+			return nil, errortypes.NewErrInvalidSearchParameter("terms", fmt.Sprintf("term %d must have a non-zero weight", i))
+		}
+	}
+
+	// Query images are only kept for searches that are recorded. A search without relevant
+	// images is recorded too.
+	defer func() {
+		var noRelevant *errortypes.ErrNoRelevantImage
+		if err != nil && !errors.As(err, &noRelevant) {
+			for _, term := range terms {
+				s.deleteQueryImage(ctx, term.Image)
+			}
+		}
+	}()
+
+	embeddings := make([][]float32, len(terms))
+	weights := make([]float64, len(terms))
+	modelNames := make([]string, len(terms))
+
+	errGroup, errCtx := errgroup.WithContext(ctx)
+
+	for i := range terms {
+		weights[i] = terms[i].Weight
+		errGroup.Go(func() error {
+			e, err := s.embedQueryTerm(errCtx, &terms[i])
+			if err != nil {
+				return err
+			}
+			embeddings[i] = e.Embedding
+			modelNames[i] = e.Model
+			return nil
+		})
+	}
+
+	if err := errGroup.Wait(); err != nil {
+		return nil, err
+	}
+
+	for _, modelName := range modelNames {
+		if modelName != modelNames[0] {
+			return nil, errortypes.NewErrInvalidSearchParameter("terms", "terms were embedded by different models")
+		}
+	}
+
+	embedding := combineEmbeddings(embeddings, weights)
+	if embedding == nil {
+		return nil, errortypes.NewErrInvalidSearchParameter("terms", "weighted terms cancel each other out")
+	}
+
+	search := models.Search{
+		ModelName:   modelNames[0],
+		QueryType:   models.QueryTypeComposed,
+		QueryTerms:  terms,
+		MaxDistance: maxDistance,
+	}
+
+	// A single positive term is recorded the same way as the dedicated search it stands for.
+	if term := terms[0]; len(terms) == 1 && term.Weight > 0 {
+		search.QueryType = term.Type
+		search.QueryTerms = nil
+		search.QueryText = term.Text
+		search.QueryImage = term.Image
+		search.QueryImageID = term.ImageID
+	}
+
+	return s.search(ctx, &imagemodel.SearchQuery{
+		Search:    search,
+		Embedding: embedding,
+	}, options)
+}
+
+func (s *imageService) embedQueryTerm(ctx context.Context, term *models.QueryTerm) (*models.Embedding, error) {
+	switch term.Type {
+	case models.QueryTypeText:
+		return s.clipService.TextEmbedding(ctx, term.Text)
+	case models.QueryTypeSimilar:
+		e, err := s.imageRepository.GetImageEmbedding(ctx, *term.ImageID, s.config.ModelName)
+		if err != nil {
+			return nil, err
+		}
+		return &models.Embedding{
+			Model:     e.ModelName,
+			Embedding: e.Embedding,
+		}, nil
+	default:
+		embedding, queryImage, err := s.embedQueryImage(ctx, term.Upload)
+		if err != nil {
+			return nil, err
+		}
+		term.Image = queryImage
+		return embedding, nil
+	}
+}
+
+// embedQueryImage embeds an uploaded query image and, when configured, keeps it in storage.
+func (s *imageService) embedQueryImage(ctx context.Context, stream *models.StorageFileStream) (*models.Embedding, *models.QueryImage, error) {
+	imageBytes, err := io.ReadAll(stream.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var embedding *models.Embedding
+	var queryImage *models.QueryImage
+
+	errGroup, errCtx := errgroup.WithContext(ctx)
+
+	errGroup.Go(func() error {
+		e, err := s.clipService.ImageEmbedding(errCtx, bytes.NewReader(imageBytes))
+		if err != nil {
+			return err
+		}
+		embedding = e
+		return nil
 	})
+
+	if s.config.StoreQueryImages {
+		errGroup.Go(func() error {
+			f, err := s.storageService.Upload(errCtx, &models.StorageFileStream{
+				Reader:        bytes.NewReader(imageBytes),
+				Filename:      stream.Filename,
+				ContentType:   stream.ContentType,
+				ContentLength: stream.ContentLength,
+			})
+			if err != nil {
+				return err
+			}
+			queryImage = &models.QueryImage{
+				StorageProvider: f.Provider,
+				StorageKey:      f.Key,
+			}
+			return nil
+		})
+	}
+
+	if err := errGroup.Wait(); err != nil {
+		s.deleteQueryImage(ctx, queryImage)
+		return nil, nil, err
+	}
+
+	return embedding, queryImage, nil
+}
+
+// deleteQueryImage removes a stored query image of a search that failed. It is best effort.
+func (s *imageService) deleteQueryImage(ctx context.Context, queryImage *models.QueryImage) {
+	if queryImage == nil {
+		return
+	}
+	s.deleteFile(ctx, &models.Image{StorageProvider: queryImage.StorageProvider, StorageKey: queryImage.StorageKey})
+}
+
+func (s *imageService) SearchSimilarImages(ctx context.Context, id uuid.UUID, options models.SearchOptions) (*models.SearchWithResults, error) {
+	if err := validateSearchOptions(&options); err != nil {
+		return nil, err
+	}
+
+	maxDistance, err := s.maxDistance(options)
 	if err != nil {
 		return nil, err
 	}
 
-	if searchWithImage.Image.URL, err = s.storageService.FormatURL(ctx, &models.StorageFile{
-		Provider: searchWithImage.Image.StorageProvider,
-		Key:      searchWithImage.Image.StorageKey,
-	}); err != nil {
+	embedding, err := s.imageRepository.GetImageEmbedding(ctx, id, s.config.ModelName)
+	if err != nil {
 		return nil, err
 	}
 
-	return searchWithImage, nil
+	return s.search(ctx, &imagemodel.SearchQuery{
+		Search: models.Search{
+			ModelName:    embedding.ModelName,
+			QueryType:    models.QueryTypeSimilar,
+			QueryImageID: &id,
+			MaxDistance:  maxDistance,
+		},
+		Embedding: embedding.Embedding,
+	}, options)
 }
 
-func (s *imageService) SearchFeedback(ctx context.Context, query_id uuid.UUID, rating models.Rating) (*models.SearchFeedbackWithQuery, error) {
+// search runs the first page of a new search and records it together with the results shown.
+func (s *imageService) search(ctx context.Context, searchQuery *imagemodel.SearchQuery, options models.SearchOptions) (*models.SearchWithResults, error) {
+	if options.Diversity != nil && *options.Diversity > 0 {
+		searchQuery.Rerank = &models.Rerank{
+			Strategy:   models.RerankStrategyMMR,
+			Diversity:  *options.Diversity,
+			Candidates: min(options.Limit*RerankCandidateFactor, MaxRerankCandidates),
+		}
+	}
+
+	if !options.Filters.IsEmpty() {
+		searchQuery.Filters = &options.Filters
+	}
+
+	if options.Mode == models.SearchModeHybrid {
+		textQuery := lexicalQuery(&searchQuery.Search)
+		if textQuery == "" {
+			return nil, errortypes.NewErrInvalidSearchParameter("mode", "hybrid search needs a text query")
+		}
+
+		searchQuery.Hybrid = &models.Hybrid{
+			TextQuery:    textQuery,
+			TextWeight:   1,
+			VectorWeight: 1,
+			K:            HybridRRFK,
+			Candidates:   min(options.Limit*RerankCandidateFactor, MaxRerankCandidates),
+		}
+		if options.TextWeight != nil {
+			searchQuery.Hybrid.TextWeight = *options.TextWeight
+		}
+		if options.VectorWeight != nil {
+			searchQuery.Hybrid.VectorWeight = *options.VectorWeight
+		}
+	}
+
+	results, err := s.rankPage(ctx, searchQuery, nil, options.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// URLs are formatted before the search is recorded so that its search.created event
+	// carries them.
+	if err := s.formatSearchURLs(ctx, &models.SearchWithResults{Search: searchQuery.Search, Results: results}); err != nil {
+		return nil, err
+	}
+
+	searchWithResults, err := s.imageRepository.CreateSearchQuery(ctx, searchQuery, results)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(searchWithResults.Results) == 0 {
+		searchWithResults.Results = []models.SearchResult{}
+		return nil, errortypes.NewErrNoRelevantImage(searchWithResults)
+	}
+
+	searchWithResults.NextCursor = nextCursor(searchQuery.ID, results, options.Limit)
+
+	return searchWithResults, nil
+}
+
+func (s *imageService) searchNextPage(ctx context.Context, options models.SearchOptions) (*models.SearchWithResults, error) {
+	cursor, err := imagemodel.DecodeSearchCursor(options.Cursor)
+	if err != nil {
+		return nil, errortypes.NewErrInvalidSearchParameter("cursor", err.Error())
+	}
+
+	searchQuery, err := s.imageRepository.GetSearchQueryEmbedding(ctx, cursor.SearchID)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.rankPage(ctx, searchQuery, cursor, options.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.imageRepository.AppendSearchResults(ctx, searchQuery.ID, results); err != nil {
+		return nil, err
+	}
+
+	searchWithResults := &models.SearchWithResults{
+		Search:     searchQuery.Search,
+		Results:    results,
+		NextCursor: nextCursor(searchQuery.ID, results, options.Limit),
+	}
+
+	if err := s.formatSearchURLs(ctx, searchWithResults); err != nil {
+		return nil, err
+	}
+
+	return searchWithResults, nil
+}
+
+// lexicalQuery returns the text matched against image filenames and text in hybrid mode: the
+// query text, or the positive text terms of a composed query.
+func lexicalQuery(search *models.Search) string {
+	if search.QueryText != "" {
+		return search.QueryText
+	}
+
+	var texts []string
+	for _, term := range search.QueryTerms {
+		if term.Type == models.QueryTypeText && term.Weight > 0 {
+			texts = append(texts, term.Text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// rankPage fetches the page of results following after, or the first page when after is nil.
+// Searches recorded with a re-ranking strategy over-fetch candidates and re-rank them, taking
+// the results shown on earlier pages into account. Hybrid searches are fused in the repository
+// and page by excluding the results already shown.
+func (s *imageService) rankPage(ctx context.Context, searchQuery *imagemodel.SearchQuery, after *imagemodel.SearchCursor, limit int) ([]models.SearchResult, error) {
+	page := imagemodel.SearchPage{
+		After: after,
+		Limit: limit,
+	}
+
+	offset := 0
+	if after != nil {
+		offset = after.Rank
+		if searchQuery.Hybrid == nil {
+			page.MinDistance = after.Distance
+		}
+	}
+
+	if searchQuery.Rerank == nil {
+		candidates, err := s.imageRepository.FindNearestImages(ctx, searchQuery, page)
+		if err != nil {
+			return nil, err
+		}
+		return rankResults(candidates, offset), nil
+	}
+
+	page.MinDistance = 0
+	page.Limit = max(searchQuery.Rerank.Candidates, limit)
+	page.WithEmbeddings = true
+
+	candidates, err := s.imageRepository.FindNearestImages(ctx, searchQuery, page)
+	if err != nil {
+		return nil, err
+	}
+
+	var shown [][]float32
+	if after != nil {
+		if shown, err = s.imageRepository.ListSearchResultEmbeddings(ctx, searchQuery.ID, after.Rank); err != nil {
+			return nil, err
+		}
+	}
+
+	return rankResults(mmr(candidates, shown, searchQuery.Rerank.Diversity, limit), offset), nil
+}
+
+func validateSearchOptions(options *models.SearchOptions) error {
+	if options.Limit == 0 {
+		options.Limit = DefaultSearchLimit
+	}
+	if options.Limit < 0 || options.Limit > MaxSearchLimit {
+		return errortypes.NewErrInvalidSearchParameter("limit", fmt.Sprintf("must be between 1 and %d", MaxSearchLimit))
+	}
+	if options.Diversity != nil && (*options.Diversity < 0 || *options.Diversity > 1) {
+		return errortypes.NewErrInvalidSearchParameter("diversity", "must be between 0 and 1")
+	}
+
+	switch options.Mode {
+	case "", models.SearchModeVector:
+		if options.TextWeight != nil || options.VectorWeight != nil {
+			return errortypes.NewErrInvalidSearchParameter("mode", "text_weight and vector_weight require hybrid mode")
+		}
+	case models.SearchModeHybrid:
+		if options.Diversity != nil && *options.Diversity > 0 {
+			return errortypes.NewErrInvalidSearchParameter("diversity", "cannot be combined with hybrid mode")
+		}
+		if options.TextWeight != nil && *options.TextWeight < 0 {
+			return errortypes.NewErrInvalidSearchParameter("text_weight", "must not be negative")
+		}
+		if options.VectorWeight != nil && *options.VectorWeight < 0 {
+			return errortypes.NewErrInvalidSearchParameter("vector_weight", "must not be negative")
+		}
+		if options.TextWeight != nil && options.VectorWeight != nil && *options.TextWeight == 0 && *options.VectorWeight == 0 {
+			return errortypes.NewErrInvalidSearchParameter("text_weight", "text_weight and vector_weight cannot both be zero")
+		}
+	default:
+		return errortypes.NewErrInvalidSearchParameter("mode", fmt.Sprintf("must be %s or %s", models.SearchModeVector, models.SearchModeHybrid))
+	}
+
+	return validateSearchFilters(&options.Filters)
+}
+
+func validateSearchFilters(f *models.SearchFilters) error {
+	if f.CreatedAfter != nil && f.CreatedBefore != nil && !f.CreatedAfter.Before(*f.CreatedBefore) {
+		return errortypes.NewErrInvalidSearchParameter("created_before", "must be after created_after")
+	}
+	if len(f.FilenamePattern) > MaxFilenamePatternLength {
+		return errortypes.NewErrInvalidSearchParameter("filename", fmt.Sprintf("must be at most %d characters", MaxFilenamePatternLength))
+	}
+	if f.MinFileSize != nil && f.MaxFileSize != nil && *f.MinFileSize > *f.MaxFileSize {
+		return errortypes.NewErrInvalidSearchParameter("min_file_size", "must not exceed max_file_size")
+	}
+	if f.MinWidth != nil && f.MaxWidth != nil && *f.MinWidth > *f.MaxWidth {
+		return errortypes.NewErrInvalidSearchParameter("min_width", "must not exceed max_width")
+	}
+	if f.MinHeight != nil && f.MaxHeight != nil && *f.MinHeight > *f.MaxHeight {
+		return errortypes.NewErrInvalidSearchParameter("min_height", "must not exceed max_height")
+	}
+	// Tag names are trimmed when written, and required tags are matched by count, so each
+	// must appear once.
+	var err error
+	if f.Tags, err = normalizeTagNames("tag", f.Tags); err != nil {
+		return err
+	}
+	if f.ExcludeTags, err = normalizeTagNames("exclude_tag", f.ExcludeTags); err != nil {
+		return err
+	}
+	for _, tag := range f.Tags {
+		if slices.Contains(f.ExcludeTags, tag) {
+			return errortypes.NewErrInvalidSearchParameter("tag", fmt.Sprintf("%q is both required and excluded", tag))
+		}
+	}
+
+	return nil
+}
+
+// normalizeTagNames trims, sorts and deduplicates the tag names of a filter.
+func normalizeTagNames(parameter string, names []string) ([]string, error) {
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if names[i] == "" {
+			return nil, errortypes.NewErrInvalidSearchParameter(parameter, "must not be empty")
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
+
+// maxDistance resolves the similarity threshold of a new search. Pages fetched by cursor reuse
+// the threshold stored with the search instead.
+func (s *imageService) maxDistance(options models.SearchOptions) (float64, error) {
+	switch {
+	case options.MinScore != nil && options.MaxDistance != nil:
+		return 0, errortypes.NewErrInvalidSearchParameter("min_score", "cannot be combined with max_distance")
+	case options.MinScore != nil:
+		// Written so that NaN, which compares false against both bounds, is refused too.
+		if !(*options.MinScore >= -1 && *options.MinScore <= 1) {
+			return 0, errortypes.NewErrInvalidSearchParameter("min_score", "must be between -1 and 1")
+		}
+		return 1 - *options.MinScore, nil
+	case options.MaxDistance != nil:
+		if !(*options.MaxDistance >= 0 && *options.MaxDistance <= 2) {
+			return 0, errortypes.NewErrInvalidSearchParameter("max_distance", "must be between 0 and 2")
+		}
+		return *options.MaxDistance, nil
+	default:
+		return 1 - s.config.DefaultMinScore, nil
+	}
+}
+
+func rankResults(candidates []imagemodel.SearchCandidate, offset int) []models.SearchResult {
+	results := make([]models.SearchResult, len(candidates))
+	for i, candidate := range candidates {
+		results[i] = candidate.SearchResult
+		results[i].Rank = offset + i + 1
+	}
+	return results
+}
+
+// nextCursor returns an empty cursor once a page comes back short, since there is nothing left to fetch.
+func nextCursor(searchID uuid.UUID, results []models.SearchResult, limit int) string {
+	if len(results) == 0 || len(results) < limit {
+		return ""
+	}
+
+	last := results[len(results)-1]
+
+	return imagemodel.SearchCursor{
+		SearchID: searchID,
+		Rank:     last.Rank,
+		Distance: last.Distance,
+	}.Encode()
+}
+
+func (s *imageService) formatSearchURLs(ctx context.Context, search *models.SearchWithResults) error {
+	if search.QueryImage != nil {
+		url, err := s.storageService.FormatURL(ctx, &models.StorageFile{
+			Provider: search.QueryImage.StorageProvider,
+			Key:      search.QueryImage.StorageKey,
+		})
+		if err != nil {
+			return err
+		}
+		search.QueryImage.URL = url
+	}
+
+	for _, term := range search.QueryTerms {
+		if term.Image == nil {
+			continue
+		}
+		url, err := s.storageService.FormatURL(ctx, &models.StorageFile{
+			Provider: term.Image.StorageProvider,
+			Key:      term.Image.StorageKey,
+		})
+		if err != nil {
+			return err
+		}
+		term.Image.URL = url
+	}
+
+	for i := range search.Results {
+		url, err := s.storageService.FormatURL(ctx, &models.StorageFile{
+			Provider: search.Results[i].Image.StorageProvider,
+			Key:      search.Results[i].Image.StorageKey,
+		})
+		if err != nil {
+			return err
+		}
+		search.Results[i].Image.URL = url
+	}
+
+	return nil
+}
+
+func (s *imageService) SearchFeedback(ctx context.Context, query_id uuid.UUID, imageID *uuid.UUID, rating models.Rating) (*models.SearchFeedbackWithQuery, error) {
 	return s.imageRepository.CreateSearchFeedback(ctx, &models.SearchFeedbackWithQuery{
 		SearchFeedback: models.SearchFeedback{
-			Rating: rating,
+			ImageID: imageID,
+			Rating:  rating,
 		},
 		Query: models.Search{
 			ID: query_id,
 		},
 	})
 }
+
+// RefineSearch applies Rocchio relevance feedback to a search and runs the refined query as a
+// new search linked to it. Thresholds, filters, re-ranking and hybrid mode are inherited unless
+// options override them.
+func (s *imageService) RefineSearch(ctx context.Context, id uuid.UUID, options models.SearchOptions) (*models.SearchWithResults, error) {
+	if err := validateSearchOptions(&options); err != nil {
+		return nil, err
+	}
+
+	parent, err := s.imageRepository.GetSearchQueryEmbedding(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rated, err := s.imageRepository.ListRatedEmbeddings(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rated) == 0 {
+		return nil, errortypes.NewErrSearchFeedbackMissing(id)
+	}
+
+	embedding := rocchio(parent.Embedding, rated)
+	if embedding == nil {
+		return nil, errortypes.NewErrSearchFeedbackCancelsQuery(id)
+	}
+
+	search := parent.Search
+	search.ID = uuid.Nil
+	search.QueryType = models.QueryTypeRefined
+	search.ParentID = &parent.ID
+	search.Rerank = nil
+	search.Hybrid = nil
+	search.CreatedAt = time.Time{}
+
+	if options.MinScore != nil || options.MaxDistance != nil {
+		if search.MaxDistance, err = s.maxDistance(options); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Filters.IsEmpty() && parent.Filters != nil {
+		options.Filters = *parent.Filters
+	}
+	search.Filters = nil
+
+	if options.Diversity == nil && parent.Rerank != nil && options.Mode != models.SearchModeHybrid {
+		options.Diversity = &parent.Rerank.Diversity
+	}
+
+	if options.Mode == "" && parent.Hybrid != nil {
+		options.Mode = models.SearchModeHybrid
+		if options.TextWeight == nil {
+			options.TextWeight = &parent.Hybrid.TextWeight
+		}
+		if options.VectorWeight == nil {
+			options.VectorWeight = &parent.Hybrid.VectorWeight
+		}
+	}
+
+	return s.search(ctx, &imagemodel.SearchQuery{
+		Search:    search,
+		Embedding: embedding,
+	}, options)
+}
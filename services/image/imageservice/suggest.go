@@ -0,0 +1,63 @@
+package imageservice
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+const (
+	DefaultSuggestLimit  = 10
+	MaxSuggestLimit      = 50
+	MaxSuggestTextLength = 255
+
+	// DefaultSuggestMinFrequency keeps queries searched only once or twice from being suggested.
+	// Searches are not tied to clients, so one client repeating a query reaches the floor on its
+	// own; the floor filters noise and does not keep personal queries private.
+	DefaultSuggestMinFrequency = 3
+
+	// SuggestCandidateFactor is how many nearest past searches per requested suggestion are
+	// grouped into similar queries.
+	SuggestCandidateFactor = 20
+)
+
+// SuggestQueries suggests past text queries, either completing prefix or, given similarTo,
+// semantically similar to it. Queries searched fewer than the configured minimum number of
+// times are never suggested.
+func (s *imageService) SuggestQueries(ctx context.Context, prefix string, similarTo string, limit int) ([]models.QuerySuggestion, error) {
+	prefix = strings.TrimLeft(prefix, " ")
+	similarTo = strings.TrimSpace(similarTo)
+
+	if (prefix == "") == (similarTo == "") {
+		return nil, errortypes.NewErrInvalidSearchParameter("prefix", "exactly one of prefix and similar_to is required")
+	}
+	if len(prefix) > MaxSuggestTextLength || len(similarTo) > MaxSuggestTextLength {
+		return nil, errortypes.NewErrInvalidSearchParameter("prefix", fmt.Sprintf("must be at most %d characters", MaxSuggestTextLength))
+	}
+
+	if limit == 0 {
+		limit = DefaultSuggestLimit
+	}
+	if limit < 0 || limit > MaxSuggestLimit {
+		return nil, errortypes.NewErrInvalidSearchParameter("limit", fmt.Sprintf("must be between 1 and %d", MaxSuggestLimit))
+	}
+
+	minFrequency := s.config.SuggestMinFrequency
+	if minFrequency <= 0 {
+		minFrequency = DefaultSuggestMinFrequency
+	}
+
+	if prefix != "" {
+		return s.imageRepository.SuggestQueries(ctx, prefix, minFrequency, limit)
+	}
+
+	embedding, err := s.clipService.TextEmbedding(ctx, similarTo)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.imageRepository.FindSimilarQueries(ctx, embedding.Model, embedding.Embedding, similarTo, minFrequency, limit*SuggestCandidateFactor, limit)
+}
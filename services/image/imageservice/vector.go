@@ -0,0 +1,63 @@
+package imageservice
+
+import "math"
+
+// normalize scales v to unit length. It returns nil for a zero vector.
+func normalize(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	norm = math.Sqrt(norm)
+
+	if norm < 1e-9 {
+		return nil
+	}
+
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+// combineEmbeddings sums the unit-length vectors scaled by their weights and re-normalizes the
+// result, so that negative weights push the query away from a term. It returns nil when the
+// vectors have different dimensions or the weighted terms cancel each other out.
+func combineEmbeddings(vectors [][]float32, weights []float64) []float32 {
+	if len(vectors) == 0 {
+		return nil
+	}
+
+	sum := make([]float64, len(vectors[0]))
+	for i, v := range vectors {
+		if len(v) != len(sum) {
+			return nil
+		}
+
+		unit := normalize(v)
+		for j, x := range unit {
+			sum[j] += weights[i] * float64(x)
+		}
+	}
+
+	out := make([]float32, len(sum))
+	for i, x := range sum {
+		out[i] = float32(x)
+	}
+	return normalize(out)
+}
+
+// dot returns the dot product of a and b, which is their cosine similarity for unit vectors.
+// Missing vectors have no similarity to anything.
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
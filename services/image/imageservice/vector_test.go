@@ -0,0 +1,63 @@
+package imageservice
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCombineEmbeddings(t *testing.T) {
+	tests := []struct {
+		name    string
+		vectors [][]float32
+		weights []float64
+		want    []float32
+	}{
+		{
+			name:    "vectors count equally whatever their length",
+			vectors: [][]float32{{10, 0}, {0, 1}},
+			weights: []float64{1, 1},
+			want:    []float32{math.Sqrt2 / 2, math.Sqrt2 / 2},
+		},
+		{
+			name:    "terms that cancel out",
+			vectors: [][]float32{{1, 0}, {2, 0}},
+			weights: []float64{1, -1},
+			want:    nil,
+		},
+		{
+			name:    "zero vector",
+			vectors: [][]float32{{0, 0}},
+			weights: []float64{1},
+			want:    nil,
+		},
+		{
+			name:    "different dimensions",
+			vectors: [][]float32{{1, 0}, {1, 0, 0}},
+			weights: []float64{1, 1},
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := combineEmbeddings(tt.vectors, tt.weights)
+			if !approxEqual(got, tt.want) {
+				t.Errorf("combineEmbeddings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// approxEqual compares vectors element-wise with a tolerance for float32 rounding. Nil only
+// equals nil.
+func approxEqual(a, b []float32) bool {
+	if (a == nil) != (b == nil) || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(float64(a[i])-float64(b[i])) > 1e-6 {
+			return false
+		}
+	}
+	return true
+}
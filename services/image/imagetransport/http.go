@@ -3,8 +3,14 @@ package imagetransport
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"time"
 
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/go-kit/log"
@@ -23,12 +29,58 @@ func NewHTTPHandler(svc imageendpoint.Endpoints, logger log.Logger) http.Handler
 
 	m := http.NewServeMux()
 
-	m.Handle("POST /images", httptransport.NewServer(
+	createImageHandler := httptransport.NewServer(
 		svc.CreateImageEndpoint,
 		decodeCreateImageRequest,
 		encodeCreateImageResponse,
 		options...,
-	))
+	)
+
+	createImageFromURLHandler := httptransport.NewServer(
+		svc.CreateImageFromURLEndpoint,
+		decodeCreateImageFromURLRequest,
+		encodeCreateImageFromURLResponse,
+		options...,
+	)
+
+	createImageAsyncHandler := httptransport.NewServer(
+		svc.CreateImageAsyncEndpoint,
+		decodeCreateImageAsyncRequest,
+		encodeCreateImageAsyncResponse,
+		options...,
+	)
+
+	createImageFromURLAsyncHandler := httptransport.NewServer(
+		svc.CreateImageFromURLAsyncEndpoint,
+		decodeCreateImageFromURLAsyncRequest,
+		encodeCreateImageFromURLAsyncResponse,
+		options...,
+	)
+
+	// A JSON body names a URL to fetch the image from; anything else is a multipart upload.
+	// With async=true the image is indexed by a job and the job is returned instead.
+	m.Handle("POST /images", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		async := false
+		if v := r.URL.Query().Get("async"); v != "" {
+			var err error
+			if async, err = strconv.ParseBool(v); err != nil {
+				errortypes.ErrorEncoder(r.Context(), errortypes.NewErrInvalidImageParameter("async", "must be true or false"), w)
+				return
+			}
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch {
+		case mediaType == "application/json" && async:
+			createImageFromURLAsyncHandler.ServeHTTP(w, r)
+		case mediaType == "application/json":
+			createImageFromURLHandler.ServeHTTP(w, r)
+		case async:
+			createImageAsyncHandler.ServeHTTP(w, r)
+		default:
+			createImageHandler.ServeHTTP(w, r)
+		}
+	}))
 
 	m.Handle("GET /images/{id}", httptransport.NewServer(
 		svc.GetImageEndpoint,
@@ -37,6 +89,34 @@ func NewHTTPHandler(svc imageendpoint.Endpoints, logger log.Logger) http.Handler
 		options...,
 	))
 
+	m.Handle("POST /images/import", httptransport.NewServer(
+		svc.ImportImagesEndpoint,
+		decodeImportImagesRequest,
+		encodeImportImagesResponse,
+		options...,
+	))
+
+	m.Handle("PUT /images/{id}/content", httptransport.NewServer(
+		svc.ReplaceImageContentEndpoint,
+		decodeReplaceImageContentRequest,
+		encodeReplaceImageContentResponse,
+		options...,
+	))
+
+	m.Handle("DELETE /images/{id}", httptransport.NewServer(
+		svc.DeleteImageEndpoint,
+		decodeDeleteImageRequest,
+		encodeDeleteImageResponse,
+		options...,
+	))
+
+	m.Handle("POST /images/delete:batch", httptransport.NewServer(
+		svc.DeleteImagesEndpoint,
+		decodeDeleteImagesRequest,
+		encodeDeleteImagesResponse,
+		options...,
+	))
+
 	m.Handle("GET /images", httptransport.NewServer(
 		svc.SearchImageEndpoint,
 		decodeSearchImageRequest,
@@ -44,6 +124,27 @@ func NewHTTPHandler(svc imageendpoint.Endpoints, logger log.Logger) http.Handler
 		options...,
 	))
 
+	m.Handle("POST /images/search", httptransport.NewServer(
+		svc.SearchComposedEndpoint,
+		decodeSearchComposedRequest,
+		encodeSearchComposedResponse,
+		options...,
+	))
+
+	m.Handle("POST /images/search:batch", httptransport.NewServer(
+		svc.SearchBatchEndpoint,
+		decodeSearchBatchRequest,
+		encodeSearchBatchResponse,
+		options...,
+	))
+
+	m.Handle("GET /images/{id}/similar", httptransport.NewServer(
+		svc.SearchSimilarEndpoint,
+		decodeSearchSimilarRequest,
+		encodeSearchSimilarResponse,
+		options...,
+	))
+
 	m.Handle("POST /images/{id}/feedback", httptransport.NewServer(
 		svc.SearchFeedbackEndpoint,
 		decodeSearchFeedbackRequest,
@@ -51,6 +152,55 @@ func NewHTTPHandler(svc imageendpoint.Endpoints, logger log.Logger) http.Handler
 		options...,
 	))
 
+	m.Handle("POST /searches/{id}/refine", httptransport.NewServer(
+		svc.RefineSearchEndpoint,
+		decodeRefineSearchRequest,
+		encodeRefineSearchResponse,
+		options...,
+	))
+
+	m.Handle("GET /searches/suggest", httptransport.NewServer(
+		svc.SuggestQueriesEndpoint,
+		decodeSuggestQueriesRequest,
+		encodeSuggestQueriesResponse,
+		options...,
+	))
+
+	m.Handle("GET /searches/{id}", httptransport.NewServer(
+		svc.GetSearchEndpoint,
+		decodeGetSearchRequest,
+		encodeGetSearchResponse,
+		options...,
+	))
+
+	m.Handle("GET /searches", httptransport.NewServer(
+		svc.ListSearchesEndpoint,
+		decodeListSearchesRequest,
+		encodeListSearchesResponse,
+		options...,
+	))
+
+	m.Handle("POST /autotag/runs", httptransport.NewServer(
+		svc.StartAutotagRunEndpoint,
+		decodeStartAutotagRunRequest,
+		encodeStartAutotagRunResponse,
+		options...,
+	))
+
+	m.Handle("GET /autotag/runs/{id}", httptransport.NewServer(
+		svc.GetAutotagRunEndpoint,
+		decodeGetAutotagRunRequest,
+		encodeGetAutotagRunResponse,
+		options...,
+	))
+
+	m.Handle("POST /classify", httptransport.NewServer(
+		svc.ClassifyEndpoint,
+		decodeClassifyRequest,
+		encodeClassifyResponse,
+		options...,
+	))
+
 	return m
 }
 
@@ -73,6 +223,10 @@ func decodeCreateImageRequest(ctx context.Context, r *http.Request) (interface{}
 			ContentType:   header.Header.Get("Content-Type"),
 			ContentLength: header.Size,
 		},
+		Metadata: models.ImageMetadata{
+			Title:       r.FormValue("title"),
+			Description: r.FormValue("description"),
+		},
 		Closer: func() error {
 			return file.Close()
 		},
@@ -90,6 +244,91 @@ func encodeCreateImageResponse(ctx context.Context, w http.ResponseWriter, respo
 	return json.NewEncoder(w).Encode(resp.V)
 }
 
+type createImageFromURLRequest struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+func decodeCreateImageFromURLRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req createImageFromURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("failed to decode request body: %w", err)
+	}
+
+	return imageendpoint.CreateImageFromURLRequest{
+		URL: req.URL,
+		Metadata: models.ImageMetadata{
+			Title:       req.Title,
+			Description: req.Description,
+		},
+	}, nil
+}
+
+func encodeCreateImageFromURLResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.CreateImageFromURLResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeCreateImageAsyncRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	request, err := decodeCreateImageRequest(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	req := request.(imageendpoint.CreateImageRequest)
+	return imageendpoint.CreateImageAsyncRequest{
+		Image:    req.Image,
+		Metadata: req.Metadata,
+		Closer:   req.Closer,
+	}, nil
+}
+
+func encodeCreateImageAsyncResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.CreateImageAsyncResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeCreateImageFromURLAsyncRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	request, err := decodeCreateImageFromURLRequest(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	req := request.(imageendpoint.CreateImageFromURLRequest)
+	return imageendpoint.CreateImageFromURLAsyncRequest{
+		URL:      req.URL,
+		Metadata: req.Metadata,
+	}, nil
+}
+
+func encodeCreateImageFromURLAsyncResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.CreateImageFromURLAsyncResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
 func decodeGetImageRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
@@ -112,14 +351,369 @@ func encodeGetImageResponse(ctx context.Context, w http.ResponseWriter, response
 	return json.NewEncoder(w).Encode(resp.V)
 }
 
+func decodeImportImagesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	// Parts beyond maxMemory are spooled to a temporary file, which the archive is then read
+	// from in place. The archive is imported before the response is written, so it is kept
+	// small enough to be embedded within the timeouts of proxies and clients; larger datasets
+	// are imported in several archives.
+	const (
+		maxArchiveSize = 512 << 20
+		maxMemory      = 32 << 20
+	)
+
+	r.Body = http.MaxBytesReader(nil, r.Body, maxArchiveSize)
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		return nil, fmt.Errorf("failed to parse multipart form: %w", err)
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get form file: %w", err)
+	}
+
+	return imageendpoint.ImportImagesRequest{
+		Archive: file,
+		Size:    header.Size,
+		Closer: func() error {
+			return file.Close()
+		},
+	}, nil
+}
+
+type importImageResultResponse struct {
+	Index    int                      `json:"index"`
+	Filename string                   `json:"filename"`
+	Status   models.ImageImportStatus `json:"status"`
+	Image    *models.Image            `json:"image,omitempty"`
+	Reason   string                   `json:"reason,omitempty"`
+	Error    errortypes.ServiceError  `json:"error,omitempty"`
+}
+
+type importImagesResponse struct {
+	Created int                         `json:"created"`
+	Skipped int                         `json:"skipped"`
+	Failed  int                         `json:"failed"`
+	Files   []importImageResultResponse `json:"files"`
+}
+
+func encodeImportImagesResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.ImportImagesResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	body := importImagesResponse{Files: make([]importImageResultResponse, len(resp.V))}
+	for i, result := range resp.V {
+		body.Files[i] = importImageResultResponse{
+			Index:    result.Index,
+			Filename: result.Filename,
+			Status:   result.Status,
+			Image:    result.Image,
+			Reason:   result.Reason,
+		}
+		if result.Err != nil {
+			body.Files[i].Error = errortypes.AsServiceError(result.Err)
+		}
+
+		switch result.Status {
+		case models.ImageImportStatusCreated:
+			body.Created++
+		case models.ImageImportStatusSkipped:
+			body.Skipped++
+		case models.ImageImportStatusFailed:
+			body.Failed++
+		}
+	}
+
+	return json.NewEncoder(w).Encode(body)
+}
+
+func decodeReplaceImageContentRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	const maxFileSize = 10 << 20
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	if err := r.ParseMultipartForm(maxFileSize); err != nil {
+		return nil, fmt.Errorf("failed to parse multipart form: %w", err)
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get form file: %w", err)
+	}
+
+	return imageendpoint.ReplaceImageContentRequest{
+		ID: id,
+		Image: &models.StorageFileStream{
+			Reader:        file,
+			Filename:      header.Filename,
+			ContentType:   header.Header.Get("Content-Type"),
+			ContentLength: header.Size,
+		},
+		Closer: func() error {
+			return file.Close()
+		},
+	}, nil
+}
+
+func encodeReplaceImageContentResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.ReplaceImageContentResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeDeleteImageRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	return imageendpoint.DeleteImageRequest{
+		ID: id,
+	}, nil
+}
+
+func encodeDeleteImageResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.DeleteImageResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+type deleteImagesRequest struct {
+	IDs []uuid.UUID `json:"ids"`
+}
+
+// deleteImageResultResponse carries the HTTP status DELETE /images/{id} would have answered the
+// image with.
+type deleteImageResultResponse struct {
+	Index  int                     `json:"index"`
+	ID     uuid.UUID               `json:"id"`
+	Status int                     `json:"status"`
+	Error  errortypes.ServiceError `json:"error,omitempty"`
+}
+
+type deleteImagesResponse struct {
+	Results []deleteImageResultResponse `json:"results"`
+}
+
+func decodeDeleteImagesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req deleteImagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("failed to decode request body: %w", err)
+	}
+
+	return imageendpoint.DeleteImagesRequest{
+		IDs: req.IDs,
+	}, nil
+}
+
+func encodeDeleteImagesResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.DeleteImagesResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	results := make([]deleteImageResultResponse, len(resp.V))
+	for i, result := range resp.V {
+		results[i] = deleteImageResultResponse{
+			Index:  result.Index,
+			ID:     result.ID,
+			Status: http.StatusNoContent,
+		}
+		if result.Err != nil {
+			results[i].Error = errortypes.AsServiceError(result.Err)
+			results[i].Status = results[i].Error.GetStatusCode()
+		}
+	}
+
+	return json.NewEncoder(w).Encode(deleteImagesResponse{Results: results})
+}
+
 func decodeSearchImageRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	query := r.URL.Query().Get("query")
 
+	options, err := decodeSearchOptions(r)
+	if err != nil {
+		return nil, err
+	}
+	options.Cursor = r.URL.Query().Get("cursor")
+
 	return imageendpoint.SearchImageRequest{
-		Query: query,
+		Query:   query,
+		Options: options,
 	}, nil
 }
 
+// decodeSearchOptions reads the options shared by every search endpoint from the query string or form.
+func decodeSearchOptions(r *http.Request) (models.SearchOptions, error) {
+	options := models.SearchOptions{}
+
+	if limit := r.FormValue("limit"); limit != "" {
+		v, err := strconv.Atoi(limit)
+		if err != nil {
+			return options, errortypes.NewErrInvalidSearchParameter("limit", "must be an integer")
+		}
+		options.Limit = v
+	}
+	if minScore := r.FormValue("min_score"); minScore != "" {
+		v, err := parseFiniteFloat(minScore)
+		if err != nil {
+			return options, errortypes.NewErrInvalidSearchParameter("min_score", "must be a finite number")
+		}
+		options.MinScore = &v
+	}
+	if maxDistance := r.FormValue("max_distance"); maxDistance != "" {
+		v, err := parseFiniteFloat(maxDistance)
+		if err != nil {
+			return options, errortypes.NewErrInvalidSearchParameter("max_distance", "must be a finite number")
+		}
+		options.MaxDistance = &v
+	}
+	if diversity := r.FormValue("diversity"); diversity != "" {
+		v, err := parseFiniteFloat(diversity)
+		if err != nil {
+			return options, errortypes.NewErrInvalidSearchParameter("diversity", "must be a finite number")
+		}
+		options.Diversity = &v
+	}
+	if mode := r.FormValue("mode"); mode != "" {
+		options.Mode = models.SearchMode(mode)
+	}
+	if textWeight := r.FormValue("text_weight"); textWeight != "" {
+		v, err := parseFiniteFloat(textWeight)
+		if err != nil {
+			return options, errortypes.NewErrInvalidSearchParameter("text_weight", "must be a finite number")
+		}
+		options.TextWeight = &v
+	}
+	if vectorWeight := r.FormValue("vector_weight"); vectorWeight != "" {
+		v, err := parseFiniteFloat(vectorWeight)
+		if err != nil {
+			return options, errortypes.NewErrInvalidSearchParameter("vector_weight", "must be a finite number")
+		}
+		options.VectorWeight = &v
+	}
+
+	filters, err := decodeSearchFilters(r)
+	if err != nil {
+		return options, err
+	}
+	options.Filters = filters
+
+	return options, nil
+}
+
+// parseFiniteFloat parses a number, refusing NaN and infinities, which strconv.ParseFloat
+// accepts but which compare false against every bound.
+func parseFiniteFloat(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("%s is not a finite number", s)
+	}
+	return v, nil
+}
+
+// decodeSearchFilters reads the image attribute and library filters. content_type, collection,
+// tag and exclude_tag may be repeated.
+func decodeSearchFilters(r *http.Request) (models.SearchFilters, error) {
+	filters := models.SearchFilters{
+		ContentTypes:    r.Form["content_type"],
+		FilenamePattern: r.FormValue("filename"),
+		Tags:            r.Form["tag"],
+		ExcludeTags:     r.Form["exclude_tag"],
+	}
+
+	for _, v := range r.Form["collection"] {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return filters, errortypes.NewErrInvalidSearchParameter("collection", "must be a collection id")
+		}
+		filters.CollectionIDs = append(filters.CollectionIDs, id)
+	}
+
+	var err error
+	if filters.CreatedAfter, err = formTime(r, "created_after"); err != nil {
+		return filters, err
+	}
+	if filters.CreatedBefore, err = formTime(r, "created_before"); err != nil {
+		return filters, err
+	}
+	if filters.MinFileSize, err = formInt64(r, "min_file_size"); err != nil {
+		return filters, err
+	}
+	if filters.MaxFileSize, err = formInt64(r, "max_file_size"); err != nil {
+		return filters, err
+	}
+	if filters.MinWidth, err = formInt(r, "min_width"); err != nil {
+		return filters, err
+	}
+	if filters.MaxWidth, err = formInt(r, "max_width"); err != nil {
+		return filters, err
+	}
+	if filters.MinHeight, err = formInt(r, "min_height"); err != nil {
+		return filters, err
+	}
+	if filters.MaxHeight, err = formInt(r, "max_height"); err != nil {
+		return filters, err
+	}
+
+	return filters, nil
+}
+
+func formTime(r *http.Request, name string) (*time.Time, error) {
+	value := r.FormValue(name)
+	if value == "" {
+		return nil, nil
+	}
+	v, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errortypes.NewErrInvalidSearchParameter(name, "must be an RFC 3339 timestamp")
+	}
+	return &v, nil
+}
+
+func formInt64(r *http.Request, name string) (*int64, error) {
+	value := r.FormValue(name)
+	if value == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || v < 0 {
+		return nil, errortypes.NewErrInvalidSearchParameter(name, "must be a non-negative integer")
+	}
+	return &v, nil
+}
+
+func formInt(r *http.Request, name string) (*int, error) {
+	v, err := formInt64(r, name)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	i := int(*v)
+	return &i, nil
+}
+
 func encodeSearchImageResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	resp := response.(imageendpoint.SearchImageResponse)
 
@@ -131,6 +725,205 @@ func encodeSearchImageResponse(ctx context.Context, w http.ResponseWriter, respo
 	return json.NewEncoder(w).Encode(resp.V)
 }
 
+type queryTermRequest struct {
+	Text    string     `json:"text"`
+	ImageID *uuid.UUID `json:"image_id"`
+	File    string     `json:"file"`
+	Weight  *float64   `json:"weight"`
+}
+
+// decodeSearchComposedRequest accepts either a single "file" part, or a "terms" JSON array whose
+// entries reference uploaded parts by their form field name.
+func decodeSearchComposedRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	const maxFileSize = 10 << 20
+
+	if err := r.ParseMultipartForm(maxFileSize); err != nil {
+		return nil, fmt.Errorf("failed to parse multipart form: %w", err)
+	}
+
+	options, err := decodeSearchOptions(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rawTerms := r.FormValue("terms")
+	if rawTerms == "" {
+		rawTerms = `[{"file": "file"}]`
+	}
+
+	var termRequests []queryTermRequest
+	if err := json.Unmarshal([]byte(rawTerms), &termRequests); err != nil {
+		return nil, errortypes.NewErrInvalidSearchParameter("terms", "must be a JSON array of terms")
+	}
+
+	var files []multipart.File
+	closer := func() error {
+		var errs []error
+		for _, file := range files {
+			errs = append(errs, file.Close())
+		}
+		return errors.Join(errs...)
+	}
+
+	terms := make([]models.QueryTerm, len(termRequests))
+	for i, t := range termRequests {
+		terms[i] = models.QueryTerm{
+			Text:    t.Text,
+			ImageID: t.ImageID,
+			Weight:  1,
+		}
+		if t.Weight != nil {
+			terms[i].Weight = *t.Weight
+		}
+
+		if t.File == "" {
+			continue
+		}
+
+		file, header, err := r.FormFile(t.File)
+		if err != nil {
+			closer()
+			return nil, fmt.Errorf("failed to get form file %s: %w", t.File, err)
+		}
+		files = append(files, file)
+
+		terms[i].Upload = &models.StorageFileStream{
+			Reader:        file,
+			Filename:      header.Filename,
+			ContentType:   header.Header.Get("Content-Type"),
+			ContentLength: header.Size,
+		}
+	}
+
+	return imageendpoint.SearchComposedRequest{
+		Terms:   terms,
+		Options: options,
+		Closer:  closer,
+	}, nil
+}
+
+func encodeSearchComposedResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.SearchComposedResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+type batchSearchQueryRequest struct {
+	Query        string               `json:"query"`
+	Cursor       string               `json:"cursor"`
+	Limit        int                  `json:"limit"`
+	MinScore     *float64             `json:"min_score"`
+	MaxDistance  *float64             `json:"max_distance"`
+	Diversity    *float64             `json:"diversity"`
+	Mode         models.SearchMode    `json:"mode"`
+	TextWeight   *float64             `json:"text_weight"`
+	VectorWeight *float64             `json:"vector_weight"`
+	Filters      models.SearchFilters `json:"filters"`
+}
+
+type batchSearchRequest struct {
+	Queries []batchSearchQueryRequest `json:"queries"`
+}
+
+// batchSearchResultResponse carries the HTTP status and body GET /images would have answered
+// the query with.
+type batchSearchResultResponse struct {
+	Index  int                       `json:"index"`
+	Status int                       `json:"status"`
+	Search *models.SearchWithResults `json:"search,omitempty"`
+	Error  errortypes.ServiceError   `json:"error,omitempty"`
+}
+
+type batchSearchResponse struct {
+	Results []batchSearchResultResponse `json:"results"`
+}
+
+func decodeSearchBatchRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req batchSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("failed to decode request body: %w", err)
+	}
+
+	queries := make([]models.BatchSearchQuery, len(req.Queries))
+	for i, q := range req.Queries {
+		queries[i] = models.BatchSearchQuery{
+			Query: q.Query,
+			Options: models.SearchOptions{
+				Limit:        q.Limit,
+				Cursor:       q.Cursor,
+				MinScore:     q.MinScore,
+				MaxDistance:  q.MaxDistance,
+				Diversity:    q.Diversity,
+				Mode:         q.Mode,
+				TextWeight:   q.TextWeight,
+				VectorWeight: q.VectorWeight,
+				Filters:      q.Filters,
+			},
+		}
+	}
+
+	return imageendpoint.SearchBatchRequest{
+		Queries: queries,
+	}, nil
+}
+
+func encodeSearchBatchResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.SearchBatchResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	results := make([]batchSearchResultResponse, len(resp.V))
+	for i, result := range resp.V {
+		results[i] = batchSearchResultResponse{
+			Index:  result.Index,
+			Status: http.StatusOK,
+			Search: result.Search,
+		}
+		if result.Err != nil {
+			results[i].Error = errortypes.AsServiceError(result.Err)
+			results[i].Status = results[i].Error.GetStatusCode()
+		}
+	}
+
+	return json.NewEncoder(w).Encode(batchSearchResponse{Results: results})
+}
+
+func decodeSearchSimilarRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	options, err := decodeSearchOptions(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return imageendpoint.SearchSimilarRequest{
+		ID:      id,
+		Options: options,
+	}, nil
+}
+
+func encodeSearchSimilarResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.SearchSimilarResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
 func decodeSearchFeedbackRequest(ctx context.Context, r *http.Request) (interface{}, error) {
 	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
@@ -139,8 +932,18 @@ func decodeSearchFeedbackRequest(ctx context.Context, r *http.Request) (interfac
 
 	rating := models.Rating(r.FormValue("rating"))
 
+	var imageID *uuid.UUID
+	if v := r.FormValue("image_id"); v != "" {
+		parsed, err := uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse image_id: %w", err)
+		}
+		imageID = &parsed
+	}
+
 	return imageendpoint.SearchFeedbackRequest{
 		QueryID: id,
+		ImageID: imageID,
 		Rating:  rating,
 	}, nil
 }
@@ -155,3 +958,238 @@ func encodeSearchFeedbackResponse(ctx context.Context, w http.ResponseWriter, re
 
 	return json.NewEncoder(w).Encode(resp.V)
 }
+
+func decodeRefineSearchRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	options, err := decodeSearchOptions(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return imageendpoint.RefineSearchRequest{
+		ID:      id,
+		Options: options,
+	}, nil
+}
+
+func encodeRefineSearchResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.RefineSearchResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeSuggestQueriesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	limit := 0
+	if v := r.FormValue("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil {
+			return nil, errortypes.NewErrInvalidSearchParameter("limit", "must be an integer")
+		}
+	}
+
+	return imageendpoint.SuggestQueriesRequest{
+		Prefix:    r.FormValue("prefix"),
+		SimilarTo: r.FormValue("similar_to"),
+		Limit:     limit,
+	}, nil
+}
+
+func encodeSuggestQueriesResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.SuggestQueriesResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeGetSearchRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	return imageendpoint.GetSearchRequest{
+		ID: id,
+	}, nil
+}
+
+func encodeGetSearchResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.GetSearchResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeListSearchesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	filters := models.SearchHistoryFilters{
+		ModelName: r.FormValue("model"),
+		Rating:    models.Rating(r.FormValue("rating")),
+		QueryText: r.FormValue("text"),
+	}
+
+	var err error
+	if filters.CreatedAfter, err = formTime(r, "created_after"); err != nil {
+		return nil, err
+	}
+	if filters.CreatedBefore, err = formTime(r, "created_before"); err != nil {
+		return nil, err
+	}
+	if hasFeedback := r.FormValue("has_feedback"); hasFeedback != "" {
+		v, err := strconv.ParseBool(hasFeedback)
+		if err != nil {
+			return nil, errortypes.NewErrInvalidSearchParameter("has_feedback", "must be true or false")
+		}
+		filters.HasFeedback = &v
+	}
+
+	limit := 0
+	if v := r.FormValue("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			return nil, errortypes.NewErrInvalidSearchParameter("limit", "must be an integer")
+		}
+	}
+
+	return imageendpoint.ListSearchesRequest{
+		Filters: filters,
+		Cursor:  r.FormValue("cursor"),
+		Limit:   limit,
+	}, nil
+}
+
+func encodeListSearchesResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.ListSearchesResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeStartAutotagRunRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return imageendpoint.StartAutotagRunRequest{}, nil
+}
+
+func encodeStartAutotagRunResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.StartAutotagRunResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeGetAutotagRunRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	return imageendpoint.GetAutotagRunRequest{
+		ID: id,
+	}, nil
+}
+
+func encodeGetAutotagRunResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.GetAutotagRunResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+type classifyRequest struct {
+	ImageID   *uuid.UUID `json:"image_id"`
+	Labels    []string   `json:"labels"`
+	Templates []string   `json:"templates"`
+}
+
+// decodeClassifyRequest accepts a JSON body classifying a stored image, or a multipart form with
+// either a "file" part or an image_id field and repeated label and template fields.
+func decodeClassifyRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	const maxFileSize = 10 << 20
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		var req classifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, fmt.Errorf("failed to decode request body: %w", err)
+		}
+
+		return imageendpoint.ClassifyRequest{
+			ImageID:   req.ImageID,
+			Labels:    req.Labels,
+			Templates: req.Templates,
+			Closer:    func() error { return nil },
+		}, nil
+	}
+
+	if err := r.ParseMultipartForm(maxFileSize); err != nil {
+		return nil, fmt.Errorf("failed to parse multipart form: %w", err)
+	}
+
+	req := imageendpoint.ClassifyRequest{
+		Labels:    r.Form["label"],
+		Templates: r.Form["template"],
+		Closer:    func() error { return nil },
+	}
+
+	if imageID := r.FormValue("image_id"); imageID != "" {
+		id, err := uuid.Parse(imageID)
+		if err != nil {
+			return nil, errortypes.NewErrInvalidClassifyParameter("image_id", "must be an image id")
+		}
+		req.ImageID = &id
+	}
+
+	file, header, err := r.FormFile("file")
+	if err == nil {
+		req.Image = &models.StorageFileStream{
+			Reader:        file,
+			Filename:      header.Filename,
+			ContentType:   header.Header.Get("Content-Type"),
+			ContentLength: header.Size,
+		}
+		req.Closer = func() error {
+			return file.Close()
+		}
+	} else if !errors.Is(err, http.ErrMissingFile) {
+		return nil, fmt.Errorf("failed to get form file: %w", err)
+	}
+
+	return req, nil
+}
+
+func encodeClassifyResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(imageendpoint.ClassifyResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
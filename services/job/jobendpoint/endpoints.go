@@ -0,0 +1,70 @@
+package jobendpoint
+
+import (
+	"context"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/job/jobservice"
+)
+
+type Endpoints struct {
+	logger         log.Logger
+	GetJobEndpoint endpoint.Endpoint
+}
+
+func New(svc jobservice.Service, logger log.Logger) Endpoints {
+	var getJobEndpoint endpoint.Endpoint
+	{
+		getJobEndpoint = MakeGetJobEndpoint(svc)
+	}
+
+	return Endpoints{
+		logger:         logger,
+		GetJobEndpoint: getJobEndpoint,
+	}
+}
+
+func MakeGetJobEndpoint(svc jobservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetJobRequest)
+		resp, err := svc.GetJob(ctx, req.ID)
+		return GetJobResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+var _ jobservice.Service = (*Endpoints)(nil)
+
+func (e *Endpoints) GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	resp, err := e.GetJobEndpoint(ctx, GetJobRequest{
+		ID: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(GetJobResponse)
+	return response.V, response.Err
+}
+
+var (
+	_ endpoint.Failer = GetJobResponse{}
+)
+
+type GetJobRequest struct {
+	ID uuid.UUID
+}
+
+type GetJobResponse struct {
+	V   *models.Job
+	Err error
+}
+
+func (r GetJobResponse) Failed() error {
+	return r.Err
+}
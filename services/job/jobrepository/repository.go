@@ -0,0 +1,16 @@
+package jobrepository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+type Repository interface {
+	CreateJob(ctx context.Context, job *models.Job) (*models.Job, error)
+	GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error)
+	ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]models.Job, error)
+	UpdateJob(ctx context.Context, job *models.Job) error
+}
@@ -0,0 +1,97 @@
+package jobrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+type PGRepository struct {
+	logger log.Logger
+	db     *pgxpool.Pool
+}
+
+func NewPGRepository(logger log.Logger, db *pgxpool.Pool) Repository {
+	return &PGRepository{logger: logger, db: db}
+}
+
+func (r *PGRepository) CreateJob(ctx context.Context, job *models.Job) (*models.Job, error) {
+	if job.ID == uuid.Nil {
+		job.ID = uuid.Must(uuid.NewV7())
+	}
+
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf("INSERT INTO jobs (id, type, payload, max_attempts) VALUES ($1, $2, $3, $4) RETURNING %s", jobColumns),
+		job.ID, string(job.Type), job.Payload, job.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := pgx.CollectExactlyOneRow(rows, scanJob)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (r *PGRepository) GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	rows, err := r.db.Query(ctx, fmt.Sprintf("SELECT %s FROM jobs WHERE id = $1", jobColumns), id)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := pgx.CollectExactlyOneRow(rows, scanJob)
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrJobNotFound(id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// ClaimJobs marks due jobs as running and leases them for an attempt. A job whose lease ran out
+// with its attempt unfinished is due again.
+func (r *PGRepository) ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]models.Job, error) {
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf(`UPDATE jobs SET status = 'RUNNING', attempts = attempts + 1, started_at = COALESCE(started_at, now()), next_attempt_at = now() + make_interval(secs => $2), updated_at = now()
+			WHERE id IN (
+				SELECT id FROM jobs
+				WHERE status IN ('QUEUED', 'RUNNING') AND next_attempt_at <= now()
+				ORDER BY next_attempt_at ASC LIMIT $1 FOR UPDATE SKIP LOCKED
+			)
+			RETURNING %s`, jobColumns),
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanJob)
+}
+
+func (r *PGRepository) UpdateJob(ctx context.Context, job *models.Job) error {
+	return r.db.QueryRow(ctx,
+		"UPDATE jobs SET status = $2, result = $3, last_error = $4, next_attempt_at = $5, finished_at = $6, updated_at = now() WHERE id = $1 RETURNING updated_at",
+		job.ID, string(job.Status), job.Result, job.LastError, job.NextAttemptAt, job.FinishedAt).
+		Scan(&job.UpdatedAt)
+}
+
+const jobColumns = "id, type, status, payload, result, attempts, max_attempts, last_error, next_attempt_at, started_at, finished_at, created_at, updated_at"
+
+func scanJob(row pgx.CollectableRow) (models.Job, error) {
+	job := models.Job{}
+	var jobType, status string
+	err := row.Scan(&job.ID, &jobType, &status, &job.Payload, &job.Result, &job.Attempts, &job.MaxAttempts, &job.LastError, &job.NextAttemptAt, &job.StartedAt, &job.FinishedAt, &job.CreatedAt, &job.UpdatedAt)
+	job.Type = models.JobType(jobType)
+	job.Status = models.JobStatus(status)
+	return job, err
+}
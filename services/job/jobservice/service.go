@@ -0,0 +1,31 @@
+package jobservice
+
+import (
+	"context"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/job/jobrepository"
+)
+
+// Service reports on background jobs. Jobs are queued and run by a Worker.
+type Service interface {
+	GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error)
+}
+
+type jobService struct {
+	logger        log.Logger
+	jobRepository jobrepository.Repository
+}
+
+func New(logger log.Logger, jobRepository jobrepository.Repository) Service {
+	return &jobService{
+		logger:        logger,
+		jobRepository: jobRepository,
+	}
+}
+
+func (s *jobService) GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	return s.jobRepository.GetJob(ctx, id)
+}
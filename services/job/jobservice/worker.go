@@ -0,0 +1,217 @@
+package jobservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/pkg/retry"
+	"github.com/yckao/image-search-demo-go/services/job/jobrepository"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	DefaultMaxAttempts  = 5
+	DefaultBaseBackoff  = 10 * time.Second
+	DefaultMaxBackoff   = 10 * time.Minute
+	DefaultPollInterval = 5 * time.Second
+	DefaultTimeout      = 5 * time.Minute
+	DefaultConcurrency  = 4
+)
+
+type WorkerConfig struct {
+	// MaxAttempts is how many times a job is attempted before it is marked failed.
+	MaxAttempts int
+	// Backoff spaces out the attempts of a job.
+	Backoff retry.Backoff
+	// PollInterval is how often due jobs are looked for when nothing wakes the worker.
+	PollInterval time.Duration
+	// Timeout bounds a single attempt.
+	Timeout time.Duration
+	// Concurrency bounds how many jobs run at once.
+	Concurrency int
+}
+
+// Handler runs one attempt of a job and returns its result, which is stored as JSON. An error
+// with a status code below 500 is the job's fault and fails it for good; any other error is
+// retried until the job runs out of attempts.
+type Handler func(ctx context.Context, job *models.Job) (any, error)
+
+// Worker runs the jobs queued in the database. A job is claimed for one attempt at a time, so
+// several workers may share the queue, and a job whose worker died is attempted again once its
+// claim runs out.
+type Worker struct {
+	logger        log.Logger
+	config        WorkerConfig
+	jobRepository jobrepository.Repository
+	poller        *retry.Poller
+
+	mu       sync.RWMutex
+	handlers map[models.JobType]Handler
+}
+
+func NewWorker(logger log.Logger, config WorkerConfig, jobRepository jobrepository.Repository) *Worker {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+	config.Backoff = config.Backoff.WithDefaults(retry.Backoff{Base: DefaultBaseBackoff, Max: DefaultMaxBackoff})
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultConcurrency
+	}
+
+	return &Worker{
+		logger:        logger,
+		config:        config,
+		jobRepository: jobRepository,
+		poller:        retry.NewPoller(config.PollInterval),
+		handlers:      map[models.JobType]Handler{},
+	}
+}
+
+// Handle registers the handler of a job type. Jobs of a type without a handler fail.
+func (w *Worker) Handle(jobType models.JobType, handler Handler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers[jobType] = handler
+}
+
+// Enqueue queues a job whose handler will be given payload, marshaled to JSON.
+func (w *Worker) Enqueue(ctx context.Context, jobType models.JobType, payload any) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := w.jobRepository.CreateJob(ctx, &models.Job{
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: w.config.MaxAttempts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w.poller.Wake()
+
+	return job, nil
+}
+
+// Run runs due jobs until ctx is done.
+func (w *Worker) Run(ctx context.Context) error {
+	return w.poller.Run(ctx, w.config.Concurrency, func(ctx context.Context) int {
+		n, err := w.work(ctx)
+		if err != nil {
+			w.logger.Log("job", "work", "error", err)
+		}
+		return n
+	})
+}
+
+// work runs as many due jobs as may run at once. Only jobs that start right away are claimed; a
+// job left waiting for a free slot could outlive its claim and be run again by another worker.
+func (w *Worker) work(ctx context.Context) (int, error) {
+	jobs, err := w.jobRepository.ClaimJobs(ctx, w.config.Concurrency, retry.Lease(w.config.Timeout))
+	if err != nil {
+		return 0, err
+	}
+
+	group := errgroup.Group{}
+	for i := range jobs {
+		group.Go(func() error {
+			w.run(ctx, &jobs[i])
+			return nil
+		})
+	}
+	group.Wait()
+
+	return len(jobs), nil
+}
+
+func (w *Worker) run(ctx context.Context, job *models.Job) {
+	w.mu.RLock()
+	handler, ok := w.handlers[job.Type]
+	w.mu.RUnlock()
+
+	var result any
+	var err error
+	final := false
+	switch {
+	case !ok:
+		err = fmt.Errorf("no handler for job type %s", job.Type)
+		final = true
+	case job.Attempts > job.MaxAttempts:
+		// The claim of the last attempt ran out before the attempt finished.
+		err = errors.New("job was interrupted")
+		final = true
+	default:
+		result, err = w.attempt(ctx, handler, job)
+	}
+
+	if ctx.Err() != nil {
+		// Shutting down; the job is attempted again once its claim runs out.
+		return
+	}
+
+	if err == nil {
+		result, err := json.Marshal(result)
+		if err == nil {
+			now := time.Now()
+			job.Status = models.JobStatusSucceeded
+			job.Result = result
+			job.LastError = nil
+			job.NextAttemptAt = nil
+			job.FinishedAt = &now
+			w.update(ctx, job)
+			return
+		}
+		final = true
+	}
+
+	message := err.Error()
+	job.LastError = &message
+	w.logger.Log("job", job.ID, "type", job.Type, "attempt", job.Attempts, "error", err)
+
+	now := time.Now()
+	if final || IsFinalAttempt(job, err) {
+		job.Status = models.JobStatusFailed
+		job.NextAttemptAt = nil
+		job.FinishedAt = &now
+	} else {
+		next := now.Add(w.config.Backoff.Delay(job.Attempts))
+		job.Status = models.JobStatusQueued
+		job.NextAttemptAt = &next
+	}
+
+	w.update(ctx, job)
+}
+
+func (w *Worker) attempt(ctx context.Context, handler Handler, job *models.Job) (any, error) {
+	ctx, cancel := context.WithTimeout(ctx, w.config.Timeout)
+	defer cancel()
+
+	return handler(ctx, job)
+}
+
+func (w *Worker) update(ctx context.Context, job *models.Job) {
+	if err := w.jobRepository.UpdateJob(ctx, job); err != nil {
+		w.logger.Log("job", job.ID, "error", err)
+	}
+}
+
+// IsFinalAttempt reports whether a job that failed with err will not be attempted again, so
+// that a handler knows when to clean up after itself.
+func IsFinalAttempt(job *models.Job, err error) bool {
+	return job.Attempts >= job.MaxAttempts || errortypes.AsServiceError(err).GetStatusCode() < 500
+}
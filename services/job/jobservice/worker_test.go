@@ -0,0 +1,224 @@
+package jobservice
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/pkg/retry"
+	"github.com/yckao/image-search-demo-go/services/job/jobrepository"
+)
+
+// jobRepository claims queued jobs from memory the way the Postgres repository does, counting
+// the attempt, and records their last update.
+type jobRepository struct {
+	jobrepository.Repository
+	queued []models.Job
+	limits []int
+
+	mu      sync.Mutex
+	updated map[uuid.UUID]models.Job
+}
+
+func (r *jobRepository) ClaimJobs(ctx context.Context, limit int, lease time.Duration) ([]models.Job, error) {
+	r.limits = append(r.limits, limit)
+
+	jobs := r.queued[:min(limit, len(r.queued))]
+	r.queued = r.queued[len(jobs):]
+	for i := range jobs {
+		jobs[i].Status = models.JobStatusRunning
+		jobs[i].Attempts++
+	}
+	return jobs, nil
+}
+
+func (r *jobRepository) UpdateJob(ctx context.Context, job *models.Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.updated == nil {
+		r.updated = map[uuid.UUID]models.Job{}
+	}
+	r.updated[job.ID] = *job
+	return nil
+}
+
+func TestWorkerClaimsOnlyWhatItRuns(t *testing.T) {
+	repository := &jobRepository{}
+	for range 5 {
+		repository.queued = append(repository.queued, models.Job{ID: uuid.New(), Type: models.JobTypeCreateImage, MaxAttempts: 3})
+	}
+
+	w := NewWorker(log.NewNopLogger(), WorkerConfig{Concurrency: 2}, repository)
+	w.Handle(models.JobTypeCreateImage, func(ctx context.Context, job *models.Job) (any, error) {
+		return nil, nil
+	})
+
+	var claimed []int
+	for len(repository.queued) > 0 {
+		n, err := w.work(context.Background())
+		if err != nil {
+			t.Fatalf("work() error = %v", err)
+		}
+		claimed = append(claimed, n)
+	}
+
+	for i, limit := range repository.limits {
+		if limit != 2 {
+			t.Errorf("claim %d asked for %d jobs, want Concurrency", i, limit)
+		}
+	}
+	if len(claimed) != 3 || claimed[0] != 2 || claimed[1] != 2 || claimed[2] != 1 {
+		t.Errorf("work() claimed %v, want [2 2 1]", claimed)
+	}
+	if len(repository.updated) != 5 {
+		t.Errorf("%d jobs were run, want 5", len(repository.updated))
+	}
+}
+
+func TestWorkerRun(t *testing.T) {
+	config := WorkerConfig{Backoff: retry.Backoff{Base: time.Minute, Max: time.Hour}}
+	failing := func(err error) Handler {
+		return func(ctx context.Context, job *models.Job) (any, error) {
+			return nil, err
+		}
+	}
+
+	tests := []struct {
+		name          string
+		jobType       models.JobType
+		attempts      int
+		handler       Handler
+		wantStatus    models.JobStatus
+		wantResult    string
+		wantError     string
+		wantNextAfter time.Duration
+	}{
+		{
+			name: "success stores the result",
+			handler: func(ctx context.Context, job *models.Job) (any, error) {
+				return map[string]int{"n": 1}, nil
+			},
+			wantStatus: models.JobStatusSucceeded,
+			wantResult: `{"n":1}`,
+		},
+		{
+			name:          "internal error is retried",
+			handler:       failing(errors.New("storage unavailable")),
+			wantStatus:    models.JobStatusQueued,
+			wantError:     "storage unavailable",
+			wantNextAfter: time.Minute,
+		},
+		{
+			name:          "backoff doubles with every attempt",
+			attempts:      2,
+			handler:       failing(errors.New("storage unavailable")),
+			wantStatus:    models.JobStatusQueued,
+			wantError:     "storage unavailable",
+			wantNextAfter: 4 * time.Minute,
+		},
+		{
+			name:       "last attempt fails the job",
+			attempts:   4,
+			handler:    failing(errors.New("storage unavailable")),
+			wantStatus: models.JobStatusFailed,
+			wantError:  "storage unavailable",
+		},
+		{
+			name:       "client error is not retried",
+			handler:    failing(errortypes.NewErrImageNotFound(uuid.Nil)),
+			wantStatus: models.JobStatusFailed,
+			wantError:  errortypes.NewErrImageNotFound(uuid.Nil).Error(),
+		},
+		{
+			name:       "job without a handler fails",
+			jobType:    "unknown",
+			wantStatus: models.JobStatusFailed,
+			wantError:  "no handler for job type unknown",
+		},
+		{
+			name:       "interrupted last attempt fails the job",
+			attempts:   5,
+			handler:    failing(errors.New("must not run")),
+			wantStatus: models.JobStatusFailed,
+			wantError:  "job was interrupted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := models.Job{ID: uuid.New(), Type: models.JobTypeCreateImage, Attempts: tt.attempts, MaxAttempts: 5}
+			if tt.jobType != "" {
+				job.Type = tt.jobType
+			}
+			repository := &jobRepository{queued: []models.Job{job}}
+
+			w := NewWorker(log.NewNopLogger(), config, repository)
+			if tt.handler != nil {
+				w.Handle(models.JobTypeCreateImage, tt.handler)
+			}
+
+			start := time.Now()
+			if _, err := w.work(context.Background()); err != nil {
+				t.Fatalf("work() error = %v", err)
+			}
+
+			got, ok := repository.updated[job.ID]
+			if !ok {
+				t.Fatal("work() did not record the attempt")
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if string(got.Result) != tt.wantResult {
+				t.Errorf("Result = %s, want %s", got.Result, tt.wantResult)
+			}
+
+			var lastError string
+			if got.LastError != nil {
+				lastError = *got.LastError
+			}
+			if lastError != tt.wantError {
+				t.Errorf("LastError = %q, want %q", lastError, tt.wantError)
+			}
+
+			finished := tt.wantStatus == models.JobStatusSucceeded || tt.wantStatus == models.JobStatusFailed
+			if (got.FinishedAt != nil) != finished {
+				t.Errorf("FinishedAt = %v, want set %t", got.FinishedAt, finished)
+			}
+			switch {
+			case tt.wantNextAfter == 0 && got.NextAttemptAt != nil:
+				t.Errorf("NextAttemptAt = %v, want nil", got.NextAttemptAt)
+			case tt.wantNextAfter != 0 && (got.NextAttemptAt == nil || got.NextAttemptAt.Before(start.Add(tt.wantNextAfter)) || got.NextAttemptAt.After(time.Now().Add(tt.wantNextAfter))):
+				t.Errorf("NextAttemptAt = %v, want %v after the attempt", got.NextAttemptAt, tt.wantNextAfter)
+			}
+		})
+	}
+}
+
+func TestIsFinalAttempt(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		want     bool
+	}{
+		{name: "internal error with attempts left", attempts: 1, err: errors.New("timeout"), want: false},
+		{name: "internal error on the last attempt", attempts: 3, err: errors.New("timeout"), want: true},
+		{name: "client error with attempts left", attempts: 1, err: errortypes.NewErrImageNotFound(uuid.Nil), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &models.Job{Attempts: tt.attempts, MaxAttempts: 3}
+			if got := IsFinalAttempt(job, tt.err); got != tt.want {
+				t.Errorf("IsFinalAttempt() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package jobtransport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/services/job/jobendpoint"
+)
+
+func NewHTTPHandler(svc jobendpoint.Endpoints, logger log.Logger) http.Handler {
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(errortypes.ErrorEncoder),
+		httptransport.ServerErrorLogger(logger),
+	}
+
+	m := http.NewServeMux()
+
+	m.Handle("GET /jobs/{id}", httptransport.NewServer(
+		svc.GetJobEndpoint,
+		decodeGetJobRequest,
+		encodeGetJobResponse,
+		options...,
+	))
+
+	return m
+}
+
+func decodeGetJobRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	return jobendpoint.GetJobRequest{
+		ID: id,
+	}, nil
+}
+
+func encodeGetJobResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(jobendpoint.GetJobResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
@@ -0,0 +1,717 @@
+package libraryendpoint
+
+import (
+	"context"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/library/libraryservice"
+)
+
+type Endpoints struct {
+	logger                        log.Logger
+	CreateTagEndpoint             endpoint.Endpoint
+	ListTagsEndpoint              endpoint.Endpoint
+	GetTagEndpoint                endpoint.Endpoint
+	UpdateTagEndpoint             endpoint.Endpoint
+	DeleteTagEndpoint             endpoint.Endpoint
+	TagImageEndpoint              endpoint.Endpoint
+	UntagImageEndpoint            endpoint.Endpoint
+	ListTagImagesEndpoint         endpoint.Endpoint
+	CreateCollectionEndpoint      endpoint.Endpoint
+	ListCollectionsEndpoint       endpoint.Endpoint
+	GetCollectionEndpoint         endpoint.Endpoint
+	UpdateCollectionEndpoint      endpoint.Endpoint
+	DeleteCollectionEndpoint      endpoint.Endpoint
+	AddCollectionImageEndpoint    endpoint.Endpoint
+	RemoveCollectionImageEndpoint endpoint.Endpoint
+	ListCollectionImagesEndpoint  endpoint.Endpoint
+}
+
+func New(svc libraryservice.Service, logger log.Logger) Endpoints {
+	var createTagEndpoint endpoint.Endpoint
+	{
+		createTagEndpoint = MakeCreateTagEndpoint(svc)
+	}
+
+	var listTagsEndpoint endpoint.Endpoint
+	{
+		listTagsEndpoint = MakeListTagsEndpoint(svc)
+	}
+
+	var getTagEndpoint endpoint.Endpoint
+	{
+		getTagEndpoint = MakeGetTagEndpoint(svc)
+	}
+
+	var updateTagEndpoint endpoint.Endpoint
+	{
+		updateTagEndpoint = MakeUpdateTagEndpoint(svc)
+	}
+
+	var deleteTagEndpoint endpoint.Endpoint
+	{
+		deleteTagEndpoint = MakeDeleteTagEndpoint(svc)
+	}
+
+	var tagImageEndpoint endpoint.Endpoint
+	{
+		tagImageEndpoint = MakeTagImageEndpoint(svc)
+	}
+
+	var untagImageEndpoint endpoint.Endpoint
+	{
+		untagImageEndpoint = MakeUntagImageEndpoint(svc)
+	}
+
+	var listTagImagesEndpoint endpoint.Endpoint
+	{
+		listTagImagesEndpoint = MakeListTagImagesEndpoint(svc)
+	}
+
+	var createCollectionEndpoint endpoint.Endpoint
+	{
+		createCollectionEndpoint = MakeCreateCollectionEndpoint(svc)
+	}
+
+	var listCollectionsEndpoint endpoint.Endpoint
+	{
+		listCollectionsEndpoint = MakeListCollectionsEndpoint(svc)
+	}
+
+	var getCollectionEndpoint endpoint.Endpoint
+	{
+		getCollectionEndpoint = MakeGetCollectionEndpoint(svc)
+	}
+
+	var updateCollectionEndpoint endpoint.Endpoint
+	{
+		updateCollectionEndpoint = MakeUpdateCollectionEndpoint(svc)
+	}
+
+	var deleteCollectionEndpoint endpoint.Endpoint
+	{
+		deleteCollectionEndpoint = MakeDeleteCollectionEndpoint(svc)
+	}
+
+	var addCollectionImageEndpoint endpoint.Endpoint
+	{
+		addCollectionImageEndpoint = MakeAddCollectionImageEndpoint(svc)
+	}
+
+	var removeCollectionImageEndpoint endpoint.Endpoint
+	{
+		removeCollectionImageEndpoint = MakeRemoveCollectionImageEndpoint(svc)
+	}
+
+	var listCollectionImagesEndpoint endpoint.Endpoint
+	{
+		listCollectionImagesEndpoint = MakeListCollectionImagesEndpoint(svc)
+	}
+
+	return Endpoints{
+		logger:                        logger,
+		CreateTagEndpoint:             createTagEndpoint,
+		ListTagsEndpoint:              listTagsEndpoint,
+		GetTagEndpoint:                getTagEndpoint,
+		UpdateTagEndpoint:             updateTagEndpoint,
+		DeleteTagEndpoint:             deleteTagEndpoint,
+		TagImageEndpoint:              tagImageEndpoint,
+		UntagImageEndpoint:            untagImageEndpoint,
+		ListTagImagesEndpoint:         listTagImagesEndpoint,
+		CreateCollectionEndpoint:      createCollectionEndpoint,
+		ListCollectionsEndpoint:       listCollectionsEndpoint,
+		GetCollectionEndpoint:         getCollectionEndpoint,
+		UpdateCollectionEndpoint:      updateCollectionEndpoint,
+		DeleteCollectionEndpoint:      deleteCollectionEndpoint,
+		AddCollectionImageEndpoint:    addCollectionImageEndpoint,
+		RemoveCollectionImageEndpoint: removeCollectionImageEndpoint,
+		ListCollectionImagesEndpoint:  listCollectionImagesEndpoint,
+	}
+}
+
+func MakeCreateTagEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateTagRequest)
+		resp, err := svc.CreateTag(ctx, req.Name)
+		return CreateTagResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeListTagsEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		resp, err := svc.ListTags(ctx)
+		return ListTagsResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeGetTagEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetTagRequest)
+		resp, err := svc.GetTag(ctx, req.ID)
+		return GetTagResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeUpdateTagEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UpdateTagRequest)
+		resp, err := svc.UpdateTag(ctx, req.ID, req.Name)
+		return UpdateTagResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeDeleteTagEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteTagRequest)
+		err := svc.DeleteTag(ctx, req.ID)
+		return DeleteTagResponse{
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeTagImageEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(TagImageRequest)
+		err := svc.TagImage(ctx, req.TagID, req.ImageID)
+		return TagImageResponse{
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeUntagImageEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UntagImageRequest)
+		err := svc.UntagImage(ctx, req.TagID, req.ImageID)
+		return UntagImageResponse{
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeListTagImagesEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ListTagImagesRequest)
+		resp, err := svc.ListTagImages(ctx, req.TagID, req.After, req.Limit)
+		return ListTagImagesResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeCreateCollectionEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateCollectionRequest)
+		resp, err := svc.CreateCollection(ctx, req.Name, req.Description)
+		return CreateCollectionResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeListCollectionsEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		resp, err := svc.ListCollections(ctx)
+		return ListCollectionsResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeGetCollectionEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetCollectionRequest)
+		resp, err := svc.GetCollection(ctx, req.ID)
+		return GetCollectionResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeUpdateCollectionEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UpdateCollectionRequest)
+		resp, err := svc.UpdateCollection(ctx, req.ID, req.Name, req.Description)
+		return UpdateCollectionResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeDeleteCollectionEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteCollectionRequest)
+		err := svc.DeleteCollection(ctx, req.ID)
+		return DeleteCollectionResponse{
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeAddCollectionImageEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(AddCollectionImageRequest)
+		err := svc.AddCollectionImage(ctx, req.CollectionID, req.ImageID)
+		return AddCollectionImageResponse{
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeRemoveCollectionImageEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RemoveCollectionImageRequest)
+		err := svc.RemoveCollectionImage(ctx, req.CollectionID, req.ImageID)
+		return RemoveCollectionImageResponse{
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeListCollectionImagesEndpoint(svc libraryservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ListCollectionImagesRequest)
+		resp, err := svc.ListCollectionImages(ctx, req.CollectionID, req.After, req.Limit)
+		return ListCollectionImagesResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+var _ libraryservice.Service = (*Endpoints)(nil)
+
+func (e *Endpoints) CreateTag(ctx context.Context, name string) (*models.Tag, error) {
+	resp, err := e.CreateTagEndpoint(ctx, CreateTagRequest{
+		Name: name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(CreateTagResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) ListTags(ctx context.Context) ([]models.Tag, error) {
+	resp, err := e.ListTagsEndpoint(ctx, ListTagsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(ListTagsResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) GetTag(ctx context.Context, id uuid.UUID) (*models.Tag, error) {
+	resp, err := e.GetTagEndpoint(ctx, GetTagRequest{
+		ID: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(GetTagResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) UpdateTag(ctx context.Context, id uuid.UUID, name string) (*models.Tag, error) {
+	resp, err := e.UpdateTagEndpoint(ctx, UpdateTagRequest{
+		ID:   id,
+		Name: name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(UpdateTagResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) DeleteTag(ctx context.Context, id uuid.UUID) error {
+	resp, err := e.DeleteTagEndpoint(ctx, DeleteTagRequest{
+		ID: id,
+	})
+	if err != nil {
+		return err
+	}
+	response := resp.(DeleteTagResponse)
+	return response.Err
+}
+
+func (e *Endpoints) TagImage(ctx context.Context, tagID uuid.UUID, imageID uuid.UUID) error {
+	resp, err := e.TagImageEndpoint(ctx, TagImageRequest{
+		TagID:   tagID,
+		ImageID: imageID,
+	})
+	if err != nil {
+		return err
+	}
+	response := resp.(TagImageResponse)
+	return response.Err
+}
+
+func (e *Endpoints) UntagImage(ctx context.Context, tagID uuid.UUID, imageID uuid.UUID) error {
+	resp, err := e.UntagImageEndpoint(ctx, UntagImageRequest{
+		TagID:   tagID,
+		ImageID: imageID,
+	})
+	if err != nil {
+		return err
+	}
+	response := resp.(UntagImageResponse)
+	return response.Err
+}
+
+func (e *Endpoints) ListTagImages(ctx context.Context, tagID uuid.UUID, after *uuid.UUID, limit int) (*models.ImageList, error) {
+	resp, err := e.ListTagImagesEndpoint(ctx, ListTagImagesRequest{
+		TagID: tagID,
+		After: after,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(ListTagImagesResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) CreateCollection(ctx context.Context, name string, description string) (*models.Collection, error) {
+	resp, err := e.CreateCollectionEndpoint(ctx, CreateCollectionRequest{
+		Name:        name,
+		Description: description,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(CreateCollectionResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) ListCollections(ctx context.Context) ([]models.Collection, error) {
+	resp, err := e.ListCollectionsEndpoint(ctx, ListCollectionsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(ListCollectionsResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) GetCollection(ctx context.Context, id uuid.UUID) (*models.Collection, error) {
+	resp, err := e.GetCollectionEndpoint(ctx, GetCollectionRequest{
+		ID: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(GetCollectionResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) UpdateCollection(ctx context.Context, id uuid.UUID, name string, description string) (*models.Collection, error) {
+	resp, err := e.UpdateCollectionEndpoint(ctx, UpdateCollectionRequest{
+		ID:          id,
+		Name:        name,
+		Description: description,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(UpdateCollectionResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) DeleteCollection(ctx context.Context, id uuid.UUID) error {
+	resp, err := e.DeleteCollectionEndpoint(ctx, DeleteCollectionRequest{
+		ID: id,
+	})
+	if err != nil {
+		return err
+	}
+	response := resp.(DeleteCollectionResponse)
+	return response.Err
+}
+
+func (e *Endpoints) AddCollectionImage(ctx context.Context, collectionID uuid.UUID, imageID uuid.UUID) error {
+	resp, err := e.AddCollectionImageEndpoint(ctx, AddCollectionImageRequest{
+		CollectionID: collectionID,
+		ImageID:      imageID,
+	})
+	if err != nil {
+		return err
+	}
+	response := resp.(AddCollectionImageResponse)
+	return response.Err
+}
+
+func (e *Endpoints) RemoveCollectionImage(ctx context.Context, collectionID uuid.UUID, imageID uuid.UUID) error {
+	resp, err := e.RemoveCollectionImageEndpoint(ctx, RemoveCollectionImageRequest{
+		CollectionID: collectionID,
+		ImageID:      imageID,
+	})
+	if err != nil {
+		return err
+	}
+	response := resp.(RemoveCollectionImageResponse)
+	return response.Err
+}
+
+func (e *Endpoints) ListCollectionImages(ctx context.Context, collectionID uuid.UUID, after *uuid.UUID, limit int) (*models.ImageList, error) {
+	resp, err := e.ListCollectionImagesEndpoint(ctx, ListCollectionImagesRequest{
+		CollectionID: collectionID,
+		After:        after,
+		Limit:        limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(ListCollectionImagesResponse)
+	return response.V, response.Err
+}
+
+var (
+	_ endpoint.Failer = CreateTagResponse{}
+	_ endpoint.Failer = ListTagsResponse{}
+	_ endpoint.Failer = GetTagResponse{}
+	_ endpoint.Failer = UpdateTagResponse{}
+	_ endpoint.Failer = DeleteTagResponse{}
+	_ endpoint.Failer = TagImageResponse{}
+	_ endpoint.Failer = UntagImageResponse{}
+	_ endpoint.Failer = ListTagImagesResponse{}
+	_ endpoint.Failer = CreateCollectionResponse{}
+	_ endpoint.Failer = ListCollectionsResponse{}
+	_ endpoint.Failer = GetCollectionResponse{}
+	_ endpoint.Failer = UpdateCollectionResponse{}
+	_ endpoint.Failer = DeleteCollectionResponse{}
+	_ endpoint.Failer = AddCollectionImageResponse{}
+	_ endpoint.Failer = RemoveCollectionImageResponse{}
+	_ endpoint.Failer = ListCollectionImagesResponse{}
+)
+
+type CreateTagRequest struct {
+	Name string
+}
+
+type CreateTagResponse struct {
+	V   *models.Tag
+	Err error
+}
+
+func (r CreateTagResponse) Failed() error {
+	return r.Err
+}
+
+type ListTagsRequest struct{}
+
+type ListTagsResponse struct {
+	V   []models.Tag
+	Err error
+}
+
+func (r ListTagsResponse) Failed() error {
+	return r.Err
+}
+
+type GetTagRequest struct {
+	ID uuid.UUID
+}
+
+type GetTagResponse struct {
+	V   *models.Tag
+	Err error
+}
+
+func (r GetTagResponse) Failed() error {
+	return r.Err
+}
+
+type UpdateTagRequest struct {
+	ID   uuid.UUID
+	Name string
+}
+
+type UpdateTagResponse struct {
+	V   *models.Tag
+	Err error
+}
+
+func (r UpdateTagResponse) Failed() error {
+	return r.Err
+}
+
+type DeleteTagRequest struct {
+	ID uuid.UUID
+}
+
+type DeleteTagResponse struct {
+	Err error
+}
+
+func (r DeleteTagResponse) Failed() error {
+	return r.Err
+}
+
+type TagImageRequest struct {
+	TagID   uuid.UUID
+	ImageID uuid.UUID
+}
+
+type TagImageResponse struct {
+	Err error
+}
+
+func (r TagImageResponse) Failed() error {
+	return r.Err
+}
+
+type UntagImageRequest struct {
+	TagID   uuid.UUID
+	ImageID uuid.UUID
+}
+
+type UntagImageResponse struct {
+	Err error
+}
+
+func (r UntagImageResponse) Failed() error {
+	return r.Err
+}
+
+type ListTagImagesRequest struct {
+	TagID uuid.UUID
+	After *uuid.UUID
+	Limit int
+}
+
+type ListTagImagesResponse struct {
+	V   *models.ImageList
+	Err error
+}
+
+func (r ListTagImagesResponse) Failed() error {
+	return r.Err
+}
+
+type CreateCollectionRequest struct {
+	Name        string
+	Description string
+}
+
+type CreateCollectionResponse struct {
+	V   *models.Collection
+	Err error
+}
+
+func (r CreateCollectionResponse) Failed() error {
+	return r.Err
+}
+
+type ListCollectionsRequest struct{}
+
+type ListCollectionsResponse struct {
+	V   []models.Collection
+	Err error
+}
+
+func (r ListCollectionsResponse) Failed() error {
+	return r.Err
+}
+
+type GetCollectionRequest struct {
+	ID uuid.UUID
+}
+
+type GetCollectionResponse struct {
+	V   *models.Collection
+	Err error
+}
+
+func (r GetCollectionResponse) Failed() error {
+	return r.Err
+}
+
+type UpdateCollectionRequest struct {
+	ID          uuid.UUID
+	Name        string
+	Description string
+}
+
+type UpdateCollectionResponse struct {
+	V   *models.Collection
+	Err error
+}
+
+func (r UpdateCollectionResponse) Failed() error {
+	return r.Err
+}
+
+type DeleteCollectionRequest struct {
+	ID uuid.UUID
+}
+
+type DeleteCollectionResponse struct {
+	Err error
+}
+
+func (r DeleteCollectionResponse) Failed() error {
+	return r.Err
+}
+
+type AddCollectionImageRequest struct {
+	CollectionID uuid.UUID
+	ImageID      uuid.UUID
+}
+
+type AddCollectionImageResponse struct {
+	Err error
+}
+
+func (r AddCollectionImageResponse) Failed() error {
+	return r.Err
+}
+
+type RemoveCollectionImageRequest struct {
+	CollectionID uuid.UUID
+	ImageID      uuid.UUID
+}
+
+type RemoveCollectionImageResponse struct {
+	Err error
+}
+
+func (r RemoveCollectionImageResponse) Failed() error {
+	return r.Err
+}
+
+type ListCollectionImagesRequest struct {
+	CollectionID uuid.UUID
+	After        *uuid.UUID
+	Limit        int
+}
+
+type ListCollectionImagesResponse struct {
+	V   *models.ImageList
+	Err error
+}
+
+func (r ListCollectionImagesResponse) Failed() error {
+	return r.Err
+}
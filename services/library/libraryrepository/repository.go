@@ -0,0 +1,27 @@
+package libraryrepository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+type Repository interface {
+	CreateTag(ctx context.Context, tag *models.Tag) (*models.Tag, error)
+	ListTags(ctx context.Context) ([]models.Tag, error)
+	GetTag(ctx context.Context, id uuid.UUID) (*models.Tag, error)
+	UpdateTag(ctx context.Context, tag *models.Tag) (*models.Tag, error)
+	DeleteTag(ctx context.Context, id uuid.UUID) error
+	AddImageTag(ctx context.Context, tagID uuid.UUID, imageID uuid.UUID) error
+	RemoveImageTag(ctx context.Context, tagID uuid.UUID, imageID uuid.UUID) error
+	ListTagImages(ctx context.Context, tagID uuid.UUID, after *uuid.UUID, limit int) ([]models.Image, error)
+	CreateCollection(ctx context.Context, collection *models.Collection) (*models.Collection, error)
+	ListCollections(ctx context.Context) ([]models.Collection, error)
+	GetCollection(ctx context.Context, id uuid.UUID) (*models.Collection, error)
+	UpdateCollection(ctx context.Context, collection *models.Collection) (*models.Collection, error)
+	DeleteCollection(ctx context.Context, id uuid.UUID) error
+	AddCollectionImage(ctx context.Context, collectionID uuid.UUID, imageID uuid.UUID) error
+	RemoveCollectionImage(ctx context.Context, collectionID uuid.UUID, imageID uuid.UUID) error
+	ListCollectionImages(ctx context.Context, collectionID uuid.UUID, after *uuid.UUID, limit int) ([]models.Image, error)
+}
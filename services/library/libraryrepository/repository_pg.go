@@ -0,0 +1,264 @@
+package libraryrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/image/imagerepository"
+)
+
+type PGRepository struct {
+	logger log.Logger
+	db     *pgxpool.Pool
+}
+
+func NewPGRepository(logger log.Logger, db *pgxpool.Pool) Repository {
+	return &PGRepository{logger: logger, db: db}
+}
+
+func (r *PGRepository) CreateTag(ctx context.Context, tag *models.Tag) (*models.Tag, error) {
+	if tag.ID == uuid.Nil {
+		tag.ID = uuid.Must(uuid.NewV7())
+	}
+
+	if err := r.db.QueryRow(ctx,
+		"INSERT INTO tags (id, name) VALUES ($1, $2) RETURNING created_at",
+		tag.ID, tag.Name).Scan(&tag.CreatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return nil, errortypes.NewErrTagAlreadyExists(tag.Name)
+		}
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (r *PGRepository) ListTags(ctx context.Context) ([]models.Tag, error) {
+	rows, err := r.db.Query(ctx, "SELECT id, name, created_at FROM tags ORDER BY name ASC")
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Tag, error) {
+		tag := models.Tag{}
+		err := row.Scan(&tag.ID, &tag.Name, &tag.CreatedAt)
+		return tag, err
+	})
+}
+
+func (r *PGRepository) GetTag(ctx context.Context, id uuid.UUID) (*models.Tag, error) {
+	tag := models.Tag{}
+
+	if err := r.db.QueryRow(ctx, "SELECT id, name, created_at FROM tags WHERE id = $1", id).
+		Scan(&tag.ID, &tag.Name, &tag.CreatedAt); err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrTagNotFound(id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+func (r *PGRepository) UpdateTag(ctx context.Context, tag *models.Tag) (*models.Tag, error) {
+	if err := r.db.QueryRow(ctx,
+		"UPDATE tags SET name = $2 WHERE id = $1 RETURNING created_at",
+		tag.ID, tag.Name).Scan(&tag.CreatedAt); err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrTagNotFound(tag.ID)
+	} else if isUniqueViolation(err) {
+		return nil, errortypes.NewErrTagAlreadyExists(tag.Name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (r *PGRepository) DeleteTag(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, "DELETE FROM tags WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return errortypes.NewErrTagNotFound(id)
+	}
+
+	return nil
+}
+
+func (r *PGRepository) AddImageTag(ctx context.Context, tagID uuid.UUID, imageID uuid.UUID) error {
+	if _, err := r.db.Exec(ctx,
+		"INSERT INTO image_tags (image_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		imageID, tagID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+			if pgErr.ConstraintName == "image_tags_image_id_fkey" {
+				return errortypes.NewErrImageNotFound(imageID)
+			}
+			return errortypes.NewErrTagNotFound(tagID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *PGRepository) RemoveImageTag(ctx context.Context, tagID uuid.UUID, imageID uuid.UUID) error {
+	if _, err := r.GetTag(ctx, tagID); err != nil {
+		return err
+	}
+
+	_, err := r.db.Exec(ctx, "DELETE FROM image_tags WHERE image_id = $1 AND tag_id = $2", imageID, tagID)
+	return err
+}
+
+func (r *PGRepository) ListTagImages(ctx context.Context, tagID uuid.UUID, after *uuid.UUID, limit int) ([]models.Image, error) {
+	if _, err := r.GetTag(ctx, tagID); err != nil {
+		return nil, err
+	}
+
+	return r.listLinkedImages(ctx, "image_tags", "tag_id", tagID, after, limit)
+}
+
+func (r *PGRepository) CreateCollection(ctx context.Context, collection *models.Collection) (*models.Collection, error) {
+	if collection.ID == uuid.Nil {
+		collection.ID = uuid.Must(uuid.NewV7())
+	}
+
+	if err := r.db.QueryRow(ctx,
+		"INSERT INTO collections (id, name, description) VALUES ($1, $2, $3) RETURNING created_at, updated_at",
+		collection.ID, collection.Name, collection.Description).Scan(&collection.CreatedAt, &collection.UpdatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return nil, errortypes.NewErrCollectionAlreadyExists(collection.Name)
+		}
+		return nil, err
+	}
+
+	return collection, nil
+}
+
+func (r *PGRepository) ListCollections(ctx context.Context) ([]models.Collection, error) {
+	rows, err := r.db.Query(ctx, "SELECT id, name, description, created_at, updated_at FROM collections ORDER BY name ASC")
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Collection, error) {
+		collection := models.Collection{}
+		err := row.Scan(&collection.ID, &collection.Name, &collection.Description, &collection.CreatedAt, &collection.UpdatedAt)
+		return collection, err
+	})
+}
+
+func (r *PGRepository) GetCollection(ctx context.Context, id uuid.UUID) (*models.Collection, error) {
+	collection := models.Collection{}
+
+	if err := r.db.QueryRow(ctx, "SELECT id, name, description, created_at, updated_at FROM collections WHERE id = $1", id).
+		Scan(&collection.ID, &collection.Name, &collection.Description, &collection.CreatedAt, &collection.UpdatedAt); err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrCollectionNotFound(id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &collection, nil
+}
+
+func (r *PGRepository) UpdateCollection(ctx context.Context, collection *models.Collection) (*models.Collection, error) {
+	if err := r.db.QueryRow(ctx,
+		"UPDATE collections SET name = $2, description = $3, updated_at = now() WHERE id = $1 RETURNING created_at, updated_at",
+		collection.ID, collection.Name, collection.Description).Scan(&collection.CreatedAt, &collection.UpdatedAt); err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrCollectionNotFound(collection.ID)
+	} else if isUniqueViolation(err) {
+		return nil, errortypes.NewErrCollectionAlreadyExists(collection.Name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return collection, nil
+}
+
+func (r *PGRepository) DeleteCollection(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, "DELETE FROM collections WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return errortypes.NewErrCollectionNotFound(id)
+	}
+
+	return nil
+}
+
+func (r *PGRepository) AddCollectionImage(ctx context.Context, collectionID uuid.UUID, imageID uuid.UUID) error {
+	if _, err := r.db.Exec(ctx,
+		"INSERT INTO collection_images (collection_id, image_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		collectionID, imageID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+			if pgErr.ConstraintName == "collection_images_image_id_fkey" {
+				return errortypes.NewErrImageNotFound(imageID)
+			}
+			return errortypes.NewErrCollectionNotFound(collectionID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *PGRepository) RemoveCollectionImage(ctx context.Context, collectionID uuid.UUID, imageID uuid.UUID) error {
+	if _, err := r.GetCollection(ctx, collectionID); err != nil {
+		return err
+	}
+
+	_, err := r.db.Exec(ctx, "DELETE FROM collection_images WHERE collection_id = $1 AND image_id = $2", collectionID, imageID)
+	return err
+}
+
+func (r *PGRepository) ListCollectionImages(ctx context.Context, collectionID uuid.UUID, after *uuid.UUID, limit int) ([]models.Image, error) {
+	if _, err := r.GetCollection(ctx, collectionID); err != nil {
+		return nil, err
+	}
+
+	return r.listLinkedImages(ctx, "collection_images", "collection_id", collectionID, after, limit)
+}
+
+// listLinkedImages pages through the images linked to ownerID in a link table, ordered by image ID.
+func (r *PGRepository) listLinkedImages(ctx context.Context, table string, ownerColumn string, ownerID uuid.UUID, after *uuid.UUID, limit int) ([]models.Image, error) {
+	args := []any{ownerID, limit}
+	conditions := []string{"l." + ownerColumn + " = $1"}
+	if after != nil {
+		args = append(args, *after)
+		conditions = append(conditions, "i.id > $3")
+	}
+
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf("SELECT %s FROM %s l JOIN images i ON l.image_id = i.id WHERE %s ORDER BY i.id ASC LIMIT $2",
+			imagerepository.ImageColumns("i."), table, strings.Join(conditions, " AND ")),
+		args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Image, error) {
+		image := models.Image{}
+		err := row.Scan(imagerepository.ImageDest(&image)...)
+		return image, err
+	})
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation
+}
@@ -0,0 +1,200 @@
+package libraryservice
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/library/libraryrepository"
+	"github.com/yckao/image-search-demo-go/services/storage/storageservice"
+)
+
+// Service organizes the image library into tags and collections. Both link to images many to
+// many, and searches can be scoped by them through models.SearchFilters.
+type Service interface {
+	CreateTag(ctx context.Context, name string) (*models.Tag, error)
+	ListTags(ctx context.Context) ([]models.Tag, error)
+	GetTag(ctx context.Context, id uuid.UUID) (*models.Tag, error)
+	UpdateTag(ctx context.Context, id uuid.UUID, name string) (*models.Tag, error)
+	DeleteTag(ctx context.Context, id uuid.UUID) error
+	TagImage(ctx context.Context, tagID uuid.UUID, imageID uuid.UUID) error
+	UntagImage(ctx context.Context, tagID uuid.UUID, imageID uuid.UUID) error
+	ListTagImages(ctx context.Context, tagID uuid.UUID, after *uuid.UUID, limit int) (*models.ImageList, error)
+	CreateCollection(ctx context.Context, name string, description string) (*models.Collection, error)
+	ListCollections(ctx context.Context) ([]models.Collection, error)
+	GetCollection(ctx context.Context, id uuid.UUID) (*models.Collection, error)
+	UpdateCollection(ctx context.Context, id uuid.UUID, name string, description string) (*models.Collection, error)
+	DeleteCollection(ctx context.Context, id uuid.UUID) error
+	AddCollectionImage(ctx context.Context, collectionID uuid.UUID, imageID uuid.UUID) error
+	RemoveCollectionImage(ctx context.Context, collectionID uuid.UUID, imageID uuid.UUID) error
+	ListCollectionImages(ctx context.Context, collectionID uuid.UUID, after *uuid.UUID, limit int) (*models.ImageList, error)
+}
+
+const (
+	MaxTagNameLength        = 100
+	MaxCollectionNameLength = 255
+
+	DefaultImageListLimit = 50
+	MaxImageListLimit     = 500
+)
+
+type libraryService struct {
+	logger            log.Logger
+	storageService    storageservice.Service
+	libraryRepository libraryrepository.Repository
+}
+
+func New(logger log.Logger, storageService storageservice.Service, libraryRepository libraryrepository.Repository) Service {
+	return &libraryService{
+		logger:            logger,
+		storageService:    storageService,
+		libraryRepository: libraryRepository,
+	}
+}
+
+func (s *libraryService) CreateTag(ctx context.Context, name string) (*models.Tag, error) {
+	name, err := validateName("name", name, MaxTagNameLength)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.libraryRepository.CreateTag(ctx, &models.Tag{Name: name})
+}
+
+func (s *libraryService) ListTags(ctx context.Context) ([]models.Tag, error) {
+	return s.libraryRepository.ListTags(ctx)
+}
+
+func (s *libraryService) GetTag(ctx context.Context, id uuid.UUID) (*models.Tag, error) {
+	return s.libraryRepository.GetTag(ctx, id)
+}
+
+func (s *libraryService) UpdateTag(ctx context.Context, id uuid.UUID, name string) (*models.Tag, error) {
+	name, err := validateName("name", name, MaxTagNameLength)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.libraryRepository.UpdateTag(ctx, &models.Tag{ID: id, Name: name})
+}
+
+func (s *libraryService) DeleteTag(ctx context.Context, id uuid.UUID) error {
+	return s.libraryRepository.DeleteTag(ctx, id)
+}
+
+func (s *libraryService) TagImage(ctx context.Context, tagID uuid.UUID, imageID uuid.UUID) error {
+	return s.libraryRepository.AddImageTag(ctx, tagID, imageID)
+}
+
+func (s *libraryService) UntagImage(ctx context.Context, tagID uuid.UUID, imageID uuid.UUID) error {
+	return s.libraryRepository.RemoveImageTag(ctx, tagID, imageID)
+}
+
+func (s *libraryService) ListTagImages(ctx context.Context, tagID uuid.UUID, after *uuid.UUID, limit int) (*models.ImageList, error) {
+	limit, err := validateLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := s.libraryRepository.ListTagImages(ctx, tagID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.imageList(ctx, images, limit)
+}
+
+func (s *libraryService) CreateCollection(ctx context.Context, name string, description string) (*models.Collection, error) {
+	name, err := validateName("name", name, MaxCollectionNameLength)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.libraryRepository.CreateCollection(ctx, &models.Collection{Name: name, Description: description})
+}
+
+func (s *libraryService) ListCollections(ctx context.Context) ([]models.Collection, error) {
+	return s.libraryRepository.ListCollections(ctx)
+}
+
+func (s *libraryService) GetCollection(ctx context.Context, id uuid.UUID) (*models.Collection, error) {
+	return s.libraryRepository.GetCollection(ctx, id)
+}
+
+func (s *libraryService) UpdateCollection(ctx context.Context, id uuid.UUID, name string, description string) (*models.Collection, error) {
+	name, err := validateName("name", name, MaxCollectionNameLength)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.libraryRepository.UpdateCollection(ctx, &models.Collection{ID: id, Name: name, Description: description})
+}
+
+func (s *libraryService) DeleteCollection(ctx context.Context, id uuid.UUID) error {
+	return s.libraryRepository.DeleteCollection(ctx, id)
+}
+
+func (s *libraryService) AddCollectionImage(ctx context.Context, collectionID uuid.UUID, imageID uuid.UUID) error {
+	return s.libraryRepository.AddCollectionImage(ctx, collectionID, imageID)
+}
+
+func (s *libraryService) RemoveCollectionImage(ctx context.Context, collectionID uuid.UUID, imageID uuid.UUID) error {
+	return s.libraryRepository.RemoveCollectionImage(ctx, collectionID, imageID)
+}
+
+func (s *libraryService) ListCollectionImages(ctx context.Context, collectionID uuid.UUID, after *uuid.UUID, limit int) (*models.ImageList, error) {
+	limit, err := validateLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := s.libraryRepository.ListCollectionImages(ctx, collectionID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.imageList(ctx, images, limit)
+}
+
+func (s *libraryService) imageList(ctx context.Context, images []models.Image, limit int) (*models.ImageList, error) {
+	for i := range images {
+		url, err := s.storageService.FormatURL(ctx, &models.StorageFile{
+			Provider: images[i].StorageProvider,
+			Key:      images[i].StorageKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		images[i].URL = url
+	}
+
+	list := &models.ImageList{Images: images}
+	if len(images) == limit {
+		list.NextAfter = &images[len(images)-1].ID
+	}
+
+	return list, nil
+}
+
+func validateName(parameter string, name string, maxLength int) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxLength {
+		return "", errortypes.NewErrInvalidLibraryParameter(parameter, fmt.Sprintf("must be between 1 and %d characters", maxLength))
+	}
+	return name, nil
+}
+
+func validateLimit(limit int) (int, error) {
+	if limit == 0 {
+		return DefaultImageListLimit, nil
+	}
+	if limit < 0 || limit > MaxImageListLimit {
+		return 0, errortypes.NewErrInvalidLibraryParameter("limit", fmt.Sprintf("must be between 1 and %d", MaxImageListLimit))
+	}
+	return limit, nil
+}
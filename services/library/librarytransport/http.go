@@ -0,0 +1,507 @@
+package librarytransport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/services/library/libraryendpoint"
+)
+
+func NewHTTPHandler(svc libraryendpoint.Endpoints, logger log.Logger) http.Handler {
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(errortypes.ErrorEncoder),
+		httptransport.ServerErrorLogger(logger),
+	}
+
+	m := http.NewServeMux()
+
+	m.Handle("POST /tags", httptransport.NewServer(
+		svc.CreateTagEndpoint,
+		decodeCreateTagRequest,
+		encodeCreateTagResponse,
+		options...,
+	))
+
+	m.Handle("GET /tags", httptransport.NewServer(
+		svc.ListTagsEndpoint,
+		decodeListTagsRequest,
+		encodeListTagsResponse,
+		options...,
+	))
+
+	m.Handle("GET /tags/{id}", httptransport.NewServer(
+		svc.GetTagEndpoint,
+		decodeGetTagRequest,
+		encodeGetTagResponse,
+		options...,
+	))
+
+	m.Handle("PUT /tags/{id}", httptransport.NewServer(
+		svc.UpdateTagEndpoint,
+		decodeUpdateTagRequest,
+		encodeUpdateTagResponse,
+		options...,
+	))
+
+	m.Handle("DELETE /tags/{id}", httptransport.NewServer(
+		svc.DeleteTagEndpoint,
+		decodeDeleteTagRequest,
+		encodeNoContentResponse,
+		options...,
+	))
+
+	m.Handle("GET /tags/{id}/images", httptransport.NewServer(
+		svc.ListTagImagesEndpoint,
+		decodeListTagImagesRequest,
+		encodeListTagImagesResponse,
+		options...,
+	))
+
+	m.Handle("PUT /tags/{id}/images/{image_id}", httptransport.NewServer(
+		svc.TagImageEndpoint,
+		decodeTagImageRequest,
+		encodeNoContentResponse,
+		options...,
+	))
+
+	m.Handle("DELETE /tags/{id}/images/{image_id}", httptransport.NewServer(
+		svc.UntagImageEndpoint,
+		decodeUntagImageRequest,
+		encodeNoContentResponse,
+		options...,
+	))
+
+	m.Handle("POST /collections", httptransport.NewServer(
+		svc.CreateCollectionEndpoint,
+		decodeCreateCollectionRequest,
+		encodeCreateCollectionResponse,
+		options...,
+	))
+
+	m.Handle("GET /collections", httptransport.NewServer(
+		svc.ListCollectionsEndpoint,
+		decodeListCollectionsRequest,
+		encodeListCollectionsResponse,
+		options...,
+	))
+
+	m.Handle("GET /collections/{id}", httptransport.NewServer(
+		svc.GetCollectionEndpoint,
+		decodeGetCollectionRequest,
+		encodeGetCollectionResponse,
+		options...,
+	))
+
+	m.Handle("PUT /collections/{id}", httptransport.NewServer(
+		svc.UpdateCollectionEndpoint,
+		decodeUpdateCollectionRequest,
+		encodeUpdateCollectionResponse,
+		options...,
+	))
+
+	m.Handle("DELETE /collections/{id}", httptransport.NewServer(
+		svc.DeleteCollectionEndpoint,
+		decodeDeleteCollectionRequest,
+		encodeNoContentResponse,
+		options...,
+	))
+
+	m.Handle("GET /collections/{id}/images", httptransport.NewServer(
+		svc.ListCollectionImagesEndpoint,
+		decodeListCollectionImagesRequest,
+		encodeListCollectionImagesResponse,
+		options...,
+	))
+
+	m.Handle("PUT /collections/{id}/images/{image_id}", httptransport.NewServer(
+		svc.AddCollectionImageEndpoint,
+		decodeAddCollectionImageRequest,
+		encodeNoContentResponse,
+		options...,
+	))
+
+	m.Handle("DELETE /collections/{id}/images/{image_id}", httptransport.NewServer(
+		svc.RemoveCollectionImageEndpoint,
+		decodeRemoveCollectionImageRequest,
+		encodeNoContentResponse,
+		options...,
+	))
+
+	return m
+}
+
+type tagRequest struct {
+	Name string `json:"name"`
+}
+
+func decodeCreateTagRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var body tagRequest
+	if err := decodeJSONBody(r, &body); err != nil {
+		return nil, err
+	}
+
+	return libraryendpoint.CreateTagRequest{
+		Name: body.Name,
+	}, nil
+}
+
+func encodeCreateTagResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(libraryendpoint.CreateTagResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeListTagsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return libraryendpoint.ListTagsRequest{}, nil
+}
+
+func encodeListTagsResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(libraryendpoint.ListTagsResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeGetTagRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	return libraryendpoint.GetTagRequest{
+		ID: id,
+	}, nil
+}
+
+func encodeGetTagResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(libraryendpoint.GetTagResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeUpdateTagRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	var body tagRequest
+	if err := decodeJSONBody(r, &body); err != nil {
+		return nil, err
+	}
+
+	return libraryendpoint.UpdateTagRequest{
+		ID:   id,
+		Name: body.Name,
+	}, nil
+}
+
+func encodeUpdateTagResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(libraryendpoint.UpdateTagResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeDeleteTagRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	return libraryendpoint.DeleteTagRequest{
+		ID: id,
+	}, nil
+}
+
+func decodeListTagImagesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	after, limit, err := decodeImageListPage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return libraryendpoint.ListTagImagesRequest{
+		TagID: id,
+		After: after,
+		Limit: limit,
+	}, nil
+}
+
+func encodeListTagImagesResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(libraryendpoint.ListTagImagesResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeTagImageRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, imageID, err := decodeImageLink(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return libraryendpoint.TagImageRequest{
+		TagID:   id,
+		ImageID: imageID,
+	}, nil
+}
+
+func decodeUntagImageRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, imageID, err := decodeImageLink(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return libraryendpoint.UntagImageRequest{
+		TagID:   id,
+		ImageID: imageID,
+	}, nil
+}
+
+type collectionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func decodeCreateCollectionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var body collectionRequest
+	if err := decodeJSONBody(r, &body); err != nil {
+		return nil, err
+	}
+
+	return libraryendpoint.CreateCollectionRequest{
+		Name:        body.Name,
+		Description: body.Description,
+	}, nil
+}
+
+func encodeCreateCollectionResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(libraryendpoint.CreateCollectionResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeListCollectionsRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return libraryendpoint.ListCollectionsRequest{}, nil
+}
+
+func encodeListCollectionsResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(libraryendpoint.ListCollectionsResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeGetCollectionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	return libraryendpoint.GetCollectionRequest{
+		ID: id,
+	}, nil
+}
+
+func encodeGetCollectionResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(libraryendpoint.GetCollectionResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeUpdateCollectionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	var body collectionRequest
+	if err := decodeJSONBody(r, &body); err != nil {
+		return nil, err
+	}
+
+	return libraryendpoint.UpdateCollectionRequest{
+		ID:          id,
+		Name:        body.Name,
+		Description: body.Description,
+	}, nil
+}
+
+func encodeUpdateCollectionResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(libraryendpoint.UpdateCollectionResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeDeleteCollectionRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	return libraryendpoint.DeleteCollectionRequest{
+		ID: id,
+	}, nil
+}
+
+func decodeListCollectionImagesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	after, limit, err := decodeImageListPage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return libraryendpoint.ListCollectionImagesRequest{
+		CollectionID: id,
+		After:        after,
+		Limit:        limit,
+	}, nil
+}
+
+func encodeListCollectionImagesResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(libraryendpoint.ListCollectionImagesResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeAddCollectionImageRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, imageID, err := decodeImageLink(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return libraryendpoint.AddCollectionImageRequest{
+		CollectionID: id,
+		ImageID:      imageID,
+	}, nil
+}
+
+func decodeRemoveCollectionImageRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, imageID, err := decodeImageLink(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return libraryendpoint.RemoveCollectionImageRequest{
+		CollectionID: id,
+		ImageID:      imageID,
+	}, nil
+}
+
+// encodeNoContentResponse answers endpoints that return nothing but an error.
+func encodeNoContentResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if err := response.(endpoint.Failer).Failed(); err != nil {
+		errortypes.ErrorEncoder(ctx, err, w)
+		return nil
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func decodeJSONBody(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return errortypes.NewErrInvalidLibraryParameter("body", "must be a JSON object")
+	}
+	return nil
+}
+
+func decodeImageLink(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	imageID, err := uuid.Parse(r.PathValue("image_id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("failed to parse image_id: %w", err)
+	}
+
+	return id, imageID, nil
+}
+
+func decodeImageListPage(r *http.Request) (*uuid.UUID, int, error) {
+	var after *uuid.UUID
+	if v := r.URL.Query().Get("after"); v != "" {
+		parsed, err := uuid.Parse(v)
+		if err != nil {
+			return nil, 0, errortypes.NewErrInvalidLibraryParameter("after", "must be an image id")
+		}
+		after = &parsed
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, 0, errortypes.NewErrInvalidLibraryParameter("limit", "must be an integer")
+		}
+		limit = parsed
+	}
+
+	return after, limit, nil
+}
@@ -0,0 +1,14 @@
+package outboxrepository
+
+import (
+	"context"
+	"time"
+
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+type Repository interface {
+	ClaimMessages(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMessage, error)
+	UpdateMessage(ctx context.Context, message *models.OutboxMessage) error
+	DeletePublishedMessages(ctx context.Context, before time.Time) (int64, error)
+}
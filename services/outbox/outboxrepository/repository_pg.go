@@ -0,0 +1,67 @@
+package outboxrepository
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+type PGRepository struct {
+	logger log.Logger
+	db     *pgxpool.Pool
+}
+
+func NewPGRepository(logger log.Logger, db *pgxpool.Pool) Repository {
+	return &PGRepository{logger: logger, db: db}
+}
+
+// ClaimMessages takes up to limit due messages that are the oldest unpublished message of their
+// aggregate, and pushes their next attempt out by lease so that a message whose outcome is never
+// recorded is published again once the lease runs out. While a message is unpublished the later
+// messages of its aggregate are not claimed, which keeps each aggregate in order. SKIP LOCKED keeps
+// concurrent relays from claiming the same message.
+func (r *PGRepository) ClaimMessages(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	rows, err := r.db.Query(ctx,
+		`UPDATE outbox SET next_attempt_at = now() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT o.id FROM outbox o
+			WHERE o.published_at IS NULL AND o.next_attempt_at <= now() AND NOT EXISTS (
+				SELECT 1 FROM outbox p
+				WHERE p.aggregate_type = o.aggregate_type AND p.aggregate_id = o.aggregate_id AND p.published_at IS NULL AND p.id < o.id
+			)
+			ORDER BY o.id ASC LIMIT $1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_id, event_type, aggregate_type, aggregate_id, payload, attempts, last_error, next_attempt_at, published_at, created_at`,
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.OutboxMessage, error) {
+		message := models.OutboxMessage{}
+		var eventType string
+		err := row.Scan(&message.ID, &message.EventID, &eventType, &message.AggregateType, &message.AggregateID, &message.Payload, &message.Attempts, &message.LastError, &message.NextAttemptAt, &message.PublishedAt, &message.CreatedAt)
+		message.EventType = models.EventType(eventType)
+		return message, err
+	})
+}
+
+func (r *PGRepository) UpdateMessage(ctx context.Context, message *models.OutboxMessage) error {
+	_, err := r.db.Exec(ctx,
+		"UPDATE outbox SET attempts = $2, last_error = $3, next_attempt_at = COALESCE($4, next_attempt_at), published_at = $5 WHERE id = $1",
+		message.ID, message.Attempts, message.LastError, message.NextAttemptAt, message.PublishedAt)
+	return err
+}
+
+func (r *PGRepository) DeletePublishedMessages(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, "DELETE FROM outbox WHERE published_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,92 @@
+package outboxservice
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+const (
+	DefaultNATSSubjectPrefix = "events"
+	DefaultNATSStream        = "EVENTS"
+
+	natsPublishTimeout = 5 * time.Second
+)
+
+type natsSink struct {
+	js            jetstream.JetStream
+	subjectPrefix string
+}
+
+// NewNATSSink publishes every message to JetStream on <subjectPrefix>.<event type>, for example
+// events.image.created, with the event ID as Nats-Msg-Id so that the stream drops a message the
+// relay hands over again within its duplicate window. A message counts as published once the
+// stream has acknowledged storing it, so the subjects must be captured by a stream; see
+// CreateNATSStream.
+func NewNATSSink(js jetstream.JetStream, subjectPrefix string) Sink {
+	return &natsSink{js: js, subjectPrefix: subjectPrefix}
+}
+
+func (s *natsSink) Publish(ctx context.Context, message *models.OutboxMessage) error {
+	msg := nats.NewMsg(s.subjectPrefix + "." + string(message.EventType))
+	msg.Data = message.Payload
+	msg.Header.Set(jetstream.MsgIDHeader, message.EventID.String())
+
+	ctx, cancel := context.WithTimeout(ctx, natsPublishTimeout)
+	defer cancel()
+
+	_, err := s.js.PublishMsg(ctx, msg)
+	return err
+}
+
+// CreateNATSStream creates the stream that stores the messages published on subjectPrefix, or
+// updates it to capture them if it exists.
+func CreateNATSStream(ctx context.Context, js jetstream.JetStream, name string, subjectPrefix string) error {
+	_, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     name,
+		Subjects: []string{subjectPrefix + ".>"},
+	})
+	return err
+}
+
+// StartEmbeddedNATS runs a NATS server with JetStream in process for local use, listening on
+// addr. Streams are stored in storeDir, or a directory under the system's temporary directory
+// if it is empty.
+func StartEmbeddedNATS(addr string, storeDir string) (*server.Server, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, err := server.NewServer(&server.Options{
+		Host:      host,
+		Port:      portNumber,
+		NoLog:     true,
+		NoSigs:    true,
+		JetStream: true,
+		StoreDir:  storeDir,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go ns.Start()
+
+	if !ns.ReadyForConnections(10 * time.Second) {
+		ns.Shutdown()
+		return nil, errors.New("embedded NATS server did not start")
+	}
+
+	return ns, nil
+}
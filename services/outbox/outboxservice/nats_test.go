@@ -0,0 +1,61 @@
+package outboxservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+func TestNATSSinkPublish(t *testing.T) {
+	ns, err := StartEmbeddedNATS("127.0.0.1:-1", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ns.Shutdown()
+
+	conn, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	sink := NewNATSSink(js, DefaultNATSSubjectPrefix)
+	message := &models.OutboxMessage{EventID: uuid.New(), EventType: models.EventTypeImageCreated, Payload: []byte(`{}`)}
+
+	if err := sink.Publish(ctx, message); err == nil {
+		t.Error("Publish() without a stream = nil, want an error since nothing stored the message")
+	}
+
+	if err := CreateNATSStream(ctx, js, DefaultNATSStream, DefaultNATSSubjectPrefix); err != nil {
+		t.Fatalf("CreateNATSStream() error = %v", err)
+	}
+
+	// The relay hands a message over again when it could not record that it was published.
+	for range 2 {
+		if err := sink.Publish(ctx, message); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	stream, err := js.Stream(ctx, DefaultNATSStream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 1 {
+		t.Errorf("stream holds %d messages, want the event once", info.State.Msgs)
+	}
+}
@@ -0,0 +1,141 @@
+package outboxservice
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/pkg/retry"
+	"github.com/yckao/image-search-demo-go/services/outbox/outboxrepository"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	DefaultBatchSize    = 100
+	DefaultPollInterval = time.Second
+	DefaultBaseBackoff  = time.Second
+	DefaultMaxBackoff   = 5 * time.Minute
+	DefaultConcurrency  = 8
+	DefaultRetention    = 7 * 24 * time.Hour
+
+	// claimLease must outlast publishing a whole batch to slow sinks, or messages still being
+	// published would be claimed again.
+	claimLease    = 5 * time.Minute
+	pruneInterval = time.Hour
+)
+
+type RelayConfig struct {
+	// BatchSize is how many messages are claimed at a time.
+	BatchSize int
+	// PollInterval is how often the outbox is looked at when the last batch was not full.
+	PollInterval time.Duration
+	// Backoff spaces out the attempts to publish a message, which is retried until it is published.
+	Backoff retry.Backoff
+	// Concurrency bounds how many messages, each of a different aggregate, are published at once.
+	Concurrency int
+	// Retention is how long published messages are kept. Zero keeps them forever.
+	Retention time.Duration
+}
+
+// Relay publishes the messages of the outbox to its sinks. A message is only marked published
+// once every sink has accepted it, so delivery is at least once and sinks should deduplicate by
+// event ID.
+type Relay struct {
+	logger           log.Logger
+	config           RelayConfig
+	outboxRepository outboxrepository.Repository
+	sinks            []Sink
+	poller           *retry.Poller
+}
+
+func NewRelay(logger log.Logger, config RelayConfig, outboxRepository outboxrepository.Repository, sinks ...Sink) *Relay {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	config.Backoff = config.Backoff.WithDefaults(retry.Backoff{Base: DefaultBaseBackoff, Max: DefaultMaxBackoff})
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultConcurrency
+	}
+
+	return &Relay{
+		logger:           logger,
+		config:           config,
+		outboxRepository: outboxRepository,
+		sinks:            sinks,
+		poller:           retry.NewPoller(config.PollInterval),
+	}
+}
+
+// Run publishes messages until ctx is done.
+func (r *Relay) Run(ctx context.Context) error {
+	var pruned time.Time
+	return r.poller.Run(ctx, r.config.BatchSize, func(ctx context.Context) int {
+		n, err := r.relay(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			r.logger.Log("outbox", "relay", "error", err)
+		}
+
+		if r.config.Retention > 0 && time.Since(pruned) >= pruneInterval {
+			pruned = time.Now()
+			if _, err := r.outboxRepository.DeletePublishedMessages(ctx, pruned.Add(-r.config.Retention)); err != nil {
+				r.logger.Log("outbox", "prune", "error", err)
+			}
+		}
+
+		return n
+	})
+}
+
+// relay publishes a batch of messages. A claimed batch holds at most one message per aggregate,
+// so the messages can be published concurrently without reordering an aggregate.
+func (r *Relay) relay(ctx context.Context) (int, error) {
+	messages, err := r.outboxRepository.ClaimMessages(ctx, r.config.BatchSize, claimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	group := errgroup.Group{}
+	group.SetLimit(r.config.Concurrency)
+	for i := range messages {
+		group.Go(func() error {
+			r.publish(ctx, &messages[i])
+			return nil
+		})
+	}
+	group.Wait()
+
+	return len(messages), nil
+}
+
+func (r *Relay) publish(ctx context.Context, message *models.OutboxMessage) {
+	message.Attempts++
+
+	var err error
+	for _, sink := range r.sinks {
+		if err = sink.Publish(ctx, message); err != nil {
+			break
+		}
+	}
+
+	now := time.Now()
+	if err == nil {
+		message.LastError = nil
+		message.NextAttemptAt = nil
+		message.PublishedAt = &now
+	} else {
+		errMessage := err.Error()
+		next := now.Add(r.config.Backoff.Delay(message.Attempts))
+		message.LastError = &errMessage
+		message.NextAttemptAt = &next
+		r.logger.Log("outbox", message.ID, "event", message.EventID, "attempt", message.Attempts, "error", err)
+	}
+
+	if err := r.outboxRepository.UpdateMessage(ctx, message); err != nil {
+		r.logger.Log("outbox", message.ID, "event", message.EventID, "error", err)
+	}
+}
@@ -0,0 +1,70 @@
+package outboxservice
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+// Sink receives the messages of the outbox. A message may be published more than once.
+type Sink interface {
+	Publish(ctx context.Context, message *models.OutboxMessage) error
+}
+
+const (
+	DefaultHTTPSinkTimeout = 10 * time.Second
+
+	EventIDHeader   = "X-Event-Id"
+	EventTypeHeader = "X-Event-Type"
+)
+
+type httpSink struct {
+	client *http.Client
+	url    string
+}
+
+// NewHTTPSink posts the payload of every message as JSON to url. Any response other than 2xx
+// fails the message.
+func NewHTTPSink(client *http.Client, url string) Sink {
+	return &httpSink{client: client, url: url}
+}
+
+func (s *httpSink) Publish(ctx context.Context, message *models.OutboxMessage) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(message.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set(EventIDHeader, message.EventID.String())
+	req.Header.Set(EventTypeHeader, string(message.EventType))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sink responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+type logSink struct {
+	logger log.Logger
+}
+
+// NewLogSink logs every message instead of publishing it.
+func NewLogSink(logger log.Logger) Sink {
+	return &logSink{logger: logger}
+}
+
+func (s *logSink) Publish(ctx context.Context, message *models.OutboxMessage) error {
+	return s.logger.Log("outbox", message.ID, "event", message.EventID, "type", message.EventType, "aggregate", message.AggregateType, "aggregate_id", message.AggregateID)
+}
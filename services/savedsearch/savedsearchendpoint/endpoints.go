@@ -0,0 +1,294 @@
+package savedsearchendpoint
+
+import (
+	"context"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/savedsearch/savedsearchservice"
+)
+
+type Endpoints struct {
+	logger                         log.Logger
+	CreateSavedSearchEndpoint      endpoint.Endpoint
+	ListSavedSearchesEndpoint      endpoint.Endpoint
+	GetSavedSearchEndpoint         endpoint.Endpoint
+	UpdateSavedSearchEndpoint      endpoint.Endpoint
+	DeleteSavedSearchEndpoint      endpoint.Endpoint
+	ListSavedSearchMatchesEndpoint endpoint.Endpoint
+}
+
+func New(svc savedsearchservice.Service, logger log.Logger) Endpoints {
+	var createSavedSearchEndpoint endpoint.Endpoint
+	{
+		createSavedSearchEndpoint = MakeCreateSavedSearchEndpoint(svc)
+	}
+
+	var listSavedSearchesEndpoint endpoint.Endpoint
+	{
+		listSavedSearchesEndpoint = MakeListSavedSearchesEndpoint(svc)
+	}
+
+	var getSavedSearchEndpoint endpoint.Endpoint
+	{
+		getSavedSearchEndpoint = MakeGetSavedSearchEndpoint(svc)
+	}
+
+	var updateSavedSearchEndpoint endpoint.Endpoint
+	{
+		updateSavedSearchEndpoint = MakeUpdateSavedSearchEndpoint(svc)
+	}
+
+	var deleteSavedSearchEndpoint endpoint.Endpoint
+	{
+		deleteSavedSearchEndpoint = MakeDeleteSavedSearchEndpoint(svc)
+	}
+
+	var listSavedSearchMatchesEndpoint endpoint.Endpoint
+	{
+		listSavedSearchMatchesEndpoint = MakeListSavedSearchMatchesEndpoint(svc)
+	}
+
+	return Endpoints{
+		logger:                         logger,
+		CreateSavedSearchEndpoint:      createSavedSearchEndpoint,
+		ListSavedSearchesEndpoint:      listSavedSearchesEndpoint,
+		GetSavedSearchEndpoint:         getSavedSearchEndpoint,
+		UpdateSavedSearchEndpoint:      updateSavedSearchEndpoint,
+		DeleteSavedSearchEndpoint:      deleteSavedSearchEndpoint,
+		ListSavedSearchMatchesEndpoint: listSavedSearchMatchesEndpoint,
+	}
+}
+
+func MakeCreateSavedSearchEndpoint(svc savedsearchservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateSavedSearchRequest)
+		resp, err := svc.CreateSavedSearch(ctx, req.Name, req.Query, req.MinScore, req.CallbackURL)
+		return CreateSavedSearchResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeListSavedSearchesEndpoint(svc savedsearchservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		resp, err := svc.ListSavedSearches(ctx)
+		return ListSavedSearchesResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeGetSavedSearchEndpoint(svc savedsearchservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetSavedSearchRequest)
+		resp, err := svc.GetSavedSearch(ctx, req.ID)
+		return GetSavedSearchResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeUpdateSavedSearchEndpoint(svc savedsearchservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UpdateSavedSearchRequest)
+		resp, err := svc.UpdateSavedSearch(ctx, req.ID, req.Name, req.MinScore, req.CallbackURL)
+		return UpdateSavedSearchResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeDeleteSavedSearchEndpoint(svc savedsearchservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteSavedSearchRequest)
+		err := svc.DeleteSavedSearch(ctx, req.ID)
+		return DeleteSavedSearchResponse{
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeListSavedSearchMatchesEndpoint(svc savedsearchservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ListSavedSearchMatchesRequest)
+		resp, err := svc.ListSavedSearchMatches(ctx, req.ID, req.After, req.Limit)
+		return ListSavedSearchMatchesResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+var _ savedsearchservice.Service = (*Endpoints)(nil)
+
+func (e *Endpoints) CreateSavedSearch(ctx context.Context, name string, query models.SavedSearchQuery, minScore *float64, callbackURL string) (*models.SavedSearch, error) {
+	resp, err := e.CreateSavedSearchEndpoint(ctx, CreateSavedSearchRequest{
+		Name:        name,
+		Query:       query,
+		MinScore:    minScore,
+		CallbackURL: callbackURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(CreateSavedSearchResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) ListSavedSearches(ctx context.Context) ([]models.SavedSearch, error) {
+	resp, err := e.ListSavedSearchesEndpoint(ctx, ListSavedSearchesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(ListSavedSearchesResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) GetSavedSearch(ctx context.Context, id uuid.UUID) (*models.SavedSearch, error) {
+	resp, err := e.GetSavedSearchEndpoint(ctx, GetSavedSearchRequest{
+		ID: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(GetSavedSearchResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) UpdateSavedSearch(ctx context.Context, id uuid.UUID, name string, minScore *float64, callbackURL string) (*models.SavedSearch, error) {
+	resp, err := e.UpdateSavedSearchEndpoint(ctx, UpdateSavedSearchRequest{
+		ID:          id,
+		Name:        name,
+		MinScore:    minScore,
+		CallbackURL: callbackURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(UpdateSavedSearchResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) DeleteSavedSearch(ctx context.Context, id uuid.UUID) error {
+	resp, err := e.DeleteSavedSearchEndpoint(ctx, DeleteSavedSearchRequest{
+		ID: id,
+	})
+	if err != nil {
+		return err
+	}
+	response := resp.(DeleteSavedSearchResponse)
+	return response.Err
+}
+
+func (e *Endpoints) ListSavedSearchMatches(ctx context.Context, id uuid.UUID, after *uuid.UUID, limit int) (*models.SavedSearchMatchList, error) {
+	resp, err := e.ListSavedSearchMatchesEndpoint(ctx, ListSavedSearchMatchesRequest{
+		ID:    id,
+		After: after,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(ListSavedSearchMatchesResponse)
+	return response.V, response.Err
+}
+
+var (
+	_ endpoint.Failer = CreateSavedSearchResponse{}
+	_ endpoint.Failer = ListSavedSearchesResponse{}
+	_ endpoint.Failer = GetSavedSearchResponse{}
+	_ endpoint.Failer = UpdateSavedSearchResponse{}
+	_ endpoint.Failer = DeleteSavedSearchResponse{}
+	_ endpoint.Failer = ListSavedSearchMatchesResponse{}
+)
+
+type CreateSavedSearchRequest struct {
+	Name        string
+	Query       models.SavedSearchQuery
+	MinScore    *float64
+	CallbackURL string
+}
+
+type CreateSavedSearchResponse struct {
+	V   *models.SavedSearch
+	Err error
+}
+
+func (r CreateSavedSearchResponse) Failed() error {
+	return r.Err
+}
+
+type ListSavedSearchesRequest struct{}
+
+type ListSavedSearchesResponse struct {
+	V   []models.SavedSearch
+	Err error
+}
+
+func (r ListSavedSearchesResponse) Failed() error {
+	return r.Err
+}
+
+type GetSavedSearchRequest struct {
+	ID uuid.UUID
+}
+
+type GetSavedSearchResponse struct {
+	V   *models.SavedSearch
+	Err error
+}
+
+func (r GetSavedSearchResponse) Failed() error {
+	return r.Err
+}
+
+type UpdateSavedSearchRequest struct {
+	ID          uuid.UUID
+	Name        string
+	MinScore    *float64
+	CallbackURL string
+}
+
+type UpdateSavedSearchResponse struct {
+	V   *models.SavedSearch
+	Err error
+}
+
+func (r UpdateSavedSearchResponse) Failed() error {
+	return r.Err
+}
+
+type DeleteSavedSearchRequest struct {
+	ID uuid.UUID
+}
+
+type DeleteSavedSearchResponse struct {
+	Err error
+}
+
+func (r DeleteSavedSearchResponse) Failed() error {
+	return r.Err
+}
+
+type ListSavedSearchMatchesRequest struct {
+	ID    uuid.UUID
+	After *uuid.UUID
+	Limit int
+}
+
+type ListSavedSearchMatchesResponse struct {
+	V   *models.SavedSearchMatchList
+	Err error
+}
+
+func (r ListSavedSearchMatchesResponse) Failed() error {
+	return r.Err
+}
@@ -0,0 +1,29 @@
+package savedsearchrepository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+type Repository interface {
+	CreateSavedSearch(ctx context.Context, savedSearch *models.SavedSearch, embedding []float32) (*models.SavedSearch, error)
+	ListSavedSearches(ctx context.Context) ([]models.SavedSearch, error)
+	GetSavedSearch(ctx context.Context, id uuid.UUID) (*models.SavedSearch, error)
+	UpdateSavedSearch(ctx context.Context, savedSearch *models.SavedSearch) (*models.SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, id uuid.UUID) error
+	GetImageEmbedding(ctx context.Context, imageID uuid.UUID, modelName string) (*models.Embedding, error)
+	GetSearchEmbedding(ctx context.Context, searchID uuid.UUID) (*models.Embedding, error)
+	CreateMatches(ctx context.Context, imageID uuid.UUID) (int, error)
+	ClaimMatchNotifications(ctx context.Context, limit int, lease time.Duration) ([]PendingNotification, error)
+	UpdateMatchNotification(ctx context.Context, match *models.SavedSearchMatch) error
+	ListMatches(ctx context.Context, savedSearchID uuid.UUID, after *uuid.UUID, limit int) ([]models.SavedSearchMatch, error)
+}
+
+// PendingNotification is a claimed match together with the saved search it is notified to.
+type PendingNotification struct {
+	models.SavedSearchMatch
+	SavedSearch models.SavedSearch
+}
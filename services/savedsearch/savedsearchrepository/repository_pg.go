@@ -0,0 +1,277 @@
+package savedsearchrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pgvector/pgvector-go"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/image/imagerepository"
+)
+
+type PGRepository struct {
+	logger log.Logger
+	db     *pgxpool.Pool
+}
+
+func NewPGRepository(logger log.Logger, db *pgxpool.Pool) Repository {
+	return &PGRepository{logger: logger, db: db}
+}
+
+func (r *PGRepository) CreateSavedSearch(ctx context.Context, savedSearch *models.SavedSearch, embedding []float32) (*models.SavedSearch, error) {
+	if savedSearch.ID == uuid.Nil {
+		savedSearch.ID = uuid.Must(uuid.NewV7())
+	}
+
+	if err := r.db.QueryRow(ctx,
+		"INSERT INTO saved_searches (id, name, model_name, query_type, query_text, query_image_id, query_search_id, query_embedding, min_score, callback_url) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING created_at, updated_at",
+		savedSearch.ID, savedSearch.Name, savedSearch.ModelName, string(savedSearch.QueryType), savedSearch.QueryText, savedSearch.QueryImageID, savedSearch.QuerySearchID, pgvector.NewVector(embedding), savedSearch.MinScore, savedSearch.CallbackURL).
+		Scan(&savedSearch.CreatedAt, &savedSearch.UpdatedAt); err != nil {
+		// The image or search the query was taken from may be deleted in the meantime.
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+			if pgErr.ConstraintName == "saved_searches_query_image_id_fkey" {
+				return nil, errortypes.NewErrImageNotFound(*savedSearch.QueryImageID)
+			}
+			return nil, errortypes.NewErrSearchQueryNotFound(*savedSearch.QuerySearchID)
+		}
+		return nil, err
+	}
+
+	return savedSearch, nil
+}
+
+func (r *PGRepository) ListSavedSearches(ctx context.Context) ([]models.SavedSearch, error) {
+	rows, err := r.db.Query(ctx, fmt.Sprintf("SELECT %s FROM saved_searches ORDER BY created_at ASC, id ASC", savedSearchColumns("")))
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanSavedSearch)
+}
+
+func (r *PGRepository) GetSavedSearch(ctx context.Context, id uuid.UUID) (*models.SavedSearch, error) {
+	rows, err := r.db.Query(ctx, fmt.Sprintf("SELECT %s FROM saved_searches WHERE id = $1", savedSearchColumns("")), id)
+	if err != nil {
+		return nil, err
+	}
+
+	savedSearch, err := pgx.CollectExactlyOneRow(rows, scanSavedSearch)
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrSavedSearchNotFound(id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &savedSearch, nil
+}
+
+func (r *PGRepository) UpdateSavedSearch(ctx context.Context, savedSearch *models.SavedSearch) (*models.SavedSearch, error) {
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf("UPDATE saved_searches SET name = $2, min_score = $3, callback_url = $4, updated_at = now() WHERE id = $1 RETURNING %s", savedSearchColumns("")),
+		savedSearch.ID, savedSearch.Name, savedSearch.MinScore, savedSearch.CallbackURL)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := pgx.CollectExactlyOneRow(rows, scanSavedSearch)
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrSavedSearchNotFound(savedSearch.ID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+func (r *PGRepository) DeleteSavedSearch(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, "DELETE FROM saved_searches WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return errortypes.NewErrSavedSearchNotFound(id)
+	}
+
+	return nil
+}
+
+func (r *PGRepository) GetImageEmbedding(ctx context.Context, imageID uuid.UUID, modelName string) (*models.Embedding, error) {
+	embedding := models.Embedding{}
+	var vector pgvector.Vector
+
+	if err := r.db.QueryRow(ctx,
+		"SELECT model_name, embedding FROM image_embeddings WHERE image_id = $1 AND model_name = $2 ORDER BY created_at DESC LIMIT 1", imageID, modelName).
+		Scan(&embedding.Model, &vector); err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrImageEmbeddingNotFound(imageID)
+	} else if err != nil {
+		return nil, err
+	}
+	embedding.Embedding = vector.Slice()
+
+	return &embedding, nil
+}
+
+func (r *PGRepository) GetSearchEmbedding(ctx context.Context, searchID uuid.UUID) (*models.Embedding, error) {
+	embedding := models.Embedding{}
+	var vector pgvector.Vector
+
+	if err := r.db.QueryRow(ctx,
+		"SELECT model_name, query_embedding FROM search_queries WHERE id = $1", searchID).
+		Scan(&embedding.Model, &vector); err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrSearchQueryNotFound(searchID)
+	} else if err != nil {
+		return nil, err
+	}
+	embedding.Embedding = vector.Slice()
+
+	return &embedding, nil
+}
+
+// CreateMatches records a match for every saved search that the latest embedding of the image
+// in its model reaches the threshold of, each due to be notified, and returns how many there are.
+// An image is only ever matched once per saved search. Saved searches are few enough to be
+// compared exhaustively.
+func (r *PGRepository) CreateMatches(ctx context.Context, imageID uuid.UUID) (int, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT s.id, 1 - (s.query_embedding <=> e.embedding) AS score FROM saved_searches s
+			JOIN LATERAL (
+				SELECT embedding FROM image_embeddings WHERE image_id = $1 AND model_name = s.model_name
+				ORDER BY created_at DESC LIMIT 1
+			) e ON true
+			WHERE 1 - (s.query_embedding <=> e.embedding) >= s.min_score ORDER BY s.created_at ASC, s.id ASC`,
+		imageID)
+	if err != nil {
+		return 0, err
+	}
+
+	matches, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SavedSearchMatch, error) {
+		match := models.SavedSearchMatch{ID: uuid.Must(uuid.NewV7())}
+		err := row.Scan(&match.SavedSearchID, &match.Score)
+		return match, err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Selecting the image again skips one deleted since it was matched.
+	batch := &pgx.Batch{}
+	for _, match := range matches {
+		batch.Queue("INSERT INTO saved_search_matches (id, saved_search_id, image_id, score, next_notify_at) SELECT $1, $2, id, $4, now() FROM images WHERE id = $3 ON CONFLICT (saved_search_id, image_id) DO NOTHING",
+			match.ID, match.SavedSearchID, imageID, match.Score)
+	}
+
+	if err := r.db.SendBatch(ctx, batch).Close(); err != nil {
+		return 0, err
+	}
+
+	return len(matches), nil
+}
+
+// ClaimMatchNotifications takes up to limit matches whose notification is due, oldest first, and
+// pushes their next attempt out by lease so that a notification whose outcome is never recorded,
+// because the process died mid-attempt, is retried once the lease runs out. SKIP LOCKED keeps
+// concurrent matchers from claiming the same match.
+func (r *PGRepository) ClaimMatchNotifications(ctx context.Context, limit int, lease time.Duration) ([]PendingNotification, error) {
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf(`UPDATE saved_search_matches m SET next_notify_at = now() + make_interval(secs => $2)
+			FROM saved_searches s, images i
+			WHERE s.id = m.saved_search_id AND i.id = m.image_id AND m.id IN (
+				SELECT id FROM saved_search_matches WHERE next_notify_at <= now()
+				ORDER BY next_notify_at ASC LIMIT $1 FOR UPDATE SKIP LOCKED
+			)
+			RETURNING %s, %s, %s`, matchColumns("m."), savedSearchColumns("s."), imagerepository.ImageColumns("i.")),
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (PendingNotification, error) {
+		pending := PendingNotification{}
+		var queryType string
+		dest := append(matchDest(&pending.SavedSearchMatch), savedSearchDest(&pending.SavedSearch, &queryType)...)
+		err := row.Scan(append(dest, imagerepository.ImageDest(&pending.Image)...)...)
+		pending.SavedSearch.QueryType = models.SavedSearchQueryType(queryType)
+		return pending, err
+	})
+}
+
+func (r *PGRepository) UpdateMatchNotification(ctx context.Context, match *models.SavedSearchMatch) error {
+	_, err := r.db.Exec(ctx,
+		"UPDATE saved_search_matches SET notify_attempts = $2, notified_at = $3, notify_error = $4, next_notify_at = $5 WHERE id = $1",
+		match.ID, match.NotifyAttempts, match.NotifiedAt, match.NotifyError, match.NextNotifyAt)
+	return err
+}
+
+// ListMatches pages through the matches of a saved search, oldest first.
+func (r *PGRepository) ListMatches(ctx context.Context, savedSearchID uuid.UUID, after *uuid.UUID, limit int) ([]models.SavedSearchMatch, error) {
+	if _, err := r.GetSavedSearch(ctx, savedSearchID); err != nil {
+		return nil, err
+	}
+
+	args := []any{savedSearchID, limit}
+	conditions := []string{"m.saved_search_id = $1"}
+	if after != nil {
+		args = append(args, *after)
+		conditions = append(conditions, "m.id > $3")
+	}
+
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf("SELECT %s, %s FROM saved_search_matches m JOIN images i ON m.image_id = i.id WHERE %s ORDER BY m.id ASC LIMIT $2",
+			matchColumns("m."), imagerepository.ImageColumns("i."), strings.Join(conditions, " AND ")),
+		args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SavedSearchMatch, error) {
+		match := models.SavedSearchMatch{}
+		err := row.Scan(append(matchDest(&match), imagerepository.ImageDest(&match.Image)...)...)
+		return match, err
+	})
+}
+
+// matchColumns lists the saved_search_matches columns read by matchDest, each prefixed with prefix.
+func matchColumns(prefix string) string {
+	columns := []string{"id", "saved_search_id", "score", "notify_attempts", "notified_at", "notify_error", "next_notify_at", "created_at"}
+	for i := range columns {
+		columns[i] = prefix + columns[i]
+	}
+	return strings.Join(columns, ", ")
+}
+
+func matchDest(match *models.SavedSearchMatch) []any {
+	return []any{&match.ID, &match.SavedSearchID, &match.Score, &match.NotifyAttempts, &match.NotifiedAt, &match.NotifyError, &match.NextNotifyAt, &match.CreatedAt}
+}
+
+// savedSearchColumns lists the saved_searches columns read by savedSearchDest, each prefixed with prefix.
+func savedSearchColumns(prefix string) string {
+	columns := []string{"id", "name", "model_name", "query_type", "query_text", "query_image_id", "query_search_id", "min_score", "callback_url", "created_at", "updated_at"}
+	for i := range columns {
+		columns[i] = prefix + columns[i]
+	}
+	return strings.Join(columns, ", ")
+}
+
+func savedSearchDest(savedSearch *models.SavedSearch, queryType *string) []any {
+	return []any{&savedSearch.ID, &savedSearch.Name, &savedSearch.ModelName, queryType, &savedSearch.QueryText, &savedSearch.QueryImageID, &savedSearch.QuerySearchID, &savedSearch.MinScore, &savedSearch.CallbackURL, &savedSearch.CreatedAt, &savedSearch.UpdatedAt}
+}
+
+func scanSavedSearch(row pgx.CollectableRow) (models.SavedSearch, error) {
+	savedSearch := models.SavedSearch{}
+	var queryType string
+	err := row.Scan(savedSearchDest(&savedSearch, &queryType)...)
+	savedSearch.QueryType = models.SavedSearchQueryType(queryType)
+	return savedSearch, err
+}
@@ -0,0 +1,173 @@
+package savedsearchservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/pkg/netguard"
+	"github.com/yckao/image-search-demo-go/pkg/retry"
+	"github.com/yckao/image-search-demo-go/services/savedsearch/savedsearchrepository"
+	"github.com/yckao/image-search-demo-go/services/storage/storageservice"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	DefaultNotifyMaxAttempts  = 8
+	DefaultNotifyBaseBackoff  = 30 * time.Second
+	DefaultNotifyMaxBackoff   = time.Hour
+	DefaultNotifyPollInterval = 5 * time.Second
+	DefaultNotifyConcurrency  = 8
+)
+
+type MatcherConfig struct {
+	// MaxAttempts is how many times a match is notified before it is given up on.
+	MaxAttempts int
+	// Backoff spaces out the attempts of a notification.
+	Backoff retry.Backoff
+	// PollInterval is how often due retries are looked for when nothing wakes the matcher.
+	PollInterval time.Duration
+	// Timeout bounds a single attempt.
+	Timeout time.Duration
+	// Concurrency bounds how many matches are notified at once.
+	Concurrency int
+}
+
+// ImageMatcher matches newly ingested images against the saved searches and hands every match
+// to a Notifier. It is an outbox sink, fed the image.created events, so an image committed is
+// matched even if the process dies right after. Matches are recorded as due before they are
+// notified, so pending notifications survive a restart and failed ones are retried.
+type ImageMatcher struct {
+	logger                log.Logger
+	config                MatcherConfig
+	notifier              Notifier
+	storageService        storageservice.Service
+	savedSearchRepository savedsearchrepository.Repository
+	poller                *retry.Poller
+}
+
+func NewImageMatcher(logger log.Logger, config MatcherConfig, notifier Notifier, storageService storageservice.Service, savedSearchRepository savedsearchrepository.Repository) *ImageMatcher {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultNotifyMaxAttempts
+	}
+	config.Backoff = config.Backoff.WithDefaults(retry.Backoff{Base: DefaultNotifyBaseBackoff, Max: DefaultNotifyMaxBackoff})
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultNotifyPollInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultHTTPNotifierTimeout
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultNotifyConcurrency
+	}
+
+	return &ImageMatcher{
+		logger:                logger,
+		config:                config,
+		notifier:              notifier,
+		storageService:        storageService,
+		savedSearchRepository: savedSearchRepository,
+		poller:                retry.NewPoller(config.PollInterval),
+	}
+}
+
+// Publish records the matches of the image created in an outbox message and leaves notifying
+// them to Run, so that the relay does not wait on slow callbacks. Other events are ignored. It is
+// idempotent, so the relay may hand over the same message again.
+func (m *ImageMatcher) Publish(ctx context.Context, message *models.OutboxMessage) error {
+	if message.EventType != models.EventTypeImageCreated {
+		return nil
+	}
+
+	n, err := m.savedSearchRepository.CreateMatches(ctx, message.AggregateID)
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		m.poller.Wake()
+	}
+
+	return nil
+}
+
+// Run notifies due matches until ctx is done.
+func (m *ImageMatcher) Run(ctx context.Context) error {
+	return m.poller.Run(ctx, m.config.Concurrency, func(ctx context.Context) int {
+		n, err := m.dispatch(ctx)
+		if err != nil {
+			m.logger.Log("saved_search", "notify", "error", err)
+		}
+		return n
+	})
+}
+
+// dispatch notifies as many due matches as may be notified at once, so that every claimed match
+// is attempted right away.
+func (m *ImageMatcher) dispatch(ctx context.Context) (int, error) {
+	pending, err := m.savedSearchRepository.ClaimMatchNotifications(ctx, m.config.Concurrency, retry.Lease(m.config.Timeout))
+	if err != nil {
+		return 0, err
+	}
+
+	group := errgroup.Group{}
+	for i := range pending {
+		group.Go(func() error {
+			m.notify(ctx, &pending[i])
+			return nil
+		})
+	}
+	group.Wait()
+
+	return len(pending), nil
+}
+
+func (m *ImageMatcher) notify(ctx context.Context, pending *savedsearchrepository.PendingNotification) {
+	match := &pending.SavedSearchMatch
+	match.NotifyAttempts++
+
+	err := m.send(ctx, pending)
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		match.NotifiedAt = &now
+		match.NotifyError = nil
+		match.NextNotifyAt = nil
+	case match.NotifyAttempts >= m.config.MaxAttempts:
+		message := netguard.Reason(err)
+		match.NotifyError = &message
+		match.NextNotifyAt = nil
+	default:
+		message := netguard.Reason(err)
+		next := now.Add(m.config.Backoff.Delay(match.NotifyAttempts))
+		match.NotifyError = &message
+		match.NextNotifyAt = &next
+	}
+
+	if err != nil {
+		m.logger.Log("saved_search", pending.SavedSearch.ID, "match", match.ID, "attempt", match.NotifyAttempts, "error", err)
+	}
+
+	if err := m.savedSearchRepository.UpdateMatchNotification(ctx, match); err != nil {
+		m.logger.Log("saved_search", pending.SavedSearch.ID, "match", match.ID, "error", err)
+	}
+}
+
+func (m *ImageMatcher) send(ctx context.Context, pending *savedsearchrepository.PendingNotification) error {
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	image := &pending.Image
+	url, err := m.storageService.FormatURL(ctx, &models.StorageFile{
+		Provider: image.StorageProvider,
+		Key:      image.StorageKey,
+	})
+	if err != nil {
+		return err
+	}
+	image.URL = url
+
+	return m.notifier.Notify(ctx, &pending.SavedSearch, &pending.SavedSearchMatch)
+}
@@ -0,0 +1,179 @@
+package savedsearchservice
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/pkg/netguard"
+	"github.com/yckao/image-search-demo-go/pkg/retry"
+	"github.com/yckao/image-search-demo-go/services/savedsearch/savedsearchrepository"
+	"github.com/yckao/image-search-demo-go/services/storage/storageservice"
+)
+
+// matchRepository records which images were matched, hands out queued matches and records the
+// last update.
+type matchRepository struct {
+	savedsearchrepository.Repository
+	matched []uuid.UUID
+	pending []savedsearchrepository.PendingNotification
+
+	mu      sync.Mutex
+	updated *models.SavedSearchMatch
+}
+
+func (r *matchRepository) CreateMatches(ctx context.Context, imageID uuid.UUID) (int, error) {
+	r.matched = append(r.matched, imageID)
+	return 1, nil
+}
+
+func (r *matchRepository) ClaimMatchNotifications(ctx context.Context, limit int, lease time.Duration) ([]savedsearchrepository.PendingNotification, error) {
+	pending := r.pending[:min(limit, len(r.pending))]
+	r.pending = r.pending[len(pending):]
+	return pending, nil
+}
+
+func (r *matchRepository) UpdateMatchNotification(ctx context.Context, match *models.SavedSearchMatch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	updated := *match
+	r.updated = &updated
+	return nil
+}
+
+type urlStorage struct {
+	storageservice.Service
+}
+
+func (urlStorage) FormatURL(ctx context.Context, file *models.StorageFile) (string, error) {
+	return "https://storage.example.com/" + file.Key, nil
+}
+
+type notifierFunc func(ctx context.Context, savedSearch *models.SavedSearch, match *models.SavedSearchMatch) error
+
+func (f notifierFunc) Notify(ctx context.Context, savedSearch *models.SavedSearch, match *models.SavedSearchMatch) error {
+	return f(ctx, savedSearch, match)
+}
+
+func TestImageMatcherPublish(t *testing.T) {
+	repository := &matchRepository{}
+	m := NewImageMatcher(log.NewNopLogger(), MatcherConfig{}, nil, urlStorage{}, repository)
+
+	created := uuid.New()
+	for _, message := range []models.OutboxMessage{
+		{EventType: models.EventTypeImageCreated, AggregateID: created},
+		{EventType: models.EventTypeImageUpdated, AggregateID: uuid.New()},
+		{EventType: models.EventTypeImageDeleted, AggregateID: uuid.New()},
+	} {
+		if err := m.Publish(context.Background(), &message); err != nil {
+			t.Fatalf("Publish(%s) error = %v", message.EventType, err)
+		}
+	}
+
+	if !slices.Equal(repository.matched, []uuid.UUID{created}) {
+		t.Errorf("matched images = %v, want only the created one %v", repository.matched, created)
+	}
+}
+
+func TestImageMatcherNotify(t *testing.T) {
+	config := MatcherConfig{MaxAttempts: 3, Backoff: retry.Backoff{Base: time.Minute, Max: time.Hour}}
+
+	tests := []struct {
+		name          string
+		attempts      int
+		err           error
+		wantNotified  bool
+		wantError     string
+		wantNextAfter time.Duration
+	}{
+		{name: "success", wantNotified: true},
+		{name: "first failure is retried", err: errors.New("connection reset"), wantError: "request failed", wantNextAfter: time.Minute},
+		{name: "backoff doubles", attempts: 1, err: &netguard.ErrResponseStatus{Peer: "callback", StatusCode: 503}, wantError: "callback responded with status 503", wantNextAfter: 2 * time.Minute},
+		{name: "last attempt gives up", attempts: 2, err: &netguard.ErrAddressBlocked{}, wantError: "address not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &matchRepository{pending: []savedsearchrepository.PendingNotification{{
+				SavedSearchMatch: models.SavedSearchMatch{ID: uuid.New(), NotifyAttempts: tt.attempts, Image: models.Image{StorageKey: "cat.jpg"}},
+			}}}
+			var url string
+			notifier := notifierFunc(func(ctx context.Context, savedSearch *models.SavedSearch, match *models.SavedSearchMatch) error {
+				url = match.Image.URL
+				return tt.err
+			})
+			m := NewImageMatcher(log.NewNopLogger(), config, notifier, urlStorage{}, repository)
+
+			start := time.Now()
+			if n, err := m.dispatch(context.Background()); n != 1 || err != nil {
+				t.Fatalf("dispatch() = %d, %v, want 1, nil", n, err)
+			}
+
+			match := repository.updated
+			if match == nil {
+				t.Fatal("dispatch() did not record the attempt")
+			}
+			if url != "https://storage.example.com/cat.jpg" {
+				t.Errorf("notified image URL = %q, want it formatted from the storage key", url)
+			}
+			if match.NotifyAttempts != tt.attempts+1 {
+				t.Errorf("NotifyAttempts = %d, want %d", match.NotifyAttempts, tt.attempts+1)
+			}
+			if (match.NotifiedAt != nil) != tt.wantNotified {
+				t.Errorf("NotifiedAt = %v, want set %t", match.NotifiedAt, tt.wantNotified)
+			}
+			var notifyError string
+			if match.NotifyError != nil {
+				notifyError = *match.NotifyError
+			}
+			if notifyError != tt.wantError {
+				t.Errorf("NotifyError = %q, want %q", notifyError, tt.wantError)
+			}
+			switch {
+			case tt.wantNextAfter == 0 && match.NextNotifyAt != nil:
+				t.Errorf("NextNotifyAt = %v, want nil", match.NextNotifyAt)
+			case tt.wantNextAfter != 0 && (match.NextNotifyAt == nil || match.NextNotifyAt.Before(start.Add(tt.wantNextAfter))):
+				t.Errorf("NextNotifyAt = %v, want %v after the attempt", match.NextNotifyAt, tt.wantNextAfter)
+			}
+		})
+	}
+}
+
+func TestImageMatcherClaimsOnlyWhatItRuns(t *testing.T) {
+	repository := &matchRepository{}
+	for range 5 {
+		repository.pending = append(repository.pending, savedsearchrepository.PendingNotification{})
+	}
+	notifier := notifierFunc(func(ctx context.Context, savedSearch *models.SavedSearch, match *models.SavedSearchMatch) error {
+		return nil
+	})
+	m := NewImageMatcher(log.NewNopLogger(), MatcherConfig{Concurrency: 2}, notifier, urlStorage{}, repository)
+
+	if n, _ := m.dispatch(context.Background()); n != 2 {
+		t.Errorf("dispatch() claimed %d matches, want Concurrency", n)
+	}
+}
+
+func TestHTTPNotifierBlocksInternalCallbacks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	guard, err := netguard.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier := NewHTTPNotifier(&http.Client{Transport: guard.Transport()})
+
+	err = notifier.Notify(context.Background(), &models.SavedSearch{CallbackURL: server.URL}, &models.SavedSearchMatch{})
+	if got := netguard.Reason(err); got != "address not allowed" {
+		t.Errorf("Notify() to a loopback callback = %v, want it refused", err)
+	}
+}
@@ -0,0 +1,80 @@
+package savedsearchservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/pkg/netguard"
+)
+
+// Notifier delivers the match of a newly ingested image with a saved search.
+type Notifier interface {
+	Notify(ctx context.Context, savedSearch *models.SavedSearch, match *models.SavedSearchMatch) error
+}
+
+// MatchNotification is the payload a Notifier delivers.
+type MatchNotification struct {
+	SavedSearch *models.SavedSearch      `json:"saved_search"`
+	Match       *models.SavedSearchMatch `json:"match"`
+}
+
+const DefaultHTTPNotifierTimeout = 10 * time.Second
+
+type httpNotifier struct {
+	client *http.Client
+}
+
+// NewHTTPNotifier posts each match as JSON to the callback URL of its saved search. Saved
+// searches without a callback URL are not notified. Any response other than 2xx fails the
+// notification. Callback URLs are chosen by API callers, so client should dial through a
+// netguard.Guard.
+func NewHTTPNotifier(client *http.Client) Notifier {
+	return &httpNotifier{client: client}
+}
+
+func (n *httpNotifier) Notify(ctx context.Context, savedSearch *models.SavedSearch, match *models.SavedSearchMatch) error {
+	if savedSearch.CallbackURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(MatchNotification{SavedSearch: savedSearch, Match: match})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, savedSearch.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &netguard.ErrResponseStatus{Peer: "callback", StatusCode: resp.StatusCode}
+	}
+
+	return nil
+}
+
+type logNotifier struct {
+	logger log.Logger
+}
+
+// NewLogNotifier logs every match instead of delivering it.
+func NewLogNotifier(logger log.Logger) Notifier {
+	return &logNotifier{logger: logger}
+}
+
+func (n *logNotifier) Notify(ctx context.Context, savedSearch *models.SavedSearch, match *models.SavedSearchMatch) error {
+	return n.logger.Log("saved_search", savedSearch.ID, "match", match.ID, "image", match.Image.ID, "score", match.Score)
+}
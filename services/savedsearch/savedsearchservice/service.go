@@ -0,0 +1,177 @@
+package savedsearchservice
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/clients/clip"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/savedsearch/savedsearchrepository"
+	"github.com/yckao/image-search-demo-go/services/storage/storageservice"
+)
+
+// Service manages saved searches, which newly ingested images are matched against by an
+// ImageMatcher.
+type Service interface {
+	CreateSavedSearch(ctx context.Context, name string, query models.SavedSearchQuery, minScore *float64, callbackURL string) (*models.SavedSearch, error)
+	ListSavedSearches(ctx context.Context) ([]models.SavedSearch, error)
+	GetSavedSearch(ctx context.Context, id uuid.UUID) (*models.SavedSearch, error)
+	UpdateSavedSearch(ctx context.Context, id uuid.UUID, name string, minScore *float64, callbackURL string) (*models.SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, id uuid.UUID) error
+	ListSavedSearchMatches(ctx context.Context, id uuid.UUID, after *uuid.UUID, limit int) (*models.SavedSearchMatchList, error)
+}
+
+const (
+	MaxNameLength      = 255
+	MaxQueryTextLength = 255
+
+	DefaultMatchListLimit = 50
+	MaxMatchListLimit     = 500
+)
+
+type savedSearchService struct {
+	logger                log.Logger
+	modelName             string
+	clipService           clip.Service
+	storageService        storageservice.Service
+	savedSearchRepository savedsearchrepository.Repository
+}
+
+// New returns a Service. modelName is the CLIP model that embeds images; a saved search for a
+// stored image uses the image's embedding by that model.
+func New(logger log.Logger, modelName string, clipService clip.Service, storageService storageservice.Service, savedSearchRepository savedsearchrepository.Repository) Service {
+	return &savedSearchService{
+		logger:                logger,
+		modelName:             modelName,
+		clipService:           clipService,
+		storageService:        storageService,
+		savedSearchRepository: savedSearchRepository,
+	}
+}
+
+func (s *savedSearchService) CreateSavedSearch(ctx context.Context, name string, query models.SavedSearchQuery, minScore *float64, callbackURL string) (*models.SavedSearch, error) {
+	savedSearch := &models.SavedSearch{
+		QueryText:     strings.TrimSpace(query.Text),
+		QueryImageID:  query.ImageID,
+		QuerySearchID: query.SearchID,
+	}
+
+	var err error
+	if savedSearch.Name, savedSearch.MinScore, savedSearch.CallbackURL, err = validateSavedSearch(name, minScore, callbackURL); err != nil {
+		return nil, err
+	}
+
+	given := 0
+	for _, ok := range []bool{savedSearch.QueryText != "", query.ImageID != nil, query.SearchID != nil} {
+		if ok {
+			given++
+		}
+	}
+	if given != 1 {
+		return nil, errortypes.NewErrInvalidSavedSearchParameter("query", "exactly one of query_text, image_id and search_id is required")
+	}
+	if len(savedSearch.QueryText) > MaxQueryTextLength {
+		return nil, errortypes.NewErrInvalidSavedSearchParameter("query_text", fmt.Sprintf("must be at most %d characters", MaxQueryTextLength))
+	}
+
+	var embedding *models.Embedding
+	switch {
+	case query.ImageID != nil:
+		savedSearch.QueryType = models.SavedSearchQueryTypeImage
+		embedding, err = s.savedSearchRepository.GetImageEmbedding(ctx, *query.ImageID, s.modelName)
+	case query.SearchID != nil:
+		savedSearch.QueryType = models.SavedSearchQueryTypeSearch
+		embedding, err = s.savedSearchRepository.GetSearchEmbedding(ctx, *query.SearchID)
+	default:
+		savedSearch.QueryType = models.SavedSearchQueryTypeText
+		embedding, err = s.clipService.TextEmbedding(ctx, savedSearch.QueryText)
+	}
+	if err != nil {
+		return nil, err
+	}
+	savedSearch.ModelName = embedding.Model
+
+	return s.savedSearchRepository.CreateSavedSearch(ctx, savedSearch, embedding.Embedding)
+}
+
+func (s *savedSearchService) ListSavedSearches(ctx context.Context) ([]models.SavedSearch, error) {
+	return s.savedSearchRepository.ListSavedSearches(ctx)
+}
+
+func (s *savedSearchService) GetSavedSearch(ctx context.Context, id uuid.UUID) (*models.SavedSearch, error) {
+	return s.savedSearchRepository.GetSavedSearch(ctx, id)
+}
+
+// UpdateSavedSearch changes the name, threshold and callback of a saved search. Its query is
+// fixed; save a new search to match something else.
+func (s *savedSearchService) UpdateSavedSearch(ctx context.Context, id uuid.UUID, name string, minScore *float64, callbackURL string) (*models.SavedSearch, error) {
+	savedSearch := &models.SavedSearch{ID: id}
+
+	var err error
+	if savedSearch.Name, savedSearch.MinScore, savedSearch.CallbackURL, err = validateSavedSearch(name, minScore, callbackURL); err != nil {
+		return nil, err
+	}
+
+	return s.savedSearchRepository.UpdateSavedSearch(ctx, savedSearch)
+}
+
+func (s *savedSearchService) DeleteSavedSearch(ctx context.Context, id uuid.UUID) error {
+	return s.savedSearchRepository.DeleteSavedSearch(ctx, id)
+}
+
+func (s *savedSearchService) ListSavedSearchMatches(ctx context.Context, id uuid.UUID, after *uuid.UUID, limit int) (*models.SavedSearchMatchList, error) {
+	if limit == 0 {
+		limit = DefaultMatchListLimit
+	}
+	if limit < 0 || limit > MaxMatchListLimit {
+		return nil, errortypes.NewErrInvalidSavedSearchParameter("limit", fmt.Sprintf("must be between 1 and %d", MaxMatchListLimit))
+	}
+
+	matches, err := s.savedSearchRepository.ListMatches(ctx, id, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range matches {
+		url, err := s.storageService.FormatURL(ctx, &models.StorageFile{
+			Provider: matches[i].Image.StorageProvider,
+			Key:      matches[i].Image.StorageKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		matches[i].Image.URL = url
+	}
+
+	list := &models.SavedSearchMatchList{Matches: matches}
+	if len(matches) == limit {
+		list.NextAfter = &matches[len(matches)-1].ID
+	}
+
+	return list, nil
+}
+
+func validateSavedSearch(name string, minScore *float64, callbackURL string) (string, float64, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > MaxNameLength {
+		return "", 0, "", errortypes.NewErrInvalidSavedSearchParameter("name", fmt.Sprintf("must be between 1 and %d characters", MaxNameLength))
+	}
+
+	if minScore == nil || *minScore < -1 || *minScore > 1 {
+		return "", 0, "", errortypes.NewErrInvalidSavedSearchParameter("min_score", "must be between -1 and 1")
+	}
+
+	if callbackURL != "" {
+		u, err := url.Parse(callbackURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", 0, "", errortypes.NewErrInvalidSavedSearchParameter("callback_url", "must be an absolute http or https URL")
+		}
+	}
+
+	return name, *minScore, callbackURL, nil
+}
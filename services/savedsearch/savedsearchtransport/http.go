@@ -0,0 +1,244 @@
+package savedsearchtransport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/savedsearch/savedsearchendpoint"
+)
+
+func NewHTTPHandler(svc savedsearchendpoint.Endpoints, logger log.Logger) http.Handler {
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(errortypes.ErrorEncoder),
+		httptransport.ServerErrorLogger(logger),
+	}
+
+	m := http.NewServeMux()
+
+	m.Handle("POST /saved-searches", httptransport.NewServer(
+		svc.CreateSavedSearchEndpoint,
+		decodeCreateSavedSearchRequest,
+		encodeCreateSavedSearchResponse,
+		options...,
+	))
+
+	m.Handle("GET /saved-searches", httptransport.NewServer(
+		svc.ListSavedSearchesEndpoint,
+		decodeListSavedSearchesRequest,
+		encodeListSavedSearchesResponse,
+		options...,
+	))
+
+	m.Handle("GET /saved-searches/{id}", httptransport.NewServer(
+		svc.GetSavedSearchEndpoint,
+		decodeGetSavedSearchRequest,
+		encodeGetSavedSearchResponse,
+		options...,
+	))
+
+	m.Handle("PUT /saved-searches/{id}", httptransport.NewServer(
+		svc.UpdateSavedSearchEndpoint,
+		decodeUpdateSavedSearchRequest,
+		encodeUpdateSavedSearchResponse,
+		options...,
+	))
+
+	m.Handle("DELETE /saved-searches/{id}", httptransport.NewServer(
+		svc.DeleteSavedSearchEndpoint,
+		decodeDeleteSavedSearchRequest,
+		encodeNoContentResponse,
+		options...,
+	))
+
+	m.Handle("GET /saved-searches/{id}/matches", httptransport.NewServer(
+		svc.ListSavedSearchMatchesEndpoint,
+		decodeListSavedSearchMatchesRequest,
+		encodeListSavedSearchMatchesResponse,
+		options...,
+	))
+
+	return m
+}
+
+type savedSearchRequest struct {
+	Name        string     `json:"name"`
+	QueryText   string     `json:"query_text"`
+	ImageID     *uuid.UUID `json:"image_id"`
+	SearchID    *uuid.UUID `json:"search_id"`
+	MinScore    *float64   `json:"min_score"`
+	CallbackURL string     `json:"callback_url"`
+}
+
+func decodeCreateSavedSearchRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var body savedSearchRequest
+	if err := decodeJSONBody(r, &body); err != nil {
+		return nil, err
+	}
+
+	return savedsearchendpoint.CreateSavedSearchRequest{
+		Name: body.Name,
+		Query: models.SavedSearchQuery{
+			Text:     body.QueryText,
+			ImageID:  body.ImageID,
+			SearchID: body.SearchID,
+		},
+		MinScore:    body.MinScore,
+		CallbackURL: body.CallbackURL,
+	}, nil
+}
+
+func encodeCreateSavedSearchResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(savedsearchendpoint.CreateSavedSearchResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeListSavedSearchesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return savedsearchendpoint.ListSavedSearchesRequest{}, nil
+}
+
+func encodeListSavedSearchesResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(savedsearchendpoint.ListSavedSearchesResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeGetSavedSearchRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	return savedsearchendpoint.GetSavedSearchRequest{
+		ID: id,
+	}, nil
+}
+
+func encodeGetSavedSearchResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(savedsearchendpoint.GetSavedSearchResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeUpdateSavedSearchRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	var body savedSearchRequest
+	if err := decodeJSONBody(r, &body); err != nil {
+		return nil, err
+	}
+
+	return savedsearchendpoint.UpdateSavedSearchRequest{
+		ID:          id,
+		Name:        body.Name,
+		MinScore:    body.MinScore,
+		CallbackURL: body.CallbackURL,
+	}, nil
+}
+
+func encodeUpdateSavedSearchResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(savedsearchendpoint.UpdateSavedSearchResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeDeleteSavedSearchRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	return savedsearchendpoint.DeleteSavedSearchRequest{
+		ID: id,
+	}, nil
+}
+
+func decodeListSavedSearchMatchesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	var after *uuid.UUID
+	if v := r.URL.Query().Get("after"); v != "" {
+		parsed, err := uuid.Parse(v)
+		if err != nil {
+			return nil, errortypes.NewErrInvalidSavedSearchParameter("after", "must be a match id")
+		}
+		after = &parsed
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			return nil, errortypes.NewErrInvalidSavedSearchParameter("limit", "must be an integer")
+		}
+	}
+
+	return savedsearchendpoint.ListSavedSearchMatchesRequest{
+		ID:    id,
+		After: after,
+		Limit: limit,
+	}, nil
+}
+
+func encodeListSavedSearchMatchesResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(savedsearchendpoint.ListSavedSearchMatchesResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func encodeNoContentResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if err := response.(endpoint.Failer).Failed(); err != nil {
+		errortypes.ErrorEncoder(ctx, err, w)
+		return nil
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func decodeJSONBody(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return errortypes.NewErrInvalidSavedSearchParameter("body", "must be a JSON object")
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ type Endpoints struct {
 	UploadEndpoint    endpoint.Endpoint
 	DownloadEndpoint  endpoint.Endpoint
 	FormatURLEndpoint endpoint.Endpoint
+	DeleteEndpoint    endpoint.Endpoint
 }
 
 func New(svc storageservice.Service, logger log.Logger) Endpoints {
@@ -34,11 +35,17 @@ func New(svc storageservice.Service, logger log.Logger) Endpoints {
 		formatURLEndpoint = MakeFormatURLEndpoint(svc)
 	}
 
+	var deleteEndpoint endpoint.Endpoint
+	{
+		deleteEndpoint = MakeDeleteEndpoint(svc)
+	}
+
 	return Endpoints{
 		logger:            logger,
 		UploadEndpoint:    uploadEndpoint,
 		DownloadEndpoint:  downloadEndpoint,
 		FormatURLEndpoint: formatURLEndpoint,
+		DeleteEndpoint:    deleteEndpoint,
 	}
 }
 
@@ -85,6 +92,16 @@ func MakeFormatURLEndpoint(svc storageservice.Service) endpoint.Endpoint {
 	}
 }
 
+func MakeDeleteEndpoint(svc storageservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteRequest)
+		err := svc.Delete(ctx, req.File)
+		return DeleteResponse{
+			Err: err,
+		}, nil
+	}
+}
+
 var _ storageservice.Service = (*Endpoints)(nil)
 
 func (e *Endpoints) Upload(ctx context.Context, stream *models.StorageFileStream) (*models.StorageFile, error) {
@@ -114,10 +131,20 @@ func (e *Endpoints) FormatURL(ctx context.Context, file *models.StorageFile) (st
 	return response.V, response.Err
 }
 
+func (e *Endpoints) Delete(ctx context.Context, file *models.StorageFile) error {
+	resp, err := e.DeleteEndpoint(ctx, DeleteRequest{File: file})
+	if err != nil {
+		return err
+	}
+	response := resp.(DeleteResponse)
+	return response.Err
+}
+
 var (
 	_ endpoint.Failer = UploadResponse{}
 	_ endpoint.Failer = DownloadResponse{}
 	_ endpoint.Failer = FormatURLResponse{}
+	_ endpoint.Failer = DeleteResponse{}
 )
 
 type UploadRequest struct {
@@ -163,3 +190,15 @@ type FormatURLResponse struct {
 func (r FormatURLResponse) Failed() error {
 	return r.Err
 }
+
+type DeleteRequest struct {
+	File *models.StorageFile
+}
+
+type DeleteResponse struct {
+	Err error
+}
+
+func (r DeleteResponse) Failed() error {
+	return r.Err
+}
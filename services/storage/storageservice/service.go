@@ -10,4 +10,6 @@ type Service interface {
 	Upload(ctx context.Context, stream *models.StorageFileStream) (*models.StorageFile, error)
 	Download(ctx context.Context, file *models.StorageFile) (*models.StorageFileStream, error)
 	FormatURL(ctx context.Context, file *models.StorageFile) (string, error)
+	// Delete removes a file. Deleting a file that does not exist is not an error.
+	Delete(ctx context.Context, file *models.StorageFile) error
 }
@@ -90,6 +90,9 @@ func (s *s3Service) Download(ctx context.Context, file *models.StorageFile) (*mo
 		Bucket: aws.String(s.config.Bucket),
 		Key:    aws.String(file.Key),
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	return &models.StorageFileStream{
 		Reader:        result.Body,
@@ -102,3 +105,11 @@ func (s *s3Service) Download(ctx context.Context, file *models.StorageFile) (*mo
 func (s *s3Service) FormatURL(ctx context.Context, file *models.StorageFile) (string, error) {
 	return fmt.Sprintf(s.config.URLFormat, s.config.BaseURL, file.Key), nil
 }
+
+func (s *s3Service) Delete(ctx context.Context, file *models.StorageFile) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(file.Key),
+	})
+	return err
+}
@@ -0,0 +1,337 @@
+package webhookendpoint
+
+import (
+	"context"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/webhook/webhookservice"
+)
+
+type Endpoints struct {
+	logger                           log.Logger
+	CreateWebhookEndpoint            endpoint.Endpoint
+	ListWebhooksEndpoint             endpoint.Endpoint
+	GetWebhookEndpoint               endpoint.Endpoint
+	UpdateWebhookEndpoint            endpoint.Endpoint
+	DeleteWebhookEndpoint            endpoint.Endpoint
+	ListWebhookDeliveriesEndpoint    endpoint.Endpoint
+	RedeliverWebhookDeliveryEndpoint endpoint.Endpoint
+}
+
+func New(svc webhookservice.Service, logger log.Logger) Endpoints {
+	var createWebhookEndpoint endpoint.Endpoint
+	{
+		createWebhookEndpoint = MakeCreateWebhookEndpoint(svc)
+	}
+
+	var listWebhooksEndpoint endpoint.Endpoint
+	{
+		listWebhooksEndpoint = MakeListWebhooksEndpoint(svc)
+	}
+
+	var getWebhookEndpoint endpoint.Endpoint
+	{
+		getWebhookEndpoint = MakeGetWebhookEndpoint(svc)
+	}
+
+	var updateWebhookEndpoint endpoint.Endpoint
+	{
+		updateWebhookEndpoint = MakeUpdateWebhookEndpoint(svc)
+	}
+
+	var deleteWebhookEndpoint endpoint.Endpoint
+	{
+		deleteWebhookEndpoint = MakeDeleteWebhookEndpoint(svc)
+	}
+
+	var listWebhookDeliveriesEndpoint endpoint.Endpoint
+	{
+		listWebhookDeliveriesEndpoint = MakeListWebhookDeliveriesEndpoint(svc)
+	}
+
+	var redeliverWebhookDeliveryEndpoint endpoint.Endpoint
+	{
+		redeliverWebhookDeliveryEndpoint = MakeRedeliverWebhookDeliveryEndpoint(svc)
+	}
+
+	return Endpoints{
+		logger:                           logger,
+		CreateWebhookEndpoint:            createWebhookEndpoint,
+		ListWebhooksEndpoint:             listWebhooksEndpoint,
+		GetWebhookEndpoint:               getWebhookEndpoint,
+		UpdateWebhookEndpoint:            updateWebhookEndpoint,
+		DeleteWebhookEndpoint:            deleteWebhookEndpoint,
+		ListWebhookDeliveriesEndpoint:    listWebhookDeliveriesEndpoint,
+		RedeliverWebhookDeliveryEndpoint: redeliverWebhookDeliveryEndpoint,
+	}
+}
+
+func MakeCreateWebhookEndpoint(svc webhookservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateWebhookRequest)
+		resp, err := svc.CreateWebhook(ctx, req.URL, req.Events, req.Active)
+		return CreateWebhookResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeListWebhooksEndpoint(svc webhookservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		resp, err := svc.ListWebhooks(ctx)
+		return ListWebhooksResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeGetWebhookEndpoint(svc webhookservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetWebhookRequest)
+		resp, err := svc.GetWebhook(ctx, req.ID)
+		return GetWebhookResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeUpdateWebhookEndpoint(svc webhookservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UpdateWebhookRequest)
+		resp, err := svc.UpdateWebhook(ctx, req.ID, req.URL, req.Events, req.Active)
+		return UpdateWebhookResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeDeleteWebhookEndpoint(svc webhookservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteWebhookRequest)
+		err := svc.DeleteWebhook(ctx, req.ID)
+		return DeleteWebhookResponse{
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeListWebhookDeliveriesEndpoint(svc webhookservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ListWebhookDeliveriesRequest)
+		resp, err := svc.ListWebhookDeliveries(ctx, req.ID, req.After, req.Limit)
+		return ListWebhookDeliveriesResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+func MakeRedeliverWebhookDeliveryEndpoint(svc webhookservice.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RedeliverWebhookDeliveryRequest)
+		resp, err := svc.RedeliverWebhookDelivery(ctx, req.ID, req.DeliveryID)
+		return RedeliverWebhookDeliveryResponse{
+			V:   resp,
+			Err: err,
+		}, nil
+	}
+}
+
+var _ webhookservice.Service = (*Endpoints)(nil)
+
+func (e *Endpoints) CreateWebhook(ctx context.Context, url string, events []models.EventType, active *bool) (*models.Webhook, error) {
+	resp, err := e.CreateWebhookEndpoint(ctx, CreateWebhookRequest{
+		URL:    url,
+		Events: events,
+		Active: active,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(CreateWebhookResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	resp, err := e.ListWebhooksEndpoint(ctx, ListWebhooksRequest{})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(ListWebhooksResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	resp, err := e.GetWebhookEndpoint(ctx, GetWebhookRequest{
+		ID: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(GetWebhookResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) UpdateWebhook(ctx context.Context, id uuid.UUID, url string, events []models.EventType, active *bool) (*models.Webhook, error) {
+	resp, err := e.UpdateWebhookEndpoint(ctx, UpdateWebhookRequest{
+		ID:     id,
+		URL:    url,
+		Events: events,
+		Active: active,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(UpdateWebhookResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	resp, err := e.DeleteWebhookEndpoint(ctx, DeleteWebhookRequest{
+		ID: id,
+	})
+	if err != nil {
+		return err
+	}
+	response := resp.(DeleteWebhookResponse)
+	return response.Err
+}
+
+func (e *Endpoints) ListWebhookDeliveries(ctx context.Context, id uuid.UUID, after *uuid.UUID, limit int) (*models.WebhookDeliveryList, error) {
+	resp, err := e.ListWebhookDeliveriesEndpoint(ctx, ListWebhookDeliveriesRequest{
+		ID:    id,
+		After: after,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(ListWebhookDeliveriesResponse)
+	return response.V, response.Err
+}
+
+func (e *Endpoints) RedeliverWebhookDelivery(ctx context.Context, id uuid.UUID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	resp, err := e.RedeliverWebhookDeliveryEndpoint(ctx, RedeliverWebhookDeliveryRequest{
+		ID:         id,
+		DeliveryID: deliveryID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := resp.(RedeliverWebhookDeliveryResponse)
+	return response.V, response.Err
+}
+
+var (
+	_ endpoint.Failer = CreateWebhookResponse{}
+	_ endpoint.Failer = ListWebhooksResponse{}
+	_ endpoint.Failer = GetWebhookResponse{}
+	_ endpoint.Failer = UpdateWebhookResponse{}
+	_ endpoint.Failer = DeleteWebhookResponse{}
+	_ endpoint.Failer = ListWebhookDeliveriesResponse{}
+	_ endpoint.Failer = RedeliverWebhookDeliveryResponse{}
+)
+
+type CreateWebhookRequest struct {
+	URL    string
+	Events []models.EventType
+	Active *bool
+}
+
+type CreateWebhookResponse struct {
+	V   *models.Webhook
+	Err error
+}
+
+func (r CreateWebhookResponse) Failed() error {
+	return r.Err
+}
+
+type ListWebhooksRequest struct{}
+
+type ListWebhooksResponse struct {
+	V   []models.Webhook
+	Err error
+}
+
+func (r ListWebhooksResponse) Failed() error {
+	return r.Err
+}
+
+type GetWebhookRequest struct {
+	ID uuid.UUID
+}
+
+type GetWebhookResponse struct {
+	V   *models.Webhook
+	Err error
+}
+
+func (r GetWebhookResponse) Failed() error {
+	return r.Err
+}
+
+type UpdateWebhookRequest struct {
+	ID     uuid.UUID
+	URL    string
+	Events []models.EventType
+	Active *bool
+}
+
+type UpdateWebhookResponse struct {
+	V   *models.Webhook
+	Err error
+}
+
+func (r UpdateWebhookResponse) Failed() error {
+	return r.Err
+}
+
+type DeleteWebhookRequest struct {
+	ID uuid.UUID
+}
+
+type DeleteWebhookResponse struct {
+	Err error
+}
+
+func (r DeleteWebhookResponse) Failed() error {
+	return r.Err
+}
+
+type ListWebhookDeliveriesRequest struct {
+	ID    uuid.UUID
+	After *uuid.UUID
+	Limit int
+}
+
+type ListWebhookDeliveriesResponse struct {
+	V   *models.WebhookDeliveryList
+	Err error
+}
+
+func (r ListWebhookDeliveriesResponse) Failed() error {
+	return r.Err
+}
+
+type RedeliverWebhookDeliveryRequest struct {
+	ID         uuid.UUID
+	DeliveryID uuid.UUID
+}
+
+type RedeliverWebhookDeliveryResponse struct {
+	V   *models.WebhookDelivery
+	Err error
+}
+
+func (r RedeliverWebhookDeliveryResponse) Failed() error {
+	return r.Err
+}
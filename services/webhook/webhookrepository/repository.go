@@ -0,0 +1,29 @@
+package webhookrepository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+type Repository interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+	CreateDeliveries(ctx context.Context, message *models.OutboxMessage) (int, error)
+	CreateRedelivery(ctx context.Context, webhookID uuid.UUID, deliveryID uuid.UUID) (*models.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, webhookID uuid.UUID, after *uuid.UUID, limit int) ([]models.WebhookDelivery, error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]PendingDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+// PendingDelivery is a claimed delivery together with where to send it and what to sign it with.
+type PendingDelivery struct {
+	models.WebhookDelivery
+	URL    string
+	Secret string
+}
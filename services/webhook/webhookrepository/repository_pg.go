@@ -0,0 +1,252 @@
+package webhookrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+)
+
+type PGRepository struct {
+	logger log.Logger
+	db     *pgxpool.Pool
+}
+
+func NewPGRepository(logger log.Logger, db *pgxpool.Pool) Repository {
+	return &PGRepository{logger: logger, db: db}
+}
+
+func (r *PGRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error) {
+	if webhook.ID == uuid.Nil {
+		webhook.ID = uuid.Must(uuid.NewV7())
+	}
+
+	if err := r.db.QueryRow(ctx,
+		"INSERT INTO webhooks (id, url, events, secret, active) VALUES ($1, $2, $3, $4, $5) RETURNING created_at, updated_at",
+		webhook.ID, webhook.URL, eventStrings(webhook.Events), webhook.Secret, webhook.Active).
+		Scan(&webhook.CreatedAt, &webhook.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (r *PGRepository) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	rows, err := r.db.Query(ctx, fmt.Sprintf("SELECT %s FROM webhooks ORDER BY created_at ASC, id ASC", webhookColumns))
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanWebhook)
+}
+
+func (r *PGRepository) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	rows, err := r.db.Query(ctx, fmt.Sprintf("SELECT %s FROM webhooks WHERE id = $1", webhookColumns), id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := pgx.CollectExactlyOneRow(rows, scanWebhook)
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrWebhookNotFound(id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+func (r *PGRepository) UpdateWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error) {
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf("UPDATE webhooks SET url = $2, events = $3, active = $4, updated_at = now() WHERE id = $1 RETURNING %s", webhookColumns),
+		webhook.ID, webhook.URL, eventStrings(webhook.Events), webhook.Active)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := pgx.CollectExactlyOneRow(rows, scanWebhook)
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrWebhookNotFound(webhook.ID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+func (r *PGRepository) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return errortypes.NewErrWebhookNotFound(id)
+	}
+
+	return nil
+}
+
+// CreateDeliveries queues a delivery of an outbox message to every active webhook subscribed to
+// its event and returns how many were queued. A message is only ever queued once per webhook.
+func (r *PGRepository) CreateDeliveries(ctx context.Context, message *models.OutboxMessage) (int, error) {
+	rows, err := r.db.Query(ctx, "SELECT id FROM webhooks WHERE active AND $1 = ANY(events)", string(message.EventType))
+	if err != nil {
+		return 0, err
+	}
+
+	webhookIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return 0, err
+	}
+
+	if len(webhookIDs) == 0 {
+		return 0, nil
+	}
+
+	// Selecting the webhook again skips one deleted since it was listed.
+	batch := &pgx.Batch{}
+	for _, webhookID := range webhookIDs {
+		batch.Queue("INSERT INTO webhook_deliveries (id, webhook_id, event_id, event_type, payload) SELECT $1, id, $3, $4, $5 FROM webhooks WHERE id = $2 ON CONFLICT (webhook_id, event_id) WHERE redelivery_of IS NULL DO NOTHING",
+			uuid.Must(uuid.NewV7()), webhookID, message.EventID, string(message.EventType), []byte(message.Payload))
+	}
+
+	if err := r.db.SendBatch(ctx, batch).Close(); err != nil {
+		return 0, err
+	}
+
+	return len(webhookIDs), nil
+}
+
+// CreateRedelivery queues a new delivery of the payload of an earlier one.
+func (r *PGRepository) CreateRedelivery(ctx context.Context, webhookID uuid.UUID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	if _, err := r.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf("INSERT INTO webhook_deliveries (id, webhook_id, event_id, event_type, payload, redelivery_of) SELECT $1, webhook_id, event_id, event_type, payload, id FROM webhook_deliveries WHERE id = $2 AND webhook_id = $3 RETURNING %s", deliveryColumns("")),
+		uuid.Must(uuid.NewV7()), deliveryID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := pgx.CollectExactlyOneRow(rows, scanDelivery)
+	if err != nil && errors.Is(err, pgx.ErrNoRows) {
+		return nil, errortypes.NewErrWebhookDeliveryNotFound(deliveryID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// ListDeliveries pages through the deliveries of a webhook, newest first.
+func (r *PGRepository) ListDeliveries(ctx context.Context, webhookID uuid.UUID, after *uuid.UUID, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := r.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	args := []any{webhookID, limit}
+	conditions := []string{"webhook_id = $1"}
+	if after != nil {
+		args = append(args, *after)
+		conditions = append(conditions, "id < $3")
+	}
+
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf("SELECT %s FROM webhook_deliveries WHERE %s ORDER BY id DESC LIMIT $2", deliveryColumns(""), strings.Join(conditions, " AND ")),
+		args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, scanDelivery)
+}
+
+// ClaimDeliveries takes up to limit pending deliveries that are due, oldest first, and pushes
+// their next attempt out by lease so that a delivery whose outcome is never recorded, because the
+// process died mid-attempt, is retried once the lease runs out. SKIP LOCKED keeps concurrent
+// dispatchers from claiming the same delivery.
+func (r *PGRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]PendingDelivery, error) {
+	rows, err := r.db.Query(ctx,
+		fmt.Sprintf(`UPDATE webhook_deliveries d SET next_attempt_at = now() + make_interval(secs => $2), updated_at = now()
+			FROM webhooks w
+			WHERE w.id = d.webhook_id AND d.id IN (
+				SELECT pd.id FROM webhook_deliveries pd JOIN webhooks pw ON pw.id = pd.webhook_id
+				WHERE pd.status = 'PENDING' AND pd.next_attempt_at <= now() AND pw.active
+				ORDER BY pd.next_attempt_at ASC LIMIT $1 FOR UPDATE OF pd SKIP LOCKED
+			)
+			RETURNING %s, w.url, w.secret`, deliveryColumns("d.")),
+		limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (PendingDelivery, error) {
+		pending := PendingDelivery{}
+		var eventType, status string
+		err := row.Scan(append(deliveryDest(&pending.WebhookDelivery, &eventType, &status), &pending.URL, &pending.Secret)...)
+		pending.EventType = models.EventType(eventType)
+		pending.Status = models.WebhookDeliveryStatus(status)
+		return pending, err
+	})
+}
+
+func (r *PGRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.QueryRow(ctx,
+		"UPDATE webhook_deliveries SET status = $2, attempts = $3, response_status = $4, last_error = $5, next_attempt_at = $6, delivered_at = $7, updated_at = now() WHERE id = $1 RETURNING updated_at",
+		delivery.ID, string(delivery.Status), delivery.Attempts, delivery.ResponseStatus, delivery.LastError, delivery.NextAttemptAt, delivery.DeliveredAt).
+		Scan(&delivery.UpdatedAt)
+}
+
+// The secret is left out; it is only ever read to sign a delivery.
+const webhookColumns = "id, url, events, active, created_at, updated_at"
+
+func scanWebhook(row pgx.CollectableRow) (models.Webhook, error) {
+	webhook := models.Webhook{}
+	var events []string
+	err := row.Scan(&webhook.ID, &webhook.URL, &events, &webhook.Active, &webhook.CreatedAt, &webhook.UpdatedAt)
+	for _, event := range events {
+		webhook.Events = append(webhook.Events, models.EventType(event))
+	}
+	return webhook, err
+}
+
+func eventStrings(events []models.EventType) []string {
+	strs := make([]string, len(events))
+	for i, event := range events {
+		strs[i] = string(event)
+	}
+	return strs
+}
+
+// deliveryColumns lists the webhook_deliveries columns read by deliveryDest, each prefixed with prefix.
+func deliveryColumns(prefix string) string {
+	columns := []string{"id", "webhook_id", "event_id", "event_type", "payload", "status", "attempts", "response_status", "last_error", "next_attempt_at", "delivered_at", "redelivery_of", "created_at", "updated_at"}
+	for i := range columns {
+		columns[i] = prefix + columns[i]
+	}
+	return strings.Join(columns, ", ")
+}
+
+func deliveryDest(delivery *models.WebhookDelivery, eventType *string, status *string) []any {
+	return []any{&delivery.ID, &delivery.WebhookID, &delivery.EventID, eventType, &delivery.Payload, status, &delivery.Attempts, &delivery.ResponseStatus, &delivery.LastError, &delivery.NextAttemptAt, &delivery.DeliveredAt, &delivery.RedeliveryOf, &delivery.CreatedAt, &delivery.UpdatedAt}
+}
+
+func scanDelivery(row pgx.CollectableRow) (models.WebhookDelivery, error) {
+	delivery := models.WebhookDelivery{}
+	var eventType, status string
+	err := row.Scan(deliveryDest(&delivery, &eventType, &status)...)
+	delivery.EventType = models.EventType(eventType)
+	delivery.Status = models.WebhookDeliveryStatus(status)
+	return delivery, err
+}
@@ -0,0 +1,201 @@
+package webhookservice
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/pkg/netguard"
+	"github.com/yckao/image-search-demo-go/pkg/retry"
+	"github.com/yckao/image-search-demo-go/services/webhook/webhookrepository"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	DefaultMaxAttempts  = 8
+	DefaultBaseBackoff  = 30 * time.Second
+	DefaultMaxBackoff   = time.Hour
+	DefaultPollInterval = 5 * time.Second
+	DefaultTimeout      = 10 * time.Second
+	DefaultConcurrency  = 8
+
+	// SignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">",
+	// keyed with the webhook secret. Receivers should recompute it and reject stale timestamps.
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+
+	claimBatchSize = 20
+)
+
+type DispatcherConfig struct {
+	// MaxAttempts is how many times a delivery is attempted before it is marked failed.
+	MaxAttempts int
+	// Backoff spaces out the attempts of a delivery.
+	Backoff retry.Backoff
+	// PollInterval is how often due retries are looked for when nothing wakes the dispatcher.
+	PollInterval time.Duration
+	// Concurrency bounds how many deliveries are attempted at once.
+	Concurrency int
+}
+
+// Dispatcher turns events into webhook deliveries and sends them. It is an outbox sink.
+// Deliveries are queued in the database before anything is sent, so pending ones survive a
+// restart.
+type Dispatcher struct {
+	logger            log.Logger
+	config            DispatcherConfig
+	client            *http.Client
+	webhookRepository webhookrepository.Repository
+	poller            *retry.Poller
+}
+
+func NewDispatcher(logger log.Logger, config DispatcherConfig, client *http.Client, webhookRepository webhookrepository.Repository) *Dispatcher {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+	config.Backoff = config.Backoff.WithDefaults(retry.Backoff{Base: DefaultBaseBackoff, Max: DefaultMaxBackoff})
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultConcurrency
+	}
+
+	return &Dispatcher{
+		logger:            logger,
+		config:            config,
+		client:            client,
+		webhookRepository: webhookRepository,
+		poller:            retry.NewPoller(config.PollInterval),
+	}
+}
+
+// Publish queues a delivery of an outbox message to every webhook subscribed to its event. It
+// is idempotent, so the relay may hand over the same message again.
+func (d *Dispatcher) Publish(ctx context.Context, message *models.OutboxMessage) error {
+	n, err := d.webhookRepository.CreateDeliveries(ctx, message)
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		d.poller.Wake()
+	}
+
+	return nil
+}
+
+// Run sends due deliveries until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	return d.poller.Run(ctx, claimBatchSize, func(ctx context.Context) int {
+		n, err := d.dispatch(ctx)
+		if err != nil {
+			d.logger.Log("webhook", "dispatch", "error", err)
+		}
+		return n
+	})
+}
+
+// dispatch attempts a batch of due deliveries.
+func (d *Dispatcher) dispatch(ctx context.Context) (int, error) {
+	pending, err := d.webhookRepository.ClaimDeliveries(ctx, claimBatchSize, retry.Lease(d.client.Timeout))
+	if err != nil {
+		return 0, err
+	}
+
+	group := errgroup.Group{}
+	group.SetLimit(d.config.Concurrency)
+	for i := range pending {
+		group.Go(func() error {
+			d.deliver(ctx, &pending[i])
+			return nil
+		})
+	}
+	group.Wait()
+
+	return len(pending), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, pending *webhookrepository.PendingDelivery) {
+	delivery := &pending.WebhookDelivery
+	delivery.Attempts++
+
+	status, err := d.send(ctx, pending)
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliveryStatusSucceeded
+		delivery.LastError = nil
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.config.MaxAttempts:
+		message := netguard.Reason(err)
+		delivery.Status = models.WebhookDeliveryStatusFailed
+		delivery.LastError = &message
+		delivery.NextAttemptAt = nil
+	default:
+		message := netguard.Reason(err)
+		next := now.Add(d.config.Backoff.Delay(delivery.Attempts))
+		delivery.LastError = &message
+		delivery.NextAttemptAt = &next
+	}
+
+	if err != nil {
+		d.logger.Log("webhook", delivery.WebhookID, "delivery", delivery.ID, "attempt", delivery.Attempts, "error", err)
+	}
+
+	if err := d.webhookRepository.UpdateDelivery(ctx, delivery); err != nil {
+		d.logger.Log("webhook", delivery.WebhookID, "delivery", delivery.ID, "error", err)
+	}
+}
+
+// send posts the signed payload and returns the response status, if any. Any response other than
+// 2xx fails the attempt.
+func (d *Dispatcher) send(ctx context.Context, pending *webhookrepository.PendingDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pending.URL, bytes.NewReader(pending.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set(EventHeader, string(pending.EventType))
+	req.Header.Set(DeliveryHeader, pending.ID.String())
+	req.Header.Set(SignatureHeader, Sign(pending.Secret, time.Now(), pending.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, &netguard.ErrResponseStatus{Peer: "webhook", StatusCode: resp.StatusCode}
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns the SignatureHeader value of a payload sent at t. The timestamp is signed along
+// with the body so that a captured delivery cannot be replayed later with a fresh timestamp.
+func Sign(secret string, t time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
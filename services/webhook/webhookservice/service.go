@@ -0,0 +1,161 @@
+package webhookservice
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/webhook/webhookrepository"
+)
+
+// Service manages webhook subscriptions and their delivery log. Events are delivered by a
+// Dispatcher.
+type Service interface {
+	CreateWebhook(ctx context.Context, url string, events []models.EventType, active *bool) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, id uuid.UUID, url string, events []models.EventType, active *bool) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+	ListWebhookDeliveries(ctx context.Context, id uuid.UUID, after *uuid.UUID, limit int) (*models.WebhookDeliveryList, error)
+	RedeliverWebhookDelivery(ctx context.Context, id uuid.UUID, deliveryID uuid.UUID) (*models.WebhookDelivery, error)
+}
+
+const (
+	DefaultDeliveryListLimit = 50
+	MaxDeliveryListLimit     = 500
+
+	secretBytes = 32
+)
+
+type webhookService struct {
+	logger            log.Logger
+	dispatcher        *Dispatcher
+	webhookRepository webhookrepository.Repository
+}
+
+func New(logger log.Logger, dispatcher *Dispatcher, webhookRepository webhookrepository.Repository) Service {
+	return &webhookService{
+		logger:            logger,
+		dispatcher:        dispatcher,
+		webhookRepository: webhookRepository,
+	}
+}
+
+// CreateWebhook subscribes a URL to events and generates the secret its deliveries are signed
+// with. The secret is only returned here.
+func (s *webhookService) CreateWebhook(ctx context.Context, url string, events []models.EventType, active *bool) (*models.Webhook, error) {
+	webhook, err := validateWebhook(url, events, active)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, secretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	webhook.Secret = hex.EncodeToString(secret)
+
+	return s.webhookRepository.CreateWebhook(ctx, webhook)
+}
+
+func (s *webhookService) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	return s.webhookRepository.ListWebhooks(ctx)
+}
+
+func (s *webhookService) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	return s.webhookRepository.GetWebhook(ctx, id)
+}
+
+// UpdateWebhook replaces the URL, events and active flag of a webhook. Its secret is kept.
+// Deliveries of an inactive webhook stay pending until it is reactivated.
+func (s *webhookService) UpdateWebhook(ctx context.Context, id uuid.UUID, url string, events []models.EventType, active *bool) (*models.Webhook, error) {
+	webhook, err := validateWebhook(url, events, active)
+	if err != nil {
+		return nil, err
+	}
+	webhook.ID = id
+
+	updated, err := s.webhookRepository.UpdateWebhook(ctx, webhook)
+	if err != nil {
+		return nil, err
+	}
+
+	if updated.Active {
+		s.dispatcher.poller.Wake()
+	}
+
+	return updated, nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	return s.webhookRepository.DeleteWebhook(ctx, id)
+}
+
+func (s *webhookService) ListWebhookDeliveries(ctx context.Context, id uuid.UUID, after *uuid.UUID, limit int) (*models.WebhookDeliveryList, error) {
+	if limit == 0 {
+		limit = DefaultDeliveryListLimit
+	}
+	if limit < 0 || limit > MaxDeliveryListLimit {
+		return nil, errortypes.NewErrInvalidWebhookParameter("limit", fmt.Sprintf("must be between 1 and %d", MaxDeliveryListLimit))
+	}
+
+	deliveries, err := s.webhookRepository.ListDeliveries(ctx, id, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &models.WebhookDeliveryList{Deliveries: deliveries}
+	if len(deliveries) == limit {
+		list.NextAfter = &deliveries[len(deliveries)-1].ID
+	}
+
+	return list, nil
+}
+
+// RedeliverWebhookDelivery queues the payload of an earlier delivery again, whatever its outcome,
+// as a new delivery with a fresh set of attempts.
+func (s *webhookService) RedeliverWebhookDelivery(ctx context.Context, id uuid.UUID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery, err := s.webhookRepository.CreateRedelivery(ctx, id, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.dispatcher.poller.Wake()
+
+	return delivery, nil
+}
+
+func validateWebhook(webhookURL string, events []models.EventType, active *bool) (*models.Webhook, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errortypes.NewErrInvalidWebhookParameter("url", "must be an absolute http or https URL")
+	}
+
+	if len(events) == 0 {
+		return nil, errortypes.NewErrInvalidWebhookParameter("events", "must subscribe to at least one event")
+	}
+
+	var subscribed []models.EventType
+	for _, event := range events {
+		if !slices.Contains(models.EventTypes, event) {
+			return nil, errortypes.NewErrInvalidWebhookParameter("events", fmt.Sprintf("unknown event %q", event))
+		}
+		if !slices.Contains(subscribed, event) {
+			subscribed = append(subscribed, event)
+		}
+	}
+
+	webhook := &models.Webhook{URL: webhookURL, Events: subscribed, Active: true}
+	if active != nil {
+		webhook.Active = *active
+	}
+
+	return webhook, nil
+}
@@ -0,0 +1,273 @@
+package webhooktransport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+
+	"github.com/yckao/image-search-demo-go/pkg/errortypes"
+	"github.com/yckao/image-search-demo-go/pkg/models"
+	"github.com/yckao/image-search-demo-go/services/webhook/webhookendpoint"
+)
+
+func NewHTTPHandler(svc webhookendpoint.Endpoints, logger log.Logger) http.Handler {
+	options := []httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(errortypes.ErrorEncoder),
+		httptransport.ServerErrorLogger(logger),
+	}
+
+	m := http.NewServeMux()
+
+	m.Handle("POST /webhooks", httptransport.NewServer(
+		svc.CreateWebhookEndpoint,
+		decodeCreateWebhookRequest,
+		encodeCreateWebhookResponse,
+		options...,
+	))
+
+	m.Handle("GET /webhooks", httptransport.NewServer(
+		svc.ListWebhooksEndpoint,
+		decodeListWebhooksRequest,
+		encodeListWebhooksResponse,
+		options...,
+	))
+
+	m.Handle("GET /webhooks/{id}", httptransport.NewServer(
+		svc.GetWebhookEndpoint,
+		decodeGetWebhookRequest,
+		encodeGetWebhookResponse,
+		options...,
+	))
+
+	m.Handle("PUT /webhooks/{id}", httptransport.NewServer(
+		svc.UpdateWebhookEndpoint,
+		decodeUpdateWebhookRequest,
+		encodeUpdateWebhookResponse,
+		options...,
+	))
+
+	m.Handle("DELETE /webhooks/{id}", httptransport.NewServer(
+		svc.DeleteWebhookEndpoint,
+		decodeDeleteWebhookRequest,
+		encodeNoContentResponse,
+		options...,
+	))
+
+	m.Handle("GET /webhooks/{id}/deliveries", httptransport.NewServer(
+		svc.ListWebhookDeliveriesEndpoint,
+		decodeListWebhookDeliveriesRequest,
+		encodeListWebhookDeliveriesResponse,
+		options...,
+	))
+
+	m.Handle("POST /webhooks/{id}/deliveries/{delivery_id}/redeliver", httptransport.NewServer(
+		svc.RedeliverWebhookDeliveryEndpoint,
+		decodeRedeliverWebhookDeliveryRequest,
+		encodeRedeliverWebhookDeliveryResponse,
+		options...,
+	))
+
+	return m
+}
+
+type webhookRequest struct {
+	URL    string             `json:"url"`
+	Events []models.EventType `json:"events"`
+	Active *bool              `json:"active"`
+}
+
+func decodeCreateWebhookRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var body webhookRequest
+	if err := decodeJSONBody(r, &body); err != nil {
+		return nil, err
+	}
+
+	return webhookendpoint.CreateWebhookRequest{
+		URL:    body.URL,
+		Events: body.Events,
+		Active: body.Active,
+	}, nil
+}
+
+func encodeCreateWebhookResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(webhookendpoint.CreateWebhookResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeListWebhooksRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	return webhookendpoint.ListWebhooksRequest{}, nil
+}
+
+func encodeListWebhooksResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(webhookendpoint.ListWebhooksResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeGetWebhookRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	return webhookendpoint.GetWebhookRequest{
+		ID: id,
+	}, nil
+}
+
+func encodeGetWebhookResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(webhookendpoint.GetWebhookResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeUpdateWebhookRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	var body webhookRequest
+	if err := decodeJSONBody(r, &body); err != nil {
+		return nil, err
+	}
+
+	return webhookendpoint.UpdateWebhookRequest{
+		ID:     id,
+		URL:    body.URL,
+		Events: body.Events,
+		Active: body.Active,
+	}, nil
+}
+
+func encodeUpdateWebhookResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(webhookendpoint.UpdateWebhookResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeDeleteWebhookRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	return webhookendpoint.DeleteWebhookRequest{
+		ID: id,
+	}, nil
+}
+
+func decodeListWebhookDeliveriesRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	var after *uuid.UUID
+	if v := r.URL.Query().Get("after"); v != "" {
+		parsed, err := uuid.Parse(v)
+		if err != nil {
+			return nil, errortypes.NewErrInvalidWebhookParameter("after", "must be a delivery id")
+		}
+		after = &parsed
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			return nil, errortypes.NewErrInvalidWebhookParameter("limit", "must be an integer")
+		}
+	}
+
+	return webhookendpoint.ListWebhookDeliveriesRequest{
+		ID:    id,
+		After: after,
+		Limit: limit,
+	}, nil
+}
+
+func encodeListWebhookDeliveriesResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(webhookendpoint.ListWebhookDeliveriesResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func decodeRedeliverWebhookDeliveryRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id: %w", err)
+	}
+
+	deliveryID, err := uuid.Parse(r.PathValue("delivery_id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse delivery_id: %w", err)
+	}
+
+	return webhookendpoint.RedeliverWebhookDeliveryRequest{
+		ID:         id,
+		DeliveryID: deliveryID,
+	}, nil
+}
+
+func encodeRedeliverWebhookDeliveryResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(webhookendpoint.RedeliverWebhookDeliveryResponse)
+
+	if resp.Err != nil {
+		errortypes.ErrorEncoder(ctx, resp.Err, w)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	return json.NewEncoder(w).Encode(resp.V)
+}
+
+func encodeNoContentResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if err := response.(endpoint.Failer).Failed(); err != nil {
+		errortypes.ErrorEncoder(ctx, err, w)
+		return nil
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func decodeJSONBody(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return errortypes.NewErrInvalidWebhookParameter("body", "must be a JSON object")
+	}
+	return nil
+}